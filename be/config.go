@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"lb/limiter"
+	"lb/upstream"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// duration is a time.Duration that reads from a Go duration string ("5s", "100ms").
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"5s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// config is the shape of config.json.
+type config struct {
+	OllamaURL           string           `json:"ollama_url"`
+	OllamaURLs          []string         `json:"ollama_urls"` // takes precedence over ollama_url when set
+	Routes              []upstream.Route `json:"routes"`      // model → upstreams; empty = every upstream serves every model
+	HealthCheckInterval duration         `json:"health_check_interval"`
+	Retry               struct {
+		MaxAttempts   int      `json:"max_attempts"`
+		Backoff       duration `json:"backoff"`
+		MaxBackoff    duration `json:"max_backoff"`
+		RetryStatuses []int    `json:"retry_statuses"`
+	} `json:"retry"`
+	Timeouts struct {
+		timeoutValues
+		Models map[string]timeoutOverrides `json:"models"` // keyed by model pattern, as in routes
+		Users  map[string]timeoutOverrides `json:"users"`  // keyed by user ID
+	} `json:"timeouts"`
+	Queue struct {
+		MaxConcurrentPerUpstream int      `json:"max_concurrent_per_upstream"` // 0 = unlimited
+		MaxWait                  duration `json:"max_wait"`                    // 0 = bounded only by timeouts.total
+		PromoteBatchAfter        duration `json:"promote_batch_after"`         // batch requests waiting this long go first; 0 = never
+	} `json:"queue"`
+	Priorities  map[string]string     `json:"priorities"`   // user ID → queue lane ("interactive" or "batch")
+	Orgs        map[string]orgValues  `json:"orgs"`         // organization name → limits and members
+	Plans       map[string]planValues `json:"plans"`        // plan name → limits; "free" is built in
+	DefaultPlan string                `json:"default_plan"` // plan new users start on; default "free"
+	Port        string                `json:"port"`
+}
+
+// planValues is one plan's limits, named as in POST /admin/plans.
+type planValues struct {
+	RPS                   int    `json:"rps"`
+	MaxTokens             int64  `json:"max_tokens"`
+	MaxTokensPerRequest   int64  `json:"max_tokens_per_request"`
+	MaxConcurrentRequests int64  `json:"max_concurrent_requests"`
+	TokensPerMinute       int64  `json:"tokens_per_minute"`
+	TokensPerHour         int64  `json:"tokens_per_hour"`
+	TokensPerDay          int64  `json:"tokens_per_day"`
+	TokensPerMonth        int64  `json:"tokens_per_month"`
+	RequestsPerDay        int64  `json:"requests_per_day"`
+	QuotaPeriod           string `json:"quota_period"`
+	Weight                int    `json:"weight"`
+	StreamTokensPerSecond int64  `json:"stream_tokens_per_second"`
+	MaxComputeSeconds     int64  `json:"max_compute_seconds"`
+}
+
+func (v planValues) plan() limiter.Plan {
+	return limiter.Plan{
+		RPS:             v.RPS,
+		MaxTokens:       v.MaxTokens,
+		MaxTokensPerReq: v.MaxTokensPerRequest,
+		MaxConcurrent:   v.MaxConcurrentRequests,
+		Windows: limiter.WindowLimits{
+			TokensPerMinute: v.TokensPerMinute,
+			TokensPerHour:   v.TokensPerHour,
+			TokensPerDay:    v.TokensPerDay,
+			TokensPerMonth:  v.TokensPerMonth,
+			RequestsPerDay:  v.RequestsPerDay,
+		},
+		QuotaPeriod:        v.QuotaPeriod,
+		Weight:             v.Weight,
+		StreamTokensPerSec: v.StreamTokensPerSecond,
+		MaxComputeSeconds:  v.MaxComputeSeconds,
+	}
+}
+
+// orgValues is one organization's limits, named as in POST /admin/orgs, and
+// its members.
+type orgValues struct {
+	RPS             int      `json:"rps"`
+	MaxTokens       int64    `json:"max_tokens"`
+	TokensPerMinute int64    `json:"tokens_per_minute"`
+	TokensPerHour   int64    `json:"tokens_per_hour"`
+	TokensPerDay    int64    `json:"tokens_per_day"`
+	TokensPerMonth  int64    `json:"tokens_per_month"`
+	RequestsPerDay  int64    `json:"requests_per_day"`
+	QuotaPeriod     string   `json:"quota_period"`
+	BillingDay      int      `json:"billing_day"`
+	Members         []string `json:"members"`
+}
+
+func (v orgValues) limits() limiter.OrgLimits {
+	return limiter.OrgLimits{
+		RPS:       v.RPS,
+		MaxTokens: v.MaxTokens,
+		Windows: limiter.WindowLimits{
+			TokensPerMinute: v.TokensPerMinute,
+			TokensPerHour:   v.TokensPerHour,
+			TokensPerDay:    v.TokensPerDay,
+			TokensPerMonth:  v.TokensPerMonth,
+			RequestsPerDay:  v.RequestsPerDay,
+		},
+		QuotaPeriod: v.QuotaPeriod,
+		BillingDay:  v.BillingDay,
+	}
+}
+
+// timeoutValues is the default set of request deadlines. Omitted fields keep
+// the built-in defaults; "0s" disables that deadline.
+type timeoutValues struct {
+	FirstToken duration `json:"first_token"`
+	Idle       duration `json:"idle"`
+	Total      duration `json:"total"`
+}
+
+func (v timeoutValues) timeouts() upstream.Timeouts {
+	return upstream.Timeouts{
+		FirstToken: time.Duration(v.FirstToken),
+		Idle:       time.Duration(v.Idle),
+		Total:      time.Duration(v.Total),
+	}
+}
+
+// timeoutOverrides is a per-model or per-user set of request deadlines.
+// Omitted fields inherit from the enclosing level; "0s" disables that deadline.
+type timeoutOverrides struct {
+	FirstToken *duration `json:"first_token"`
+	Idle       *duration `json:"idle"`
+	Total      *duration `json:"total"`
+}
+
+func (v timeoutOverrides) overrides() upstream.TimeoutOverrides {
+	d := func(v *duration) *time.Duration {
+		if v == nil {
+			return nil
+		}
+		t := time.Duration(*v)
+		return &t
+	}
+	return upstream.TimeoutOverrides{FirstToken: d(v.FirstToken), Idle: d(v.Idle), Total: d(v.Total)}
+}
+
+// loadConfig reads config.json from the working directory on top of the
+// built-in defaults. A missing file is not an error; a malformed one is fatal.
+func loadConfig(path string) config {
+	var cfg config
+	// Fallback defaults
+	cfg.OllamaURL = "http://localhost:11434"
+	cfg.HealthCheckInterval = duration(5 * time.Second)
+	cfg.Retry.MaxAttempts = 3
+	cfg.Retry.Backoff = duration(100 * time.Millisecond)
+	cfg.Retry.MaxBackoff = duration(2 * time.Second)
+	cfg.Retry.RetryStatuses = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	cfg.Timeouts.FirstToken = duration(2 * time.Minute) // allows for a cold model load
+	cfg.Timeouts.Idle = duration(30 * time.Second)
+	cfg.Timeouts.Total = duration(10 * time.Minute)
+	cfg.Queue.MaxWait = duration(30 * time.Second)
+	cfg.Queue.PromoteBatchAfter = duration(10 * time.Second)
+	cfg.DefaultPlan = limiter.FreePlanName
+	cfg.Port = ":8000"
+
+	if b, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			log.Fatalf("invalid %s: %v", path, err)
+		}
+	} else {
+		log.Printf("warn: %s not found, using defaults", path)
+	}
+	if len(cfg.OllamaURLs) == 0 {
+		cfg.OllamaURLs = []string{cfg.OllamaURL}
+	}
+	if cfg.HealthCheckInterval <= 0 {
+		log.Fatalf("health_check_interval must be > 0")
+	}
+	if cfg.Queue.MaxConcurrentPerUpstream < 0 {
+		log.Fatalf("queue.max_concurrent_per_upstream must be >= 0")
+	}
+	for user, p := range cfg.Priorities {
+		if _, ok := upstream.ParsePriority(p); !ok {
+			log.Fatalf("priorities: %s has unknown priority %q", user, p)
+		}
+	}
+	return cfg
+}
+
+// retryPolicy converts the retry section into an upstream.RetryPolicy.
+func (c config) retryPolicy() upstream.RetryPolicy {
+	return upstream.RetryPolicy{
+		MaxAttempts:   c.Retry.MaxAttempts,
+		Backoff:       time.Duration(c.Retry.Backoff),
+		MaxBackoff:    time.Duration(c.Retry.MaxBackoff),
+		RetryStatuses: c.Retry.RetryStatuses,
+	}
+}
+
+// queuePolicy converts the queue section into an upstream.QueuePolicy.
+func (c config) queuePolicy() upstream.QueuePolicy {
+	return upstream.QueuePolicy{
+		MaxConcurrent: c.Queue.MaxConcurrentPerUpstream,
+		MaxWait:       time.Duration(c.Queue.MaxWait),
+		PromoteAfter:  time.Duration(c.Queue.PromoteBatchAfter),
+	}
+}
+
+// timeoutPolicy converts the timeouts section into an upstream.TimeoutPolicy.
+func (c config) timeoutPolicy() upstream.TimeoutPolicy {
+	p := upstream.TimeoutPolicy{
+		Default: c.Timeouts.timeouts(),
+		Models:  make(map[string]upstream.TimeoutOverrides, len(c.Timeouts.Models)),
+		Users:   make(map[string]upstream.TimeoutOverrides, len(c.Timeouts.Users)),
+	}
+	for pattern, v := range c.Timeouts.Models {
+		p.Models[pattern] = v.overrides()
+	}
+	for user, v := range c.Timeouts.Users {
+		p.Users[user] = v.overrides()
+	}
+	return p
+}
+
+// applyPlans defines the configured plans and organizations on lim, sets the
+// default plan, the users' queue priorities and their organizations. An
+// invalid plan or organization is fatal.
+func (c config) applyPlans(lim *limiter.Limiter) {
+	for name, v := range c.Plans {
+		if _, err := lim.DefinePlan(name, v.plan()); err != nil {
+			log.Fatalf("invalid plan %q: %v", name, err)
+		}
+	}
+	if err := lim.SetDefaultPlan(c.DefaultPlan); err != nil {
+		log.Fatalf("invalid default_plan: %v", err)
+	}
+	for user, p := range c.Priorities {
+		lim.SetPriority(user, p)
+	}
+	for name, v := range c.Orgs {
+		if _, err := lim.DefineOrg(name, v.limits()); err != nil {
+			log.Fatalf("invalid org %q: %v", name, err)
+		}
+		for _, user := range v.Members {
+			lim.SetOrg(user, name)
+		}
+	}
+}
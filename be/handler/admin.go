@@ -5,12 +5,16 @@ import (
 	"lb/auth"
 	"lb/limiter"
 	"lb/pb"
+	"lb/upstream"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
 // validateLimits ensures all limit fields are explicitly set (> 0).
+// Limits scoped to a model may also be 0 (fall back to the user-wide limit) or
+// -1 (unlimited), and cannot carry the user-wide windows or concurrency cap.
 func validateLimits(r *pb.SetLimitsRequest) error {
 	type field struct {
 		name  string
@@ -22,10 +26,34 @@ func validateLimits(r *pb.SetLimitsRequest) error {
 		{"max_tokens_per_request", r.MaxTokensPerRequest},
 	}
 	for _, f := range fields {
-		if f.value <= 0 {
+		if r.Model != "" && f.value < -1 {
+			return fmt.Errorf("field %q must be > 0, 0 (user-wide) or -1 (unlimited) for a model; got %d", f.name, f.value)
+		}
+		if r.Model == "" && f.value <= 0 {
 			return fmt.Errorf("field %q must be > 0; got %d", f.name, f.value)
 		}
 	}
+	// Sliding windows and the concurrency cap are optional: 0 leaves them
+	// unchanged, -1 removes them.
+	windows := []field{
+		{limiter.WindowTokensPerMinute, r.TokensPerMinute},
+		{limiter.WindowTokensPerHour, r.TokensPerHour},
+		{limiter.WindowTokensPerDay, r.TokensPerDay},
+		{limiter.WindowTokensPerMonth, r.TokensPerMonth},
+		{limiter.WindowRequestsPerDay, r.RequestsPerDay},
+		{"max_concurrent_requests", r.MaxConcurrentRequests},
+	}
+	for _, f := range windows {
+		if r.Model != "" && f.value != 0 {
+			return fmt.Errorf("field %q applies user-wide and cannot be scoped to a model", f.name)
+		}
+		if f.value < -1 {
+			return fmt.Errorf("field %q must be > 0, 0 (unchanged) or -1 (unlimited); got %d", f.name, f.value)
+		}
+	}
+	if r.Model != "" && (r.QuotaPeriod != "" || r.BillingDay != 0) {
+		return fmt.Errorf("fields \"quota_period\" and \"billing_day\" apply user-wide and cannot be scoped to a model")
+	}
 	if r.UserId == "" {
 		return fmt.Errorf("field \"user_id\" is required")
 	}
@@ -47,17 +75,139 @@ func SetLimits(lim *limiter.Limiter) echo.HandlerFunc {
 		if err := validateLimits(&req); err != nil {
 			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
 		}
+		if req.Model != "" {
+			lim.SetModelLimits(req.UserId, req.Model, int(req.Rps), req.MaxTokens, req.MaxTokensPerRequest)
+			return c.JSON(http.StatusOK, &pb.SetLimitsResponse{
+				UserId:              req.UserId,
+				Model:               req.Model,
+				Rps:                 req.Rps,
+				MaxTokens:           req.MaxTokens,
+				MaxTokensPerRequest: req.MaxTokensPerRequest,
+			})
+		}
+		// Checked first: the billing day is validated against the period.
+		if err := lim.SetQuotaPeriod(req.UserId, req.QuotaPeriod, int(req.BillingDay)); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
 		lim.SetLimits(req.UserId, int(req.Rps), req.MaxTokens, req.MaxTokensPerRequest)
+		lim.SetWindows(req.UserId, limiter.WindowLimits{
+			TokensPerMinute: req.TokensPerMinute,
+			TokensPerHour:   req.TokensPerHour,
+			TokensPerDay:    req.TokensPerDay,
+			TokensPerMonth:  req.TokensPerMonth,
+			RequestsPerDay:  req.RequestsPerDay,
+		})
+		lim.SetMaxConcurrent(req.UserId, req.MaxConcurrentRequests)
+		w := lim.Windows(req.UserId)
+		q := lim.Quota(req.UserId)
 
 		return c.JSON(http.StatusOK, &pb.SetLimitsResponse{
-			UserId:              req.UserId,
-			Rps:                 req.Rps,
-			MaxTokens:           req.MaxTokens,
-			MaxTokensPerRequest: req.MaxTokensPerRequest,
+			UserId:                req.UserId,
+			Rps:                   req.Rps,
+			MaxTokens:             req.MaxTokens,
+			MaxTokensPerRequest:   req.MaxTokensPerRequest,
+			TokensPerMinute:       w.TokensPerMinute,
+			TokensPerHour:         w.TokensPerHour,
+			TokensPerDay:          w.TokensPerDay,
+			TokensPerMonth:        w.TokensPerMonth,
+			RequestsPerDay:        w.RequestsPerDay,
+			MaxConcurrentRequests: lim.MaxConcurrent(req.UserId),
+			QuotaPeriod:           q.Period,
+			BillingDay:            int32(q.BillingDay),
 		})
 	}
 }
 
+// PatchLimits handles PATCH /admin/limits/:user.
+// Changes only the limits present in the body, leaving the rest and the
+// user's usage as they are; limits named in "unlimited" are removed, and
+// "reset_usage" zeroes the tokens consumed against the quota. Returns the
+// user's full resulting limit state.
+func PatchLimits(lim *limiter.Limiter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// Defense-in-depth: verify admin context key was set by AdminAuthMiddleware.
+		if ok, isAdmin := c.Get(auth.AdminCtxKey).(bool); !ok || !isAdmin {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "admin access required"})
+		}
+		var req pb.PatchLimitsRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid JSON body"})
+		}
+		up, err := limitUpdate(&req)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
+		info, err := lim.UpdateLimits(c.Param("user"), up)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, limitInfoToPB(info))
+	}
+}
+
+// limitUpdate converts a PATCH body to a limiter.LimitUpdate, turning the
+// "unlimited" markers into -1. A field both set and marked unlimited, or an
+// unknown marker, is an error.
+func limitUpdate(r *pb.PatchLimitsRequest) (limiter.LimitUpdate, error) {
+	up := limiter.LimitUpdate{
+		MaxTokens:       r.MaxTokens,
+		MaxTokensPerReq: r.MaxTokensPerRequest,
+		MaxConcurrent:   r.MaxConcurrentRequests,
+		TokensPerMinute: r.TokensPerMinute,
+		TokensPerHour:   r.TokensPerHour,
+		TokensPerDay:    r.TokensPerDay,
+		TokensPerMonth:  r.TokensPerMonth,
+		RequestsPerDay:  r.RequestsPerDay,
+		StreamPace:      r.StreamTokensPerSecond,
+		MaxCompute:      r.MaxComputeSeconds,
+		QuotaPeriod:     r.QuotaPeriod,
+		ResetUsage:      r.ResetUsage,
+	}
+	if r.Rps != nil {
+		rps := int(*r.Rps)
+		up.RPS = &rps
+	}
+	if r.BillingDay != nil {
+		day := int(*r.BillingDay)
+		up.BillingDay = &day
+	}
+	inf := int64(-1)
+	int64Fields := map[string]**int64{
+		"max_tokens":                  &up.MaxTokens,
+		"max_tokens_per_request":      &up.MaxTokensPerReq,
+		"max_concurrent_requests":     &up.MaxConcurrent,
+		limiter.WindowTokensPerMinute: &up.TokensPerMinute,
+		limiter.WindowTokensPerHour:   &up.TokensPerHour,
+		limiter.WindowTokensPerDay:    &up.TokensPerDay,
+		limiter.WindowTokensPerMonth:  &up.TokensPerMonth,
+		limiter.WindowRequestsPerDay:  &up.RequestsPerDay,
+		"stream_tokens_per_second":    &up.StreamPace,
+		"max_compute_seconds":         &up.MaxCompute,
+	}
+	for _, name := range r.Unlimited {
+		if name == "rps" {
+			if up.RPS != nil {
+				return up, fmt.Errorf("field \"rps\" is both set and marked unlimited")
+			}
+			rps := limiter.INF_RPS
+			up.RPS = &rps
+			continue
+		}
+		f, ok := int64Fields[name]
+		if !ok {
+			return up, fmt.Errorf("field %q cannot be marked unlimited", name)
+		}
+		if *f != nil {
+			return up, fmt.Errorf("field %q is both set and marked unlimited", name)
+		}
+		*f = &inf
+	}
+	return up, nil
+}
+
+// SuspendUser handles POST /admin/suspend.
+// Blocks the user's requests with a 403 carrying the reason, indefinitely or
+// for the given duration. Their limits and usage are left untouched.
 func SuspendUser(lim *limiter.Limiter) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// Defense-in-depth: verify admin context key was set by AdminAuthMiddleware.
@@ -68,12 +218,103 @@ func SuspendUser(lim *limiter.Limiter) echo.HandlerFunc {
 		if err := c.Bind(&req); err != nil || req.UserId == "" {
 			return c.JSON(http.StatusBadRequest, echo.Map{"error": "user_id is required"})
 		}
-		// rps=0 → rate.Limit(0) with burst 0: Allow() always returns false.
-		// This hard-blocks the user on every incoming request.
-		lim.SetLimits(req.UserId, 0, 0, 0)
+		by, _ := c.Get(auth.UserIDKey).(string)
+		s := limiter.Suspension{Reason: req.Reason, By: by, At: time.Now()}
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 {
+				return c.JSON(http.StatusBadRequest, echo.Map{"error": "duration must be a positive Go duration like \"24h\""})
+			}
+			s.Until = s.At.Add(d)
+		}
+		lim.Suspend(req.UserId, s)
 		return c.JSON(http.StatusOK, &pb.SuspendUserResponse{
+			UserId:     req.UserId,
+			Status:     "suspended",
+			Suspension: suspensionToPB(&s),
+		})
+	}
+}
+
+// UnsuspendUser handles POST /admin/unsuspend.
+// Lifts a suspension; the user's limits and usage are as they were before it.
+func UnsuspendUser(lim *limiter.Limiter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// Defense-in-depth: verify admin context key was set by AdminAuthMiddleware.
+		if ok, isAdmin := c.Get(auth.AdminCtxKey).(bool); !ok || !isAdmin {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "admin access required"})
+		}
+		var req pb.UnsuspendUserRequest
+		if err := c.Bind(&req); err != nil || req.UserId == "" {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "user_id is required"})
+		}
+		if !lim.Unsuspend(req.UserId) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": fmt.Sprintf("user %q is not suspended", req.UserId)})
+		}
+		return c.JSON(http.StatusOK, &pb.UnsuspendUserResponse{
+			UserId: req.UserId,
+			Status: "active",
+		})
+	}
+}
+
+// suspensionToPB converts a suspension to its API shape; nil stays nil.
+func suspensionToPB(s *limiter.Suspension) *pb.SuspensionInfo {
+	if s == nil {
+		return nil
+	}
+	info := &pb.SuspensionInfo{
+		Reason:      s.Reason,
+		SuspendedBy: s.By,
+		SuspendedAt: s.At.UTC().Format(time.RFC3339),
+	}
+	if !s.Until.IsZero() {
+		info.Until = s.Until.UTC().Format(time.RFC3339)
+	}
+	return info
+}
+
+// SetAllowedModels handles POST /admin/models.
+// Replaces the user's model allowlist; an empty list allows every model.
+func SetAllowedModels(lim *limiter.Limiter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// Defense-in-depth: verify admin context key was set by AdminAuthMiddleware.
+		if ok, isAdmin := c.Get(auth.AdminCtxKey).(bool); !ok || !isAdmin {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "admin access required"})
+		}
+		var req pb.SetAllowedModelsRequest
+		if err := c.Bind(&req); err != nil || req.UserId == "" {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "user_id is required"})
+		}
+		lim.SetAllowedModels(req.UserId, req.Models)
+		return c.JSON(http.StatusOK, &pb.SetAllowedModelsResponse{
 			UserId: req.UserId,
-			Status: "suspended",
+			Models: lim.AllowedModels(req.UserId),
+		})
+	}
+}
+
+// SetPriority handles POST /admin/priority.
+// Sets the upstream queue lane for the user's requests. Requests can still
+// lower their own priority with the X-Priority header, but not raise it.
+func SetPriority(lim *limiter.Limiter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// Defense-in-depth: verify admin context key was set by AdminAuthMiddleware.
+		if ok, isAdmin := c.Get(auth.AdminCtxKey).(bool); !ok || !isAdmin {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "admin access required"})
+		}
+		var req pb.SetPriorityRequest
+		if err := c.Bind(&req); err != nil || req.UserId == "" {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "user_id is required"})
+		}
+		if _, ok := upstream.ParsePriority(req.Priority); req.Priority != "" && !ok {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": fmt.Sprintf("priority must be %q or %q; got %q",
+				upstream.PriorityInteractive, upstream.PriorityBatch, req.Priority)})
+		}
+		lim.SetPriority(req.UserId, req.Priority)
+		return c.JSON(http.StatusOK, &pb.SetPriorityResponse{
+			UserId:   req.UserId,
+			Priority: lim.Priority(req.UserId),
 		})
 	}
 }
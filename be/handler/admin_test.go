@@ -0,0 +1,158 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"lb/auth"
+	"lb/handler"
+	"lb/limiter"
+	"lb/pb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newAdminServer wires PatchLimits behind a stub admin middleware.
+func newAdminServer(lim *limiter.Limiter) *echo.Echo {
+	e := echo.New()
+	e.PATCH("/admin/limits/:user", handler.PatchLimits(lim), func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(auth.UserIDKey, "admin")
+			c.Set(auth.AdminCtxKey, true)
+			return next(c)
+		}
+	})
+	return e
+}
+
+// patchLimits sends body to PATCH /admin/limits/:user and decodes a 200 reply.
+func patchLimits(t *testing.T, e *echo.Echo, user, body string) (*httptest.ResponseRecorder, *pb.LimitInfo) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPatch, "/admin/limits/"+user, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec, nil
+	}
+	var info pb.LimitInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return rec, &info
+}
+
+func TestPatchLimits_PartialUpdate(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("alice", 7, 5000, 300)
+	e := newAdminServer(lim)
+
+	rec, info := patchLimits(t, e, "alice", `{"max_tokens": 9000}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if info.MaxTokens != 9000 {
+		t.Errorf("max_tokens = %d, want 9000", info.MaxTokens)
+	}
+	if info.Rps != 7 || info.MaxTokensPerReq != 300 {
+		t.Errorf("rps = %v, max_tokens_per_req = %d; want 7 and 300 untouched", info.Rps, info.MaxTokensPerReq)
+	}
+	if info.MaxConcurrentRequests != limiter.FreePlan.MaxConcurrent {
+		t.Errorf("max_concurrent_requests = %d, want plan's %d", info.MaxConcurrentRequests, limiter.FreePlan.MaxConcurrent)
+	}
+}
+
+func TestPatchLimits_Unlimited(t *testing.T) {
+	lim := limiter.New()
+	e := newAdminServer(lim)
+
+	rec, info := patchLimits(t, e, "alice", `{"unlimited": ["max_tokens", "rps"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if info.MaxTokens != limiter.INF_TOKENS {
+		t.Errorf("max_tokens = %d, want unlimited", info.MaxTokens)
+	}
+	if info.Rps != limiter.INF_RPS {
+		t.Errorf("rps = %v, want unlimited", info.Rps)
+	}
+	if info.MaxTokensPerReq != limiter.FreePlan.MaxTokensPerReq {
+		t.Errorf("max_tokens_per_req = %d, want plan's %d", info.MaxTokensPerReq, limiter.FreePlan.MaxTokensPerReq)
+	}
+}
+
+func TestPatchLimits_Rejected(t *testing.T) {
+	cases := map[string]string{
+		"set and unlimited":     `{"max_tokens": 10, "unlimited": ["max_tokens"]}`,
+		"rps set and unlimited": `{"rps": 5, "unlimited": ["rps"]}`,
+		"unknown marker":        `{"unlimited": ["quota_period"]}`,
+		"misspelled marker":     `{"unlimited": ["max_token"]}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			lim := limiter.New()
+			lim.SetLimits("alice", 7, 5000, 300)
+			e := newAdminServer(lim)
+
+			rec, _ := patchLimits(t, e, "alice", body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400; body %s", rec.Code, rec.Body)
+			}
+			// All or nothing: a rejected patch changes nothing.
+			info := lim.Limits("alice")
+			if info.RPS != 7 || info.MaxTokens != 5000 || info.MaxTokensPerReq != 300 {
+				t.Errorf("limits changed by rejected patch: %+v", info)
+			}
+		})
+	}
+}
+
+func TestPatchLimits_UnknownUser(t *testing.T) {
+	lim := limiter.New()
+	e := newAdminServer(lim)
+
+	// A user the limiter hasn't seen starts on the default plan, with the
+	// patch applied on top, just as their first request would find them.
+	rec, info := patchLimits(t, e, "dave", `{"max_tokens_per_request": 50}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if info.Plan != limiter.FreePlanName {
+		t.Errorf("plan = %q, want %q", info.Plan, limiter.FreePlanName)
+	}
+	if info.MaxTokensPerReq != 50 || info.MaxTokens != limiter.FreePlan.MaxTokens {
+		t.Errorf("max_tokens_per_req = %d, max_tokens = %d; want 50 and plan's %d",
+			info.MaxTokensPerReq, info.MaxTokens, limiter.FreePlan.MaxTokens)
+	}
+	if got := lim.MaxTokensPerRequest("dave", "llama3.2:1b"); got != 50 {
+		t.Errorf("MaxTokensPerRequest = %d, want 50", got)
+	}
+}
+
+func TestPatchLimits_ResetUsage(t *testing.T) {
+	lim := limiter.New()
+	e := newAdminServer(lim)
+	lim.ConsumeTokens("alice", "llama3.2:1b", 120)
+
+	// Without reset_usage the usage survives the patch.
+	rec, info := patchLimits(t, e, "alice", `{"max_tokens": 9000}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if info.UsedTokens != 120 {
+		t.Fatalf("used_tokens = %d, want 120 kept", info.UsedTokens)
+	}
+
+	rec, info = patchLimits(t, e, "alice", `{"reset_usage": true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if info.UsedTokens != 0 {
+		t.Errorf("used_tokens = %d, want 0 after reset", info.UsedTokens)
+	}
+	if info.MaxTokens != 9000 {
+		t.Errorf("max_tokens = %d, want 9000 kept", info.MaxTokens)
+	}
+}
@@ -9,8 +9,8 @@ import (
 )
 
 // AllLimits handles GET /admin/limits.
-// Returns current RPS, token quota, per-request cap, and usage for every user
-// the limiter knows about.
+// Returns each user's plan and current RPS, token quota, per-request cap, sliding windows,
+// per-model overrides, and usage for every user the limiter knows about.
 func AllLimits(lim *limiter.Limiter) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		limits := lim.GetAllLimits()
@@ -18,13 +18,47 @@ func AllLimits(lim *limiter.Limiter) echo.HandlerFunc {
 			Limits: make(map[string]*pb.LimitInfo, len(limits)),
 		}
 		for userID, info := range limits {
-			resp.Limits[userID] = &pb.LimitInfo{
-				MaxTokens:       info.MaxTokens,
-				MaxTokensPerReq: info.MaxTokensPerReq,
-				UsedTokens:      info.UsedTokens,
-				Rps:             info.RPS,
-			}
+			resp.Limits[userID] = limitInfoToPB(info)
 		}
 		return c.JSON(http.StatusOK, resp)
 	}
 }
+
+// limitInfoToPB converts one user's limits and usage to their API shape.
+func limitInfoToPB(info limiter.LimitInfo) *pb.LimitInfo {
+	var models map[string]*pb.ModelLimitInfo
+	for model, m := range info.Models {
+		if models == nil {
+			models = make(map[string]*pb.ModelLimitInfo, len(info.Models))
+		}
+		models[model] = &pb.ModelLimitInfo{
+			MaxTokens:       m.MaxTokens,
+			MaxTokensPerReq: m.MaxTokensPerReq,
+			UsedTokens:      m.UsedTokens,
+			Rps:             m.RPS,
+		}
+	}
+	return &pb.LimitInfo{
+		Plan:                  info.Plan,
+		MaxTokens:             info.MaxTokens,
+		MaxTokensPerReq:       info.MaxTokensPerReq,
+		UsedTokens:            info.UsedTokens,
+		ReservedTokens:        info.ReservedTokens,
+		Rps:                   info.RPS,
+		AllowedModels:         info.AllowedModels,
+		TokensPerMinute:       info.Windows.TokensPerMinute,
+		TokensPerHour:         info.Windows.TokensPerHour,
+		TokensPerDay:          info.Windows.TokensPerDay,
+		TokensPerMonth:        info.Windows.TokensPerMonth,
+		RequestsPerDay:        info.Windows.RequestsPerDay,
+		WindowUsage:           info.WindowUsage,
+		MaxConcurrentRequests: info.MaxConcurrent,
+		InFlight:              info.InFlight,
+		Models:                models,
+		Suspension:            suspensionToPB(info.Suspension),
+		Quota:                 quotaToPB(info.Quota),
+		Priority:              info.Priority,
+		Org:                   info.Org,
+		StreamTokensPerSecond: info.StreamPace,
+	}
+}
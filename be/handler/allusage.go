@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"lb/limiter"
 	"lb/pb"
 	"lb/store"
 	"net/http"
@@ -10,25 +11,24 @@ import (
 
 // AllUsage handles GET /admin/usage.
 // Auth is enforced at the route-group level by AdminAuthMiddleware.
-// Returns token usage for every user, keyed by user → model.
-func AllUsage(s *store.Store) echo.HandlerFunc {
+// Returns token usage for every user, keyed by user → model, and per
+// organization: what its members used while they belonged to it.
+func AllUsage(s *store.Store, lim *limiter.Limiter) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		usage := s.GetAll()
+		orgs := lim.Orgs()
 		resp := &pb.AllUsageResponse{
 			UsageByUser: make(map[string]*pb.UsageResponse, len(usage)),
+			UsageByOrg:  make(map[string]*pb.UsageResponse, len(orgs)),
 		}
-
 		for user, models := range usage {
-			userResp := &pb.UsageResponse{
-				UsageByModel: make(map[string]*pb.ModelUsage, len(models)),
-			}
-			for model, u := range models {
-				userResp.UsageByModel[model] = &pb.ModelUsage{
-					PromptTokens:     int32(u.PromptTokens),
-					CompletionTokens: int32(u.CompletionTokens),
-				}
+			resp.UsageByUser[user] = &pb.UsageResponse{UsageByModel: usageToPB(models)}
+		}
+		for _, o := range orgs {
+			resp.UsageByOrg[o.Name] = &pb.UsageResponse{
+				UsageByModel: usageToPB(s.Org(o.Name)),
+				Quota:        quotaToPB(o.Quota),
 			}
-			resp.UsageByUser[user] = userResp
 		}
 
 		return c.JSON(http.StatusOK, resp)
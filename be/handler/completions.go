@@ -8,11 +8,10 @@ import (
 	"lb/auth"
 	"lb/limiter"
 	"lb/store"
+	"lb/upstream"
 	"log"
 	"math/rand"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -23,40 +22,21 @@ import (
 var reqCount uint64
 
 // usagePayload is the shape of the usage field in Ollama/OpenAI responses.
+// Usage is nil when the upstream didn't report it.
 type usagePayload struct {
-	Usage struct {
+	Usage *struct {
 		PromptTokens     int `json:"prompt_tokens"`
 		CompletionTokens int `json:"completion_tokens"`
 	} `json:"usage"`
 }
 
-// Completions handles POST /v1/chat/completions.
-// It authenticates the caller, enforces rate/quota limits, proxies the request
-// to Ollama, and accounts for token usage without blocking the inference path.
-func Completions(ollamaBase string, s *store.Store, lim *limiter.Limiter) echo.HandlerFunc {
-	upstream, _ := url.Parse(ollamaBase)
-
-	proxy := httputil.NewSingleHostReverseProxy(upstream)
-
-	// Create a custom director to enforce the correct upstream Host
-	// while stripping headers that Ollama might reject (like Origin from extensions)
-	originalDirector := proxy.Director
-	proxy.Director = func(req *http.Request) {
-		originalDirector(req)
-		req.Host = upstream.Host
-		req.Header.Del("Origin")
-	}
-
-	// Disable response buffering — forward chunks immediately.
-	proxy.FlushInterval = -1
-
-	// Suppress default error handling so we can manage it ourselves.
-	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		http.Error(w, "upstream error: "+err.Error(), http.StatusBadGateway)
-	}
-
-	// ModifyResponse intercepts the upstream response for accounting.
-	proxy.ModifyResponse = func(resp *http.Response) error {
+// Completions handles POST /v1/chat/completions and the legacy POST /v1/completions.
+// Both endpoints share the same request/usage shape, so the path is forwarded
+// to Ollama as-is. It authenticates the caller, enforces rate/quota limits,
+// proxies the request to Ollama, and accounts for token usage without blocking
+// the inference path.
+func Completions(pool *upstream.Pool, s *store.Store, lim *limiter.Limiter) echo.HandlerFunc {
+	proxy := newUpstreamProxy(pool, func(resp *http.Response) {
 		userKey := resp.Request.Context().Value(ctxKeyUser{}).(string)
 		model := resp.Request.Context().Value(ctxKeyModel{}).(string)
 		isStream := resp.Request.Context().Value(ctxKeyStream{}).(bool)
@@ -66,27 +46,16 @@ func Completions(ollamaBase string, s *store.Store, lim *limiter.Limiter) echo.H
 		} else {
 			accountDirect(resp, userKey, model, s, lim)
 		}
-		return nil
-	}
+	})
 
 	return func(c echo.Context) error {
 		count := atomic.AddUint64(&reqCount, 1)
 		// log 10% of requests for debugging
 		if rand.Intn(10) == 0 {
-			log.Printf("[Req #%d] ==> Incoming /v1/chat/completions request (IP: %s)", count, c.RealIP())
+			log.Printf("[Req #%d] ==> Incoming %s request (IP: %s)", count, c.Request().URL.Path, c.RealIP())
 		}
 
 		userID := c.Get(auth.UserIDKey).(string)
-		admin := c.Get(auth.AdminCtxKey).(bool)
-
-		if !admin {
-			if err := lim.CheckRPS(userID); err != nil {
-				return c.JSON(http.StatusTooManyRequests, echo.Map{"error": "rate limit exceeded"})
-			}
-			if err := lim.CheckQuota(userID); err != nil {
-				return c.JSON(http.StatusForbidden, echo.Map{"error": "token quota exceeded"})
-			}
-		}
 
 		// Peek at the body to detect streaming, model name, and max_tokens.
 		body, err := io.ReadAll(c.Request().Body)
@@ -102,6 +71,15 @@ func Completions(ollamaBase string, s *store.Store, lim *limiter.Limiter) echo.H
 		_ = json.Unmarshal(body, &peek)
 
 		model := peek.Model
+		// Reserve the worst case against the quota: estimated prompt plus the
+		// capped completion budget.
+		promptTokens := estimatePromptTokens(body)
+		cost := int64(promptTokens) + completionBudget(peek.MaxTokens, lim.MaxTokensPerRequest(userID, model))
+		release, err := admitRequest(c, lim, userID, model, cost)
+		if release == nil {
+			return err
+		}
+		defer release()
 		isStream := peek.Stream == nil || *peek.Stream // default true per OpenAI spec
 
 		var requestedMaxTokens string
@@ -122,7 +100,7 @@ func Completions(ollamaBase string, s *store.Store, lim *limiter.Limiter) echo.H
 			modified := false
 
 			// 1. Enforce max_tokens
-			cap := lim.MaxTokensPerRequest(userID)
+			cap := lim.MaxTokensPerRequest(userID, model)
 			if cap != limiter.INF_TOKEN_PER_REQ && (peek.MaxTokens == nil || *peek.MaxTokens > cap) {
 				capBytes, _ := json.Marshal(cap)
 				raw["max_tokens"] = capBytes
@@ -158,44 +136,190 @@ func Completions(ollamaBase string, s *store.Store, lim *limiter.Limiter) echo.H
 		c.Request().Header.Set("Content-Length", strconv.Itoa(len(body)))
 
 		// Attach values to request context so ModifyResponse can read them.
-		req := c.Request().WithContext(
-			contextWith(c.Request().Context(), userID, model, isStream),
-		)
-		c.SetRequest(req)
+		ctx := contextWith(c.Request().Context(), userID, model, isStream)
+		ctx = withPromptEstimate(ctx, promptTokens)
+		if admin := c.Get(auth.AdminCtxKey).(bool); !admin {
+			ctx = withQuotaCutoff(ctx)
+		}
+		if isStream {
+			ctx = withStreamPace(ctx, lim.StreamPace(userID))
+		}
+		c.SetRequest(c.Request().WithContext(ctx))
 
 		if rand.Intn(10) == 0 {
 			log.Printf("    Forwarding to upstream proxy...")
 		}
-		proxy.ServeHTTP(c.Response(), c.Request())
-		return nil
+		return serveProxy(c, proxy, pool, body)
 	}
 }
 
 // accountDirect reads the full (non-streaming) response body, parses usage,
 // restores the body for the client, and records accounting in the background.
+// When the upstream omits usage, it is estimated from the request and the
+// returned choices and recorded as estimated.
 func accountDirect(resp *http.Response, user, model string, s *store.Store, lim *limiter.Limiter) {
+	res := reservation(resp.Request.Context())
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
+		res.Release()
 		return
 	}
 	resp.Body = io.NopCloser(bytes.NewReader(body))
+	promptTokens := promptEstimate(resp.Request.Context())
+	ok := resp.StatusCode == http.StatusOK // error bodies never carry generated tokens
+	elapsed := upstreamTime(resp)
 
 	go func() {
+		defer res.Release()
+		if ok {
+			recordCompute(s, lim, user, model, 0, elapsed)
+		}
 		var p usagePayload
 		if err := json.Unmarshal(body, &p); err != nil {
 			return
 		}
+		if p.Usage == nil {
+			if ok {
+				var out choicesPayload
+				_ = json.Unmarshal(body, &out)
+				recordEstimate(s, lim, res, user, model, promptTokens, out.generatedTokens(), false)
+			}
+			return
+		}
 		total := p.Usage.PromptTokens + p.Usage.CompletionTokens
 		s.Add(user, model, p.Usage.PromptTokens, p.Usage.CompletionTokens)
-		lim.ConsumeTokens(user, total)
+		lim.ConsumeTokens(user, model, total)
 	}()
 }
 
-// accountStream wraps the streaming response body with an io.TeeReader that
-// pipes bytes to a bufio.Scanner for incremental SSE frame parsing.
-// Only the last usage-bearing frame (before [DONE]) is retained in memory.
-// All other frames are forwarded immediately — no full-body buffering.
+// choicesPayload is the generated output of a response body or SSE chunk,
+// inspected only when the upstream reports no usage.
+type choicesPayload struct {
+	Choices []struct {
+		Text    string         `json:"text"`    // legacy /v1/completions
+		Message messagePayload `json:"message"` // non-streaming chat
+		Delta   messagePayload `json:"delta"`   // streaming chat
+	} `json:"choices"`
+}
+
+type messagePayload struct {
+	Content   string          `json:"content"`
+	ToolCalls json.RawMessage `json:"tool_calls"`
+}
+
+// generatedTokens estimates the completion tokens carried by the payload.
+// Estimates round up, so every non-empty chunk counts for at least one token —
+// close to Ollama, which streams roughly one token per chunk.
+func (c *choicesPayload) generatedTokens() int {
+	n := 0
+	for _, ch := range c.Choices {
+		n += estimateTokens(ch.Text) + ch.Message.tokens() + ch.Delta.tokens()
+	}
+	return n
+}
+
+func (m *messagePayload) tokens() int {
+	n := estimateTokens(m.Content)
+	if len(m.ToolCalls) > 0 && string(m.ToolCalls) != "null" {
+		n += estimateTokens(string(m.ToolCalls))
+	}
+	return n
+}
+
+// accountStream taps the streaming response body for incremental SSE frame
+// parsing. Only the last usage-bearing frame (before [DONE]) is retained in
+// memory. All other frames are forwarded immediately — no full-body buffering.
+//
+// If the stream ends without a usage frame, the tokens streamed are estimated
+// from the generated deltas and billed as estimated. If it is cut off before
+// [DONE] (typically the client disconnected, which cancels the upstream
+// request), the estimate is additionally marked as aborted.
+//
+// Streams marked by withQuotaCutoff are ended early, with finish_reason
+// "length", once they exhaust the user's quota or a token window; what was
+// streamed until then is billed as estimated.
+//
+// Streams with a pace (see withStreamPace) reach the client through a
+// pacedBody. Accounting happens as the upstream is read, so tokens generated
+// ahead of the client are billed even if it disconnects before receiving them.
 func accountStream(resp *http.Response, user, model string, s *store.Store, lim *limiter.Limiter) {
+	res := reservation(resp.Request.Context())
+	promptTokens := promptEstimate(resp.Request.Context())
+	ok := resp.StatusCode == http.StatusOK // error bodies never carry generated tokens
+	var qb *quotaBody
+	if ok && quotaCutoff(resp.Request.Context()) {
+		qb = newQuotaBody(resp.Body, promptTokens, func() int64 { return lim.Allowance(user, model, res) })
+		resp.Body = qb
+	}
+	tapLines(resp, func(scanner *bufio.Scanner) {
+		defer res.Release()
+		var lastUsageLine string
+		var streamed int
+		done := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			data := strings.TrimPrefix(line, "data: ")
+			switch {
+			case data == "[DONE]":
+				done = true
+			case strings.Contains(data, `"usage"`) && !strings.Contains(data, `"usage":null`):
+				// Retain only lines that contain a usage field.
+				lastUsageLine = data
+			case lastUsageLine == "":
+				var chunk choicesPayload
+				if json.Unmarshal([]byte(data), &chunk) == nil {
+					streamed += chunk.generatedTokens()
+				}
+			}
+		}
+		if ok {
+			recordCompute(s, lim, user, model, 0, upstreamTime(resp))
+		}
+
+		if qb != nil && qb.cut {
+			recordEstimate(s, lim, res, user, model, qb.prompt, qb.streamed, false)
+			return
+		}
+		if lastUsageLine == "" {
+			if ok {
+				recordEstimate(s, lim, res, user, model, promptTokens, streamed, !done)
+			}
+			return
+		}
+		var p usagePayload
+		if err := json.Unmarshal([]byte(lastUsageLine), &p); err != nil || p.Usage == nil {
+			return
+		}
+		total := p.Usage.PromptTokens + p.Usage.CompletionTokens
+		s.Add(user, model, p.Usage.PromptTokens, p.Usage.CompletionTokens)
+		lim.ConsumeTokens(user, model, total)
+	})
+	if tps := streamPace(resp.Request.Context()); ok && tps != limiter.INF_TOKENS {
+		a := resp.Request.Context().Value(ctxKeyAttempt{}).(*attempt)
+		resp.Body = newPacedBody(resp.Request.Context(), resp.Body, tps, a.deadline)
+	}
+}
+
+// recordEstimate bills usage the proxy estimated itself because the upstream
+// never reported it. aborted marks responses that were cut off mid-way.
+func recordEstimate(s *store.Store, lim *limiter.Limiter, res *limiter.Reservation, user, model string, prompt, completion int, aborted bool) {
+	s.Record(user, model, store.Record{
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		Aborted:          aborted,
+		Estimated:        true,
+	})
+	lim.ConsumeTokens(user, model, prompt+completion)
+	res.Release()
+}
+
+// tapLines wraps the streaming response body with an io.TeeReader that pipes
+// bytes to a bufio.Scanner, and runs scan over it in a background goroutine.
+// The client-facing stream is never delayed by the scanner.
+func tapLines(resp *http.Response, scan func(scanner *bufio.Scanner)) {
 	pr, pw := io.Pipe()
 
 	// TeeReader sends every byte to both the original resp.Body consumer
@@ -219,33 +343,11 @@ func accountStream(resp *http.Response, user, model string, s *store.Store, lim
 
 		// scanner reads from the read-half of our pipe.
 		scanner := bufio.NewScanner(pr)
-		// Provide a larger buffer (up to 1MB) for exceptionally large SSE JSON chunks so it doesn't ErrTooLong
+		// Provide a larger buffer (up to 1MB) for exceptionally large JSON chunks so it doesn't ErrTooLong
 		buf := make([]byte, 0, 64*1024)
 		scanner.Buffer(buf, 1024*1024)
 
-		var lastUsageLine string
-		for scanner.Scan() {
-			line := scanner.Text()
-			if !strings.HasPrefix(line, "data: ") {
-				continue
-			}
-			data := strings.TrimPrefix(line, "data: ")
-			// Retain only lines that contain a usage field.
-			if strings.Contains(data, `"usage"`) {
-				lastUsageLine = data
-			}
-		}
-
-		if lastUsageLine == "" {
-			return
-		}
-		var p usagePayload
-		if err := json.Unmarshal([]byte(lastUsageLine), &p); err != nil {
-			return
-		}
-		total := p.Usage.PromptTokens + p.Usage.CompletionTokens
-		s.Add(user, model, p.Usage.PromptTokens, p.Usage.CompletionTokens)
-		lim.ConsumeTokens(user, total)
+		scan(scanner)
 	}()
 }
 
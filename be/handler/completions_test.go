@@ -0,0 +1,728 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"lb/auth"
+	"lb/handler"
+	"lb/limiter"
+	"lb/store"
+	"lb/upstream"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCompletions_ClientDisconnectCancelsUpstreamAndBillsPartial(t *testing.T) {
+	cancelled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 100; i++ {
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"tok\"}}]}\n\n")
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				close(cancelled)
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}))
+	t.Cleanup(slow.Close)
+
+	pool, _ := upstream.NewPool([]string{slow.URL}, nil)
+	s := store.New()
+	lim := limiter.New()
+	proxy := httptest.NewServer(newServer(pool, s, lim))
+	t.Cleanup(proxy.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body := `{"model":"llama3.2:1b","messages":[{"role":"user","content":"sixteen chars!!!"}]}`
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, proxy.URL+"/v1/chat/completions", strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read three frames, then hang up.
+	r := bufio.NewReader(resp.Body)
+	for frames := 0; frames < 3; {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			frames++
+		}
+	}
+	cancel()
+	resp.Body.Close()
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream request was not cancelled after client disconnect")
+	}
+
+	var u store.ModelUsage
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if u = s.Get("alice")["llama3.2:1b"]; u.AbortedRequests > 0 {
+			break
+		}
+	}
+	if u.AbortedRequests != 1 {
+		t.Fatalf("aborted requests: got %d, want 1", u.AbortedRequests)
+	}
+	if u.CompletionTokens < 3 {
+		t.Errorf("completion tokens: got %d, want at least the 3 frames read", u.CompletionTokens)
+	}
+	if u.PromptTokens != 4 {
+		t.Errorf("prompt tokens: got %d, want estimate of 4", u.PromptTokens)
+	}
+	if u.AbortedTokens != u.PromptTokens+u.CompletionTokens {
+		t.Errorf("aborted tokens: got %d, want %d", u.AbortedTokens, u.PromptTokens+u.CompletionTokens)
+	}
+}
+
+func TestCompletions_EstimatesMissingUsage(t *testing.T) {
+	noUsage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"twelve chars"}}]}`)
+	}))
+	t.Cleanup(noUsage.Close)
+
+	pool, _ := upstream.NewPool([]string{noUsage.URL}, nil)
+	s := store.New()
+	if rec := postCompletion(newServer(pool, s, limiter.New())); rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200", rec.Code)
+	}
+
+	var u store.ModelUsage
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if u = s.Get("alice")["llama3.2:1b"]; u.EstimatedRequests > 0 {
+			break
+		}
+	}
+	if u.EstimatedRequests != 1 || u.AbortedRequests != 0 {
+		t.Fatalf("got estimated=%d aborted=%d, want 1 and 0", u.EstimatedRequests, u.AbortedRequests)
+	}
+	if u.CompletionTokens != 3 {
+		t.Errorf("completion tokens: got %d, want 3", u.CompletionTokens)
+	}
+}
+
+func TestCompletions_IdleTimeoutEndsStreamWithErrorEvent(t *testing.T) {
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"tok\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done() // hang until the proxy gives up
+	}))
+	t.Cleanup(stalled.Close)
+
+	pool, _ := upstream.NewPool([]string{stalled.URL}, nil)
+	pool.Timeouts.Default = upstream.Timeouts{Idle: 50 * time.Millisecond}
+	s := store.New()
+	proxy := httptest.NewServer(newServer(pool, s, limiter.New()))
+	t.Cleanup(proxy.Close)
+
+	resp, err := http.Post(proxy.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"model":"llama3.2:1b","stream":true,"messages":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("stream should end cleanly, got %v", err)
+	}
+	if !strings.Contains(string(out), "event: error\ndata: ") || !strings.Contains(string(out), `"code":"idle_timeout"`) {
+		t.Fatalf("missing SSE error event in %q", out)
+	}
+
+	var u store.ModelUsage
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if u = s.Get("alice")["llama3.2:1b"]; u.AbortedRequests > 0 {
+			break
+		}
+	}
+	if u.AbortedRequests != 1 {
+		t.Errorf("timed-out stream should be billed as aborted, got %d", u.AbortedRequests)
+	}
+}
+
+func TestCompletions_FirstTokenTimeout(t *testing.T) {
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(hung.Close)
+
+	pool, _ := upstream.NewPool([]string{hung.URL}, nil)
+	pool.Retry = upstream.RetryPolicy{MaxAttempts: 3}
+	pool.Timeouts.Default = upstream.Timeouts{FirstToken: 50 * time.Millisecond}
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"llama3.2:1b","stream":true,"messages":[]}`))
+	rec := httptest.NewRecorder()
+	newServer(pool, store.New(), limiter.New()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("got %d, want 504", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "first_token_timeout") {
+		t.Errorf("body %q should name the first_token_timeout", rec.Body.String())
+	}
+	if n := pool.Backends()[0].Attempts(); n != 1 {
+		t.Errorf("a timed-out attempt must not be retried, got %d attempts", n)
+	}
+}
+
+func TestCompletions_FirstTokenTimeoutSparesNonStreamed(t *testing.T) {
+	// A non-streamed reply is only written once generation is done, so its
+	// first byte arrives long after the model produced its first token.
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		time.Sleep(300 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":4}}`)
+	}))
+	t.Cleanup(slow.Close)
+
+	pool, _ := upstream.NewPool([]string{slow.URL}, nil)
+	pool.Timeouts.Default = upstream.Timeouts{FirstToken: 100 * time.Millisecond, Total: 10 * time.Second}
+	rec := postCompletion(newServer(pool, store.New(), limiter.New()))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200 (body %q)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCompletions_ConcurrencySlotHeldForStream(t *testing.T) {
+	unblock := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"tok\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-unblock:
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+
+	pool, _ := upstream.NewPool([]string{slow.URL}, nil)
+	lim := limiter.New()
+	lim.SetMaxConcurrent("alice", 1)
+	proxy := httptest.NewServer(newServer(pool, store.New(), lim))
+	t.Cleanup(proxy.Close)
+
+	post := func() *http.Response {
+		resp, err := http.Post(proxy.URL+"/v1/chat/completions", "application/json",
+			strings.NewReader(`{"model":"llama3.2:1b","stream":true,"messages":[]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	first := post()
+	if first.StatusCode != http.StatusOK {
+		t.Fatalf("first stream: got %d", first.StatusCode)
+	}
+	bufio.NewReader(first.Body).ReadString('\n') // stream is open
+
+	second := post()
+	second.Body.Close()
+	if second.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second concurrent stream: got %d, want 429", second.StatusCode)
+	}
+
+	close(unblock)
+	io.ReadAll(first.Body)
+	first.Body.Close()
+
+	var last *http.Response
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		last = post()
+		io.ReadAll(last.Body)
+		last.Body.Close()
+		if last.StatusCode == http.StatusOK {
+			break
+		}
+	}
+	if last.StatusCode != http.StatusOK {
+		t.Fatalf("slot should be released once the stream finished, got %d", last.StatusCode)
+	}
+}
+
+func TestCompletions_RejectsWhenReservationCannotFit(t *testing.T) {
+	up, hits := fakeOllama(t, 0, false)
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	lim := limiter.New()
+	lim.SetLimits("alice", 100, 50, 40) // quota 50, per-request cap 40
+	lim.ConsumeTokens("alice", "", 20)  // 30 left, but a request may use up to 40
+	e := newServer(pool, store.New(), lim)
+
+	rec := postCompletion(e)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403 (body %q)", rec.Code, rec.Body.String())
+	}
+	if hits.Load() != 0 {
+		t.Error("rejected request must not reach the upstream")
+	}
+}
+
+func TestCompletions_SuspendedUserGets403WithReason(t *testing.T) {
+	up, hits := fakeOllama(t, 0, false)
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	lim := limiter.New()
+	lim.Suspend("alice", limiter.Suspension{Reason: "unpaid invoice", At: time.Now()})
+	e := newServer(pool, store.New(), lim)
+
+	rec := postCompletion(e)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "unpaid invoice") {
+		t.Errorf("body %q should state the reason", rec.Body.String())
+	}
+	if hits.Load() != 0 {
+		t.Error("suspended request must not reach the upstream")
+	}
+
+	lim.Unsuspend("alice")
+	if rec := postCompletion(e); rec.Code != http.StatusOK {
+		t.Fatalf("after unsuspend got %d, want 200", rec.Code)
+	}
+}
+
+func TestCompletions_RateLimitHeaders(t *testing.T) {
+	up, _ := fakeOllama(t, 0, false)
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	lim := limiter.New()
+	lim.SetLimits("alice", 2, 1000, 100)
+	e := newServer(pool, store.New(), lim)
+
+	rec := postCompletion(e)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200", rec.Code)
+	}
+	h := rec.Header()
+	if got := h.Get("x-ratelimit-limit-requests"); got != "2" {
+		t.Errorf("x-ratelimit-limit-requests = %q, want 2", got)
+	}
+	if got := h.Get("x-ratelimit-remaining-requests"); got != "1" {
+		t.Errorf("x-ratelimit-remaining-requests = %q, want 1", got)
+	}
+	// The worst-case cost (the 100-token cap) is reserved while in flight.
+	if got := h.Get("x-ratelimit-remaining-tokens"); got != "900" {
+		t.Errorf("x-ratelimit-remaining-tokens = %q, want 900", got)
+	}
+	if h.Get("x-ratelimit-reset-requests") == "" {
+		t.Error("missing x-ratelimit-reset-requests")
+	}
+
+	postCompletion(e)
+	rec = postCompletion(e)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request got %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" || rec.Header().Get("x-ratelimit-remaining-requests") != "0" {
+		t.Errorf("429 should carry Retry-After and remaining 0, got headers %v", rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), `"code":"rate_limit_exceeded"`) {
+		t.Errorf("429 body should use the OpenAI error envelope, got %q", rec.Body.String())
+	}
+}
+
+func TestCompletions_StreamCutOffAtQuota(t *testing.T) {
+	cancelled := make(chan struct{})
+	runaway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for {
+			fmt.Fprint(w, "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"model\":\"llama3.2:1b\",\"choices\":[{\"delta\":{\"content\":\"tok\"}}]}\n\n")
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				close(cancelled)
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}))
+	t.Cleanup(runaway.Close)
+
+	pool, _ := upstream.NewPool([]string{runaway.URL}, nil)
+	s := store.New()
+	lim := limiter.New()
+	lim.SetLimits("alice", 100, 50, limiter.INF_TOKEN_PER_REQ) // quota 50, no per-request cap
+	lim.ConsumeTokens("alice", "", 20)                         // 30 left
+	proxy := httptest.NewServer(newServer(pool, s, lim))
+	t.Cleanup(proxy.Close)
+
+	body := `{"model":"llama3.2:1b","stream":true,"messages":[{"role":"user","content":"sixteen chars!!!"}]}`
+	resp, err := http.Post(proxy.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := strings.Split(strings.TrimSpace(string(out)), "\n\n")
+	if len(frames) < 2 || frames[len(frames)-1] != "data: [DONE]" {
+		t.Fatalf("stream should end with [DONE], got %q", out)
+	}
+	final := frames[len(frames)-2]
+	for _, want := range []string{`"finish_reason":"length"`, `"prompt_tokens":4`, `"completion_tokens":26`, `"id":"chatcmpl-1"`} {
+		if !strings.Contains(final, want) {
+			t.Errorf("final chunk %q should contain %s", final, want)
+		}
+	}
+	if n := strings.Count(string(out), `"content":"tok"`); n != 26 {
+		t.Errorf("forwarded %d content chunks, want the 26 that fit", n)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream generation was not stopped")
+	}
+	var u store.ModelUsage
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if u = s.Get("alice")["llama3.2:1b"]; u.CompletionTokens > 0 {
+			break
+		}
+	}
+	if u.PromptTokens+u.CompletionTokens != 30 || u.AbortedRequests != 0 {
+		t.Errorf("billed %+v, want exactly the 30 tokens left and no abort", u)
+	}
+	if left := lim.Allowance("alice", "llama3.2:1b", nil); left != 0 {
+		t.Errorf("allowance after the stream = %d, want 0", left)
+	}
+}
+
+func TestCompletions_StreamPaced(t *testing.T) {
+	finished := make(chan time.Time, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 20; i++ {
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"tok\"}}]}\n\n")
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		finished <- time.Now()
+	}))
+	t.Cleanup(fast.Close)
+
+	pool, _ := upstream.NewPool([]string{fast.URL}, nil)
+	lim := limiter.New()
+	pace := int64(100)
+	if _, err := lim.UpdateLimits("alice", limiter.LimitUpdate{StreamPace: &pace}); err != nil {
+		t.Fatal(err)
+	}
+	st := store.New()
+	proxy := httptest.NewServer(newServer(pool, st, lim))
+	t.Cleanup(proxy.Close)
+
+	start := time.Now()
+	body := `{"model":"llama3.2:1b","stream":true,"messages":[{"role":"user","content":"hi"}]}`
+	resp, err := http.Post(proxy.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+
+	if n := strings.Count(string(out), `"content":"tok"`); n != 20 || !strings.HasSuffix(string(out), "data: [DONE]\n\n") {
+		t.Fatalf("got %d chunks in %q, want all 20 and [DONE]", n, out)
+	}
+	if elapsed < 190*time.Millisecond {
+		t.Errorf("20 tokens at 100/s were delivered in %s", elapsed)
+	}
+	if upstreamDone := (<-finished).Sub(start); upstreamDone > elapsed/2 {
+		t.Errorf("upstream was held back by the pace: it finished after %s of %s", upstreamDone, elapsed)
+	}
+
+	// Compute is billed for the time the upstream took, not the pacing.
+	var compute time.Duration
+	for deadline := time.Now().Add(time.Second); compute == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		compute = st.Get("alice")["llama3.2:1b"].Compute
+	}
+	if compute == 0 || compute > elapsed/2 {
+		t.Errorf("compute billed %s for a stream the upstream sent at once and the client read in %s", compute, elapsed)
+	}
+}
+
+func TestCompute_ReportedOrWallClock(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/chat" {
+			fmt.Fprint(w, `{"message":{"content":"hi"},"done":true,"prompt_eval_count":3,"eval_count":2,"total_duration":1500000000}`)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"hi"}}],"usage":{"prompt_tokens":3,"completion_tokens":2}}`)
+	}))
+	t.Cleanup(ollama.Close)
+
+	pool, _ := upstream.NewPool([]string{ollama.URL}, nil)
+	s := store.New()
+	lim := limiter.New()
+	e := newServer(pool, s, lim)
+	e.POST("/api/chat", handler.Native(pool, s, lim), func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(auth.UserIDKey, "alice")
+			c.Set(auth.AdminCtxKey, false)
+			return next(c)
+		}
+	})
+
+	if rec := postCompletion(e); rec.Code != http.StatusOK {
+		t.Fatalf("completion: got %d, want 200", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{"model":"moondream","stream":false,"messages":[]}`))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("native chat: got %d, want 200", rec.Code)
+	}
+
+	var usage map[string]store.ModelUsage
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if usage = s.Get("alice"); usage["llama3.2:1b"].Compute > 0 && usage["moondream"].Compute > 0 {
+			break
+		}
+	}
+	if u := usage["moondream"]; u.Compute != 1500*time.Millisecond || u.WallClockRequests != 0 {
+		t.Errorf("native: got %s (%d wall-clock), want the reported 1.5s", u.Compute, u.WallClockRequests)
+	}
+	if u := usage["llama3.2:1b"]; u.Compute < 50*time.Millisecond || u.Compute > time.Second || u.WallClockRequests != 1 {
+		t.Errorf("OpenAI-compatible: got %s (%d wall-clock), want ~50ms measured by wall clock", u.Compute, u.WallClockRequests)
+	}
+	if q := lim.Quota("alice"); q.UsedComputeSeconds < 1.55 {
+		t.Errorf("compute billed against the quota: %.3fs, want both requests", q.UsedComputeSeconds)
+	}
+}
+
+func TestCompletions_ReservationHeldUntilUsageConsumed(t *testing.T) {
+	up, _ := fakeOllama(t, 0, false)
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	lim := limiter.New()
+	lim.SetLimits("alice", 100, 1000, 40)
+	e := newServer(pool, store.New(), lim)
+
+	if rec := postCompletion(e); rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200", rec.Code)
+	}
+	// The handler has returned; the tokens must still count as reserved or used.
+	if info := lim.Limits("alice"); info.ReservedTokens+info.UsedTokens == 0 {
+		t.Fatal("reservation was released before the usage was consumed")
+	}
+	var info limiter.LimitInfo
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if info = lim.Limits("alice"); info.ReservedTokens == 0 {
+			break
+		}
+	}
+	if info.ReservedTokens != 0 || info.UsedTokens != 7 {
+		t.Errorf("after accounting: reserved %d, used %d; want 0 and 7", info.ReservedTokens, info.UsedTokens)
+	}
+
+	dead, _ := upstream.NewPool([]string{"http://127.0.0.1:1"}, nil)
+	dead.Retry = upstream.RetryPolicy{MaxAttempts: 1}
+	if rec := postCompletion(newServer(dead, store.New(), lim)); rec.Code != http.StatusBadGateway {
+		t.Fatalf("got %d, want 502", rec.Code)
+	}
+	if info := lim.Limits("alice"); info.ReservedTokens != 0 {
+		t.Errorf("a request that never reached accounting must release its reservation, %d still reserved", info.ReservedTokens)
+	}
+}
+
+func TestCompletions_RejectedRequestsDoNotCountTowardsRequestsPerDay(t *testing.T) {
+	ollama, _ := fakeOllama(t, 0, false)
+	pool, _ := upstream.NewPool([]string{ollama.URL}, nil)
+	lim := limiter.New()
+	lim.SetWindows("alice", limiter.WindowLimits{RequestsPerDay: 2})
+	lim.SetAllowedModels("alice", []string{"moondream"})
+	e := newServer(pool, store.New(), lim)
+
+	for i := 0; i < 3; i++ {
+		if rec := postCompletion(e); rec.Code != http.StatusForbidden {
+			t.Fatalf("disallowed model: got %d, want 403", rec.Code)
+		}
+	}
+	// Rejected at the reservation, after the allowlist and the slot.
+	lim.SetAllowedModels("alice", nil)
+	lim.SetLimits("alice", 100, 10, 40) // a 40-token budget cannot fit a quota of 10
+	if rec := postCompletion(e); rec.Code != http.StatusForbidden {
+		t.Fatalf("over quota: got %d, want 403", rec.Code)
+	}
+	if used := lim.Limits("alice").WindowUsage[limiter.WindowRequestsPerDay]; used != 0 {
+		t.Fatalf("requests_per_day used: got %d after rejections only, want 0", used)
+	}
+
+	lim.SetLimits("alice", 100, limiter.INF_TOKENS, limiter.INF_TOKEN_PER_REQ)
+	for i := 0; i < 2; i++ {
+		if rec := postCompletion(e); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d, want the whole daily budget of 2", i, rec.Code)
+		}
+	}
+	if rec := postCompletion(e); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("third admitted request: got %d, want 429", rec.Code)
+	}
+}
+
+func TestCompletions_StreamPacingLeftOutOfTotalTimeout(t *testing.T) {
+	// More chunks than the read-ahead buffer holds, so the proxy also stops
+	// reading the upstream until the client catches up.
+	const chunks = 600
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < chunks; i++ {
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"tok\"}}]}\n\n")
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(fast.Close)
+
+	pool, _ := upstream.NewPool([]string{fast.URL}, nil)
+	pool.Timeouts.Default = upstream.Timeouts{Total: 200 * time.Millisecond}
+	lim := limiter.New()
+	pace := int64(1000)
+	if _, err := lim.UpdateLimits("alice", limiter.LimitUpdate{StreamPace: &pace}); err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(newServer(pool, store.New(), lim))
+	t.Cleanup(proxy.Close)
+
+	start := time.Now()
+	body := `{"model":"llama3.2:1b","stream":true,"messages":[{"role":"user","content":"hi"}]}`
+	resp, err := http.Post(proxy.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+
+	if strings.Contains(string(out), "total_timeout") {
+		t.Fatalf("pacing counted against the total timeout: %q", out[max(len(out)-300, 0):])
+	}
+	if n := strings.Count(string(out), `"content":"tok"`); n != chunks || !strings.HasSuffix(string(out), "data: [DONE]\n\n") {
+		t.Fatalf("got %d chunks, want all %d and [DONE]", n, chunks)
+	}
+	if elapsed < 550*time.Millisecond {
+		t.Errorf("%d tokens at %d/s were delivered in %s: pacing was cut short", chunks, pace, elapsed)
+	}
+}
+
+func TestLegacyCompletions_ForwardedAndBilled(t *testing.T) {
+	var path string
+	var forwarded []byte
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		forwarded, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"cmpl-1","object":"text_completion","choices":[{"text":"hi","index":0}],"usage":{"prompt_tokens":5,"completion_tokens":2}}`)
+	}))
+	t.Cleanup(up.Close)
+
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	s := store.New()
+	lim := limiter.New()
+	lim.SetLimits("alice", 100, 1000, 64)
+	e := newServer(pool, s, lim)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/completions",
+		strings.NewReader(`{"model":"llama3.2:1b","stream":false,"prompt":"Say hi","max_tokens":500}`))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200 (body %q)", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"text":"hi"`) {
+		t.Errorf("client got %q, want the upstream's completion", rec.Body.String())
+	}
+	if path != "/v1/completions" {
+		t.Errorf("forwarded to %q, want /v1/completions", path)
+	}
+	if !strings.Contains(string(forwarded), `"max_tokens":64`) {
+		t.Errorf("forwarded body %s should cap max_tokens at 64", forwarded)
+	}
+
+	u := waitUsage(s, "llama3.2:1b", func(u store.ModelUsage) bool { return u.CompletionTokens > 0 })
+	if u.PromptTokens != 5 || u.CompletionTokens != 2 || u.EstimatedRequests != 0 {
+		t.Errorf("billed %+v, want the reported 5 and 2", u)
+	}
+	if used := lim.Limits("alice").UsedTokens; used != 7 {
+		t.Errorf("quota used: got %d, want 7", used)
+	}
+}
+
+func TestLegacyCompletions_StreamEstimatedAndCutOff(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 30; i++ {
+			fmt.Fprint(w, "data: {\"id\":\"cmpl-2\",\"object\":\"text_completion\",\"model\":\"llama3.2:1b\",\"choices\":[{\"text\":\"tok\",\"index\":0}]}\n\n")
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(up.Close)
+
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	s := store.New()
+	lim := limiter.New()
+	lim.SetLimits("alice", 100, 20, 16) // a 4-token prompt leaves room for 16 tokens
+	proxy := httptest.NewServer(newServer(pool, s, lim))
+	t.Cleanup(proxy.Close)
+
+	body := `{"model":"llama3.2:1b","stream":true,"prompt":"sixteen chars!!!"}`
+	resp, err := http.Post(proxy.URL+"/v1/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(out), `"text":"tok"`); n != 16 {
+		t.Errorf("forwarded %d text chunks, want the 16 that fit", n)
+	}
+	frames := strings.Split(strings.TrimSpace(string(out)), "\n\n")
+	if len(frames) < 2 || frames[len(frames)-1] != "data: [DONE]" {
+		t.Fatalf("stream should end with [DONE], got %q", out)
+	}
+	final := frames[len(frames)-2]
+	for _, want := range []string{`"object":"text_completion"`, `"text":""`, `"finish_reason":"length"`, `"completion_tokens":16`} {
+		if !strings.Contains(final, want) {
+			t.Errorf("final chunk %q should contain %s", final, want)
+		}
+	}
+
+	u := waitUsage(s, "llama3.2:1b", func(u store.ModelUsage) bool { return u.CompletionTokens > 0 })
+	if u.PromptTokens != 4 || u.CompletionTokens != 16 || u.EstimatedRequests != 1 {
+		t.Errorf("billed %+v, want an estimate of 4 prompt and 16 text tokens", u)
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"lb/limiter"
+	"lb/store"
+	"net/http"
+	"time"
+)
+
+// upstreamDurations are the timings, in nanoseconds, that Ollama's native API
+// reports on the final object of a response. The OpenAI-compatible endpoints
+// do not report them.
+type upstreamDurations struct {
+	TotalDuration      int64 `json:"total_duration"`
+	PromptEvalDuration int64 `json:"prompt_eval_duration"`
+	EvalDuration       int64 `json:"eval_duration"`
+}
+
+// reportedCompute returns the upstream time reported in a native response
+// object: total_duration, else prompt_eval_duration plus eval_duration, else 0.
+func reportedCompute(data []byte) time.Duration {
+	var d upstreamDurations
+	if len(data) == 0 || json.Unmarshal(data, &d) != nil {
+		return 0
+	}
+	if d.TotalDuration > 0 {
+		return time.Duration(d.TotalDuration)
+	}
+	return time.Duration(d.PromptEvalDuration + d.EvalDuration)
+}
+
+// upstreamTime returns the wall-clock time the response's attempt took
+// upstream: from when it was sent, after queueing, to when its body ended.
+// Time the proxy spent holding the stream back (see pacedBody) is not
+// included; a body still being read counts up to now.
+func upstreamTime(resp *http.Response) time.Duration {
+	a := resp.Request.Context().Value(ctxKeyAttempt{}).(*attempt)
+	end := a.deadline.endedAt()
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(a.sent)
+}
+
+// recordCompute bills the upstream time one request took to the user + model
+// and against the user's compute quota: reported when the upstream measured
+// it, else elapsed as measured by the proxy.
+func recordCompute(s *store.Store, lim *limiter.Limiter, user, model string, reported, elapsed time.Duration) {
+	d, wallClock := reported, false
+	if d <= 0 {
+		d, wallClock = elapsed, true
+	}
+	s.AddCompute(user, model, d, wallClock)
+	lim.ConsumeCompute(user, d)
+}
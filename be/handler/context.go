@@ -1,11 +1,23 @@
 package handler
 
-import "context"
+import (
+	"context"
+	"lb/limiter"
+	"lb/upstream"
+)
 
 // Private context key types to avoid collisions.
 type ctxKeyUser struct{}
 type ctxKeyModel struct{}
 type ctxKeyStream struct{}
+type ctxKeyBackend struct{}
+type ctxKeyAttempt struct{}
+type ctxKeyPromptEstimate struct{}
+type ctxKeyReservation struct{}
+type ctxKeyQueueWeight struct{}
+type ctxKeyPriority struct{}
+type ctxKeyQuotaCutoff struct{}
+type ctxKeyStreamPace struct{}
 
 // contextWith returns a new context carrying user, model, and streaming flag.
 func contextWith(ctx context.Context, user, model string, isStream bool) context.Context {
@@ -14,3 +26,82 @@ func contextWith(ctx context.Context, user, model string, isStream bool) context
 	ctx = context.WithValue(ctx, ctxKeyStream{}, isStream)
 	return ctx
 }
+
+// withPromptEstimate attaches the proxy's own estimate of the prompt size.
+// It is only billed when the upstream never reports real usage.
+func withPromptEstimate(ctx context.Context, tokens int) context.Context {
+	return context.WithValue(ctx, ctxKeyPromptEstimate{}, tokens)
+}
+
+// promptEstimate returns the estimate attached by withPromptEstimate, or 0.
+func promptEstimate(ctx context.Context) int {
+	n, _ := ctx.Value(ctxKeyPromptEstimate{}).(int)
+	return n
+}
+
+// withReservation attaches the quota reservation taken at admission, released
+// by accounting once the request's real usage has been recorded.
+func withReservation(ctx context.Context, r *limiter.Reservation) context.Context {
+	return context.WithValue(ctx, ctxKeyReservation{}, r)
+}
+
+// reservation returns the reservation attached by withReservation, or nil.
+// Accounting holds it until the request's usage has been consumed: each
+// accounting goroutine defers its Release, which also covers early returns.
+func reservation(ctx context.Context) *limiter.Reservation {
+	r, _ := ctx.Value(ctxKeyReservation{}).(*limiter.Reservation)
+	return r
+}
+
+// withQueueWeight attaches the user's share of a saturated upstream, relative
+// to other users (see upstream.Pool.Admit).
+func withQueueWeight(ctx context.Context, w float64) context.Context {
+	return context.WithValue(ctx, ctxKeyQueueWeight{}, w)
+}
+
+// queueWeight returns the weight attached by withQueueWeight, or 1.
+func queueWeight(ctx context.Context) float64 {
+	if w, ok := ctx.Value(ctxKeyQueueWeight{}).(float64); ok {
+		return w
+	}
+	return 1
+}
+
+// withPriority attaches the queue lane resolved by requestPriority.
+func withPriority(ctx context.Context, p upstream.Priority) context.Context {
+	return context.WithValue(ctx, ctxKeyPriority{}, p)
+}
+
+// priority returns the lane attached by withPriority, or interactive.
+func priority(ctx context.Context) upstream.Priority {
+	p, _ := ctx.Value(ctxKeyPriority{}).(upstream.Priority)
+	return p
+}
+
+// withQuotaCutoff marks a streamed request to be ended once it exhausts the
+// user's remaining quota or a token window (see quotaBody). Admins are never
+// cut off.
+func withQuotaCutoff(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyQuotaCutoff{}, true)
+}
+
+// quotaCutoff reports whether withQuotaCutoff marked the request.
+func quotaCutoff(ctx context.Context) bool {
+	on, _ := ctx.Value(ctxKeyQuotaCutoff{}).(bool)
+	return on
+}
+
+// withStreamPace attaches the generated tokens per second a streamed response
+// may be sent at (see pacedBody).
+func withStreamPace(ctx context.Context, tps int64) context.Context {
+	return context.WithValue(ctx, ctxKeyStreamPace{}, tps)
+}
+
+// streamPace returns the pace attached by withStreamPace; limiter.INF_TOKENS
+// (the default) = unpaced.
+func streamPace(ctx context.Context) int64 {
+	if tps, ok := ctx.Value(ctxKeyStreamPace{}).(int64); ok {
+		return tps
+	}
+	return limiter.INF_TOKENS
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"lb/limiter"
+	"log"
+)
+
+// allowanceRefresh is how many completion tokens a quotaBody forwards between
+// fresh looks at its allowance, which takes the limiter's lock. In between it
+// works from the last one, so concurrent streams sharing a window can each run
+// past it by at most this much.
+const allowanceRefresh = 32
+
+// quotaBody wraps an OpenAI-style SSE response body and counts the completion
+// tokens it carries, line by line. Once the next chunk would take the request
+// past its allowance (see limiter.Allowance), that chunk is dropped, the
+// upstream body is closed so generation stops, and the stream ends with a
+// final chunk carrying finish_reason "length" and the usage billed, then
+// [DONE].
+type quotaBody struct {
+	io.ReadCloser
+	br        *bufio.Reader
+	allowance func() int64 // tokens the request may use in all; -1 = unlimited
+	left      int64        // allowance as of the last refresh
+	checked   int          // streamed as of the last refresh
+	prompt    int          // estimated prompt tokens, counted against the allowance
+	streamed  int          // completion tokens forwarded so far
+	last      chunkHeader  // identity of the latest chunk, reused for the final one
+	pending   []byte       // bytes read but not yet returned
+	cut       bool         // the stream was ended by the allowance; set before the final chunk is returned
+	done      bool         // nothing left to read from upstream
+}
+
+// chunkHeader is the identity of a streamed chunk.
+type chunkHeader struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+}
+
+func newQuotaBody(body io.ReadCloser, prompt int, allowance func() int64) *quotaBody {
+	b := &quotaBody{ReadCloser: body, br: bufio.NewReader(body), allowance: allowance, prompt: prompt}
+	b.refresh()
+	return b
+}
+
+// refresh takes a fresh look at the allowance.
+func (b *quotaBody) refresh() {
+	b.left, b.checked = b.allowance(), b.streamed
+}
+
+// exceeds reports whether n more completion tokens would take the request
+// past the allowance as of the last refresh.
+func (b *quotaBody) exceeds(n int) bool {
+	return b.left != limiter.INF_TOKENS && int64(b.prompt+b.streamed+n) > b.left
+}
+
+func (b *quotaBody) Read(p []byte) (int, error) {
+	for len(b.pending) == 0 {
+		if b.done {
+			return 0, io.EOF
+		}
+		line, err := b.br.ReadBytes('\n')
+		if len(line) > 0 {
+			b.pending = b.admit(line)
+		}
+		if err != nil {
+			b.done = true
+			if len(b.pending) == 0 {
+				return 0, err
+			}
+		}
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+// admit returns what to forward for one upstream line: the line itself, or
+// the final chunk if forwarding it would exceed the allowance.
+func (b *quotaBody) admit(line []byte) []byte {
+	data, ok := bytes.CutPrefix(bytes.TrimRight(line, "\r\n"), []byte("data: "))
+	var chunk choicesPayload
+	if !ok || json.Unmarshal(data, &chunk) != nil {
+		return line // [DONE], blank lines and anything else that carries no tokens
+	}
+	_ = json.Unmarshal(data, &b.last)
+	n := chunk.generatedTokens()
+	if n == 0 {
+		return line
+	}
+	if b.streamed-b.checked >= allowanceRefresh {
+		b.refresh()
+	}
+	if b.exceeds(n) {
+		b.refresh() // never cut a stream off on a stale allowance
+		if b.exceeds(n) {
+			return b.cutOff()
+		}
+	}
+	b.streamed += n
+	return line
+}
+
+// cutOff closes the upstream body and renders the stream's final chunk.
+func (b *quotaBody) cutOff() []byte {
+	b.cut, b.done = true, true
+	b.ReadCloser.Close()
+	log.Printf("[quota] stream %s cut off after %d completion tokens", b.last.ID, b.streamed)
+
+	finish := map[string]any{"index": 0, "finish_reason": "length"}
+	if b.last.Object == "text_completion" {
+		finish["text"] = "" // legacy /v1/completions
+	} else {
+		finish["delta"] = map[string]any{}
+	}
+	object := b.last.Object
+	if object == "" {
+		object = "chat.completion.chunk"
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"id":      b.last.ID,
+		"object":  object,
+		"created": b.last.Created,
+		"model":   b.last.Model,
+		"choices": []any{finish},
+		"usage": map[string]int{
+			"prompt_tokens":     b.prompt,
+			"completion_tokens": b.streamed,
+			"total_tokens":      b.prompt + b.streamed,
+		},
+	})
+	out := append([]byte("data: "), payload...)
+	return append(out, "\n\ndata: [DONE]\n\n"...)
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"lb/upstream"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Causes attached to a request context when one of its deadlines fires.
+var (
+	errFirstTokenTimeout = errors.New("upstream timed out before sending the first token")
+	errIdleTimeout       = errors.New("upstream stalled between streamed chunks")
+	errTotalTimeout      = errors.New("request exceeded its total time limit")
+)
+
+// timeoutCode maps a context cause to the error code reported to clients.
+// ok is false when the cause is not one of the proxy's deadlines.
+func timeoutCode(cause error) (code string, ok bool) {
+	switch {
+	case errors.Is(cause, errFirstTokenTimeout):
+		return "first_token_timeout", true
+	case errors.Is(cause, errIdleTimeout):
+		return "idle_timeout", true
+	case errors.Is(cause, errTotalTimeout):
+		return "total_timeout", true
+	}
+	return "", false
+}
+
+// totalTimer enforces the total timeout of a request, across its attempts, by
+// calling cancel once the budget is spent. Unlike a context deadline it can
+// be paused while the proxy itself holds the stream back (see pacedBody), and
+// it stops once the upstream body has ended: pacing out what is left is not
+// the upstream's time. A nil *totalTimer (no total timeout) does nothing.
+type totalTimer struct {
+	timer   *time.Timer
+	due     time.Time     // when the timer fires while running
+	left    time.Duration // budget left while paused
+	paused  bool
+	stopped bool
+}
+
+func startTotal(budget time.Duration, cancel func()) *totalTimer {
+	return &totalTimer{timer: time.AfterFunc(budget, cancel), due: time.Now().Add(budget)}
+}
+
+// pause stops the clock until resume.
+func (t *totalTimer) pause() {
+	if t == nil || t.paused || t.stopped {
+		return
+	}
+	t.timer.Stop()
+	t.left, t.paused = time.Until(t.due), true
+}
+
+// resume restarts the clock with the budget left at pause.
+func (t *totalTimer) resume() {
+	if t == nil || !t.paused || t.stopped {
+		return
+	}
+	t.due, t.paused = time.Now().Add(t.left), false
+	t.timer.Reset(t.left)
+}
+
+// stop disarms the timer for good.
+func (t *totalTimer) stop() {
+	if t == nil {
+		return
+	}
+	t.stopped = true
+	t.timer.Stop()
+}
+
+// deadline enforces the first-token and idle timeouts of one upstream attempt
+// by cancelling the attempt's context when the current timer fires. It also
+// pauses and stops the request's total timer as the body is held and ends.
+type deadline struct {
+	idle     time.Duration
+	cancel   context.CancelCauseFunc
+	total    *totalTimer   // shared by the request's attempts; nil = none
+	timer    *time.Timer   // nil while no timer applies
+	started  bool          // first body byte seen
+	heldFrom time.Time     // start of the current hold; zero while reading
+	held     time.Duration // total time spent in holds so far
+	ended    atomic.Int64  // see finish; read by the accounting goroutine
+}
+
+// newDeadline derives the attempt context from ctx and arms the first-token timer.
+func newDeadline(ctx context.Context, t upstream.Timeouts, total *totalTimer) (context.Context, *deadline) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	d := &deadline{idle: t.Idle, cancel: cancel, total: total}
+	if t.FirstToken > 0 {
+		d.timer = time.AfterFunc(t.FirstToken, func() { cancel(errFirstTokenTimeout) })
+	}
+	return ctx, d
+}
+
+// progress records that body bytes arrived: the first call swaps the
+// first-token timer for the idle timer, later calls push the idle timer back.
+func (d *deadline) progress() {
+	if !d.heldFrom.IsZero() {
+		d.held += time.Since(d.heldFrom)
+		d.heldFrom = time.Time{}
+		d.total.resume()
+	}
+	if d.started {
+		if d.timer != nil {
+			d.timer.Reset(d.idle)
+		}
+		return
+	}
+	d.started = true
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.idle > 0 {
+		d.timer = time.AfterFunc(d.idle, func() { d.cancel(errIdleTimeout) })
+	}
+}
+
+// hold disarms the idle timer and pauses the total one while the proxy itself
+// stops reading the body (see pacedBody); the next progress call re-arms them
+// and adds the time held to held.
+func (d *deadline) hold() {
+	d.heldFrom = time.Now()
+	if d.started && d.timer != nil {
+		d.timer.Stop()
+	}
+	d.total.pause()
+}
+
+// finish records that the upstream body ended, as the time it would have
+// ended had the proxy never held it back, and stops the total timer. Only the
+// first call counts.
+func (d *deadline) finish() {
+	if d.ended.CompareAndSwap(0, time.Now().Add(-d.held).UnixNano()) {
+		d.total.stop()
+	}
+}
+
+// endedAt returns the time recorded by finish, or the zero time while the
+// body is still being read.
+func (d *deadline) endedAt() time.Time {
+	if n := d.ended.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
+}
+
+// stop disarms the timers and releases the attempt context.
+func (d *deadline) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.cancel(nil)
+}
+
+// deadlineBody wraps an upstream response body, feeding the deadline on every
+// read. When a deadline fires mid-body, the body ends cleanly with an error
+// event in the response's own framing rather than a dropped connection.
+type deadlineBody struct {
+	io.ReadCloser
+	ctx         context.Context
+	d           *deadline
+	contentType string
+	tail        io.Reader // error event still to be delivered, once a deadline fired
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	if b.tail != nil {
+		return b.tail.Read(p)
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.d.progress()
+	}
+	if err != nil {
+		b.d.finish()
+	}
+	if err == nil || err == io.EOF {
+		return n, err
+	}
+	cause := context.Cause(b.ctx)
+	code, ok := timeoutCode(cause)
+	if !ok {
+		return n, err
+	}
+	log.Printf("[timeout] %s: %v", code, cause)
+	b.tail = bytes.NewReader(timeoutEvent(b.contentType, code, cause))
+	if n > 0 {
+		return n, nil
+	}
+	return b.tail.Read(p)
+}
+
+// timeoutEvent renders the final error frame of a timed-out stream:
+// an SSE error event for OpenAI-style streams, an {"error": ...} line for
+// Ollama's NDJSON streams, and nothing for other bodies.
+func timeoutEvent(contentType, code string, cause error) []byte {
+	switch {
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		payload, _ := json.Marshal(map[string]any{"error": map[string]string{
+			"message": cause.Error(),
+			"type":    "timeout_error",
+			"code":    code,
+		}})
+		return []byte("event: error\ndata: " + string(payload) + "\n\n")
+	case strings.HasPrefix(contentType, "application/x-ndjson"):
+		payload, _ := json.Marshal(map[string]string{"error": cause.Error()})
+		return append(payload, '\n')
+	}
+	return nil
+}
+
+// writeTimeout answers a request whose deadline fired before any response was
+// written, as a 504 in the OpenAI error envelope.
+func writeTimeout(w http.ResponseWriter, code string, cause error) {
+	writeOpenAIError(w, http.StatusGatewayTimeout, "timeout_error", code, cause.Error())
+}
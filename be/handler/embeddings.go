@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"lb/auth"
+	"lb/limiter"
+	"lb/store"
+	"lb/upstream"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// embeddingUsagePayload is the shape of the usage field in embeddings responses.
+// Embeddings have no completion phase, so only prompt (and total) tokens are reported.
+type embeddingUsagePayload struct {
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// Embeddings handles POST /v1/embeddings.
+// It applies the same auth and rate/quota limits as Completions, proxies the
+// request to Ollama's OpenAI-compatible embeddings API, and records the
+// embedding tokens against the user's usage and quota.
+func Embeddings(pool *upstream.Pool, s *store.Store, lim *limiter.Limiter) echo.HandlerFunc {
+	proxy := newUpstreamProxy(pool, func(resp *http.Response) {
+		userKey := resp.Request.Context().Value(ctxKeyUser{}).(string)
+		model := resp.Request.Context().Value(ctxKeyModel{}).(string)
+		accountEmbeddings(resp, userKey, model, s, lim)
+	})
+
+	return func(c echo.Context) error {
+		userID := c.Get(auth.UserIDKey).(string)
+
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "failed to read body"})
+		}
+		var peek struct {
+			Model string `json:"model"`
+		}
+		_ = json.Unmarshal(body, &peek)
+		// Embeddings have no completion, so the input is the whole cost.
+		release, err := admitRequest(c, lim, userID, peek.Model, int64(estimatePromptTokens(body)))
+		if release == nil {
+			return err
+		}
+		defer release()
+
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		// Embeddings are never streamed.
+		c.SetRequest(c.Request().WithContext(contextWith(c.Request().Context(), userID, peek.Model, false)))
+
+		return serveProxy(c, proxy, pool, body)
+	}
+}
+
+// accountEmbeddings reads the embeddings response body, restores it for the
+// client, and records embedding tokens in the background.
+func accountEmbeddings(resp *http.Response, user, model string, s *store.Store, lim *limiter.Limiter) {
+	res := reservation(resp.Request.Context())
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		res.Release()
+		return
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	ok := resp.StatusCode == http.StatusOK
+	elapsed := upstreamTime(resp)
+
+	go func() {
+		defer res.Release()
+		if ok {
+			recordCompute(s, lim, user, model, 0, elapsed)
+		}
+		var p embeddingUsagePayload
+		if err := json.Unmarshal(body, &p); err != nil {
+			return
+		}
+		tokens := p.Usage.PromptTokens
+		if tokens == 0 {
+			tokens = p.Usage.TotalTokens
+		}
+		if tokens == 0 {
+			return
+		}
+		s.AddEmbedding(user, model, tokens)
+		lim.ConsumeTokens(user, model, tokens)
+	}()
+}
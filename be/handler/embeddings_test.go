@@ -0,0 +1,121 @@
+package handler_test
+
+import (
+	"io"
+	"lb/auth"
+	"lb/handler"
+	"lb/limiter"
+	"lb/store"
+	"lb/upstream"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newEmbeddingsServer wires Embeddings behind a stub auth middleware for user "alice".
+func newEmbeddingsServer(pool *upstream.Pool, s *store.Store, lim *limiter.Limiter) *echo.Echo {
+	e := echo.New()
+	e.POST("/v1/embeddings", handler.Embeddings(pool, s, lim), func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(auth.UserIDKey, "alice")
+			c.Set(auth.AdminCtxKey, false)
+			return next(c)
+		}
+	})
+	return e
+}
+
+// embeddingsOllama answers every embeddings request with reply. hits counts them.
+func embeddingsOllama(t *testing.T, status int, reply string) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// postEmbeddings sends input for nomic-embed-text to POST /v1/embeddings.
+func postEmbeddings(e *echo.Echo, input string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings",
+		strings.NewReader(`{"model":"nomic-embed-text","input":"`+input+`"}`))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestEmbeddings_BillsEmbeddingTokens(t *testing.T) {
+	cases := map[string]string{
+		"prompt tokens": `{"object":"list","data":[{"embedding":[0.1]}],"usage":{"prompt_tokens":6,"total_tokens":6}}`,
+		"total only":    `{"object":"list","data":[{"embedding":[0.1]}],"usage":{"total_tokens":6}}`,
+	}
+	for name, reply := range cases {
+		t.Run(name, func(t *testing.T) {
+			up, _ := embeddingsOllama(t, http.StatusOK, reply)
+			pool, _ := upstream.NewPool([]string{up.URL}, nil)
+			s := store.New()
+			lim := limiter.New()
+			e := newEmbeddingsServer(pool, s, lim)
+
+			if rec := postEmbeddings(e, "embed me"); rec.Code != http.StatusOK {
+				t.Fatalf("got %d, want 200 (body %q)", rec.Code, rec.Body.String())
+			}
+			u := waitUsage(s, "nomic-embed-text", func(u store.ModelUsage) bool { return u.EmbeddingTokens > 0 })
+			if u.EmbeddingTokens != 6 {
+				t.Errorf("embedding tokens = %d, want 6", u.EmbeddingTokens)
+			}
+			if u.PromptTokens != 0 || u.CompletionTokens != 0 {
+				t.Errorf("got prompt=%d completion=%d, want embeddings kept apart from chat tokens", u.PromptTokens, u.CompletionTokens)
+			}
+			if used := lim.Limits("alice").UsedTokens; used != 6 {
+				t.Errorf("quota used: got %d, want 6", used)
+			}
+		})
+	}
+}
+
+func TestEmbeddings_FailedRequestNotBilled(t *testing.T) {
+	up, _ := embeddingsOllama(t, http.StatusBadRequest, `{"error":"model does not support embeddings"}`)
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	s := store.New()
+	lim := limiter.New()
+	e := newEmbeddingsServer(pool, s, lim)
+
+	if rec := postEmbeddings(e, "embed me"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want the upstream's 400", rec.Code)
+	}
+	time.Sleep(50 * time.Millisecond) // accounting runs in the background
+	if u := s.Get("alice")["nomic-embed-text"]; u.EmbeddingTokens != 0 {
+		t.Errorf("embedding tokens = %d, want 0 for a failed request", u.EmbeddingTokens)
+	}
+	if info := lim.Limits("alice"); info.UsedTokens != 0 || info.ReservedTokens != 0 {
+		t.Errorf("used = %d, reserved = %d; want nothing left behind", info.UsedTokens, info.ReservedTokens)
+	}
+}
+
+func TestEmbeddings_InputOverQuotaRejected(t *testing.T) {
+	up, hits := embeddingsOllama(t, http.StatusOK, `{"usage":{"prompt_tokens":20}}`)
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	lim := limiter.New()
+	lim.SetLimits("alice", 100, 10, limiter.INF_TOKEN_PER_REQ)
+	e := newEmbeddingsServer(pool, store.New(), lim)
+
+	// 80 characters estimate to 20 tokens, more than the quota of 10.
+	rec := postEmbeddings(e, strings.Repeat("x", 80))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403 (body %q)", rec.Code, rec.Body.String())
+	}
+	if hits.Load() != 0 {
+		t.Error("rejected request must not reach the upstream")
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"lb/limiter"
+	"unicode/utf8"
+)
+
+// charsPerToken is the usual rule of thumb for BPE tokenizers on English text.
+// Estimates are only used when the upstream can't tell us the real count.
+const charsPerToken = 4
+
+// estimateTokens approximates the token count of text.
+func estimateTokens(text string) int {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return (n + charsPerToken - 1) / charsPerToken
+}
+
+// estimatePromptTokens approximates the prompt size of an OpenAI or native
+// Ollama request body from its messages (string or text-part content),
+// prompt, or embeddings input. Non-text parts such as images are not counted.
+func estimatePromptTokens(body []byte) int {
+	var req struct {
+		Messages []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Prompt json.RawMessage `json:"prompt"`
+		Input  json.RawMessage `json:"input"` // embeddings
+		System string          `json:"system"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return 0
+	}
+	n := estimateTokens(req.System) + rawTextTokens(req.Prompt) + rawTextTokens(req.Input)
+	for _, m := range req.Messages {
+		n += rawTextTokens(m.Content)
+	}
+	return n
+}
+
+// rawTextTokens estimates tokens in a JSON value that is a string, a list of
+// strings, or a list of {"type": "text", "text": ...} content parts.
+func rawTextTokens(raw json.RawMessage) int {
+	if len(raw) == 0 {
+		return 0
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return estimateTokens(s)
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(raw, &items) != nil {
+		return 0
+	}
+	n := 0
+	for _, item := range items {
+		var part struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(item, &s) == nil {
+			n += estimateTokens(s)
+		} else if json.Unmarshal(item, &part) == nil {
+			n += estimateTokens(part.Text)
+		}
+	}
+	return n
+}
+
+// completionBudget is the most completion tokens a request can generate: the
+// requested limit (max_tokens or num_predict), capped at the per-request cap.
+// A missing or non-positive request limit is unbounded, so only the cap
+// applies; 0 means no bound is known at all.
+func completionBudget(requested *int64, cap int64) int64 {
+	var n int64
+	if requested != nil && *requested > 0 {
+		n = *requested
+	}
+	if cap != limiter.INF_TOKEN_PER_REQ && (n == 0 || n > cap) {
+		n = cap
+	}
+	return n
+}
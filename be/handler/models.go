@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"lb/auth"
+	"lb/limiter"
+	"lb/upstream"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// modelList is the shape of Ollama's OpenAI-compatible GET /v1/models response.
+// Entries are kept raw so any fields we don't inspect pass through untouched.
+type modelList struct {
+	Object string            `json:"object"`
+	Data   []json.RawMessage `json:"data"`
+}
+
+// Models handles GET /v1/models.
+// It merges the model lists of every healthy Ollama backend (they may host
+// different models) and filters the result down to the models the caller is
+// allowed to use. Admins see the full list.
+func Models(pool *upstream.Pool, lim *limiter.Limiter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID := c.Get(auth.UserIDKey).(string)
+		admin := c.Get(auth.AdminCtxKey).(bool)
+
+		var (
+			mu      sync.Mutex
+			wg      sync.WaitGroup
+			seen    = make(map[string]bool)
+			merged  = modelList{Object: "list", Data: []json.RawMessage{}}
+			fetched bool
+		)
+		for _, b := range pool.Backends() {
+			if !b.Healthy() {
+				continue
+			}
+			wg.Add(1)
+			go func(b *upstream.Backend) {
+				defer wg.Done()
+				list, err := fetchModels(c.Request().Context(), b)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				fetched = true
+				for _, entry := range list.Data {
+					var m struct {
+						ID string `json:"id"`
+					}
+					if json.Unmarshal(entry, &m) != nil || seen[m.ID] {
+						continue
+					}
+					seen[m.ID] = true
+					if admin || lim.ModelAllowed(userID, m.ID) {
+						merged.Data = append(merged.Data, entry)
+					}
+				}
+			}(b)
+		}
+		wg.Wait()
+
+		if !fetched {
+			return openAIError(c, http.StatusBadGateway, "server_error", "upstream_error",
+				"upstream error: no backend returned a model list")
+		}
+		return c.JSON(http.StatusOK, merged)
+	}
+}
+
+// fetchModels retrieves GET /v1/models from a single backend.
+func fetchModels(ctx context.Context, b *upstream.Backend) (*modelList, error) {
+	b.Acquire()
+	defer b.Release()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.URL.JoinPath("/v1/models").String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream %s returned %d", b.URL, resp.StatusCode)
+	}
+	var list modelList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
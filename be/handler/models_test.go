@@ -0,0 +1,144 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"lb/auth"
+	"lb/handler"
+	"lb/limiter"
+	"lb/upstream"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// modelsOllama serves ids from GET /v1/models. A backend that is not healthy
+// fails its health probe but still lists its models.
+func modelsOllama(t *testing.T, healthy bool, ids ...string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			if !healthy {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"version":"0.0.0"}`))
+		case "/v1/models":
+			entries := make([]string, len(ids))
+			for i, id := range ids {
+				entries[i] = fmt.Sprintf(`{"id":%q,"object":"model","owned_by":"library"}`, id)
+			}
+			fmt.Fprintf(w, `{"object":"list","data":[%s]}`, strings.Join(entries, ","))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newModelsServer wires Models behind a stub auth middleware for user "alice".
+func newModelsServer(pool *upstream.Pool, lim *limiter.Limiter, admin bool) *echo.Echo {
+	e := echo.New()
+	e.GET("/v1/models", handler.Models(pool, lim), func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(auth.UserIDKey, "alice")
+			c.Set(auth.AdminCtxKey, admin)
+			return next(c)
+		}
+	})
+	return e
+}
+
+// getModels fetches GET /v1/models.
+func getModels(e *echo.Echo) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	return rec
+}
+
+// modelIDs decodes the ids of a 200 GET /v1/models reply, sorted.
+func modelIDs(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200 (body %q)", rec.Code, rec.Body.String())
+	}
+	var list struct {
+		Object string
+		Data   []struct{ ID string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	if list.Object != "list" {
+		t.Errorf("object = %q, want list", list.Object)
+	}
+	ids := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		ids = append(ids, m.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestModels_MergesHealthyBackends(t *testing.T) {
+	a := modelsOllama(t, true, "llama3.2:1b", "moondream:latest")
+	b := modelsOllama(t, true, "moondream:latest", "nomic-embed-text:latest")
+	down := modelsOllama(t, false, "mistral:latest")
+	pool, _ := upstream.NewPool([]string{a.URL, b.URL, down.URL}, nil)
+	pool.CheckNow(context.Background())
+
+	got := modelIDs(t, getModels(newModelsServer(pool, limiter.New(), false)))
+	want := []string{"llama3.2:1b", "moondream:latest", "nomic-embed-text:latest"}
+	if !slices.Equal(got, want) {
+		t.Errorf("models = %v, want %v: each healthy backend's models once, none from the unhealthy one", got, want)
+	}
+}
+
+func TestModels_FilteredByAllowlist(t *testing.T) {
+	up := modelsOllama(t, true, "llama3.2:1b", "moondream:latest", "nomic-embed-text:latest")
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	lim := limiter.New()
+	lim.SetAllowedModels("alice", []string{"llama3.2:1b", "moondream"}) // untagged = :latest
+
+	got := modelIDs(t, getModels(newModelsServer(pool, lim, false)))
+	if want := []string{"llama3.2:1b", "moondream:latest"}; !slices.Equal(got, want) {
+		t.Errorf("models = %v, want only the allowed %v", got, want)
+	}
+
+	// Admins see every model regardless of their own allowlist.
+	got = modelIDs(t, getModels(newModelsServer(pool, lim, true)))
+	if len(got) != 3 {
+		t.Errorf("admin sees %v, want all 3 models", got)
+	}
+}
+
+func TestModels_NoBackendAnswers(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(down.Close)
+	pool, _ := upstream.NewPool([]string{down.URL}, nil)
+
+	rec := getModels(newModelsServer(pool, limiter.New(), false))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("got %d, want 502", rec.Code)
+	}
+	var body struct {
+		Error struct {
+			Type, Code, Message string
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	if body.Error.Type != "server_error" || body.Error.Code != "upstream_error" || body.Error.Message == "" {
+		t.Errorf("got %s, want an OpenAI error envelope with type server_error and code upstream_error", rec.Body)
+	}
+}
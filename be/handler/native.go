@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"lb/auth"
+	"lb/limiter"
+	"lb/store"
+	"lb/upstream"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// nativeUsagePayload is the shape of the token counters in Ollama's native API.
+// For /api/chat and /api/generate they arrive on the final (done: true)
+// object; for /api/embed only prompt_eval_count is reported.
+type nativeUsagePayload struct {
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// Native handles Ollama's native POST /api/chat, /api/generate and /api/embed.
+// It applies the same auth, rate/quota limits and model allowlist as the
+// OpenAI-compatible endpoints, caps options.num_predict at the per-request
+// token cap, and accounts usage from the native counters.
+func Native(pool *upstream.Pool, s *store.Store, lim *limiter.Limiter) echo.HandlerFunc {
+	proxy := newUpstreamProxy(pool, func(resp *http.Response) {
+		userKey := resp.Request.Context().Value(ctxKeyUser{}).(string)
+		model := resp.Request.Context().Value(ctxKeyModel{}).(string)
+		isStream := resp.Request.Context().Value(ctxKeyStream{}).(bool)
+
+		switch {
+		case strings.HasSuffix(resp.Request.URL.Path, "/api/embed"):
+			accountNativeEmbed(resp, userKey, model, s, lim)
+		case isStream:
+			accountNDJSON(resp, userKey, model, s, lim)
+		default:
+			accountNativeDirect(resp, userKey, model, s, lim)
+		}
+	})
+
+	return func(c echo.Context) error {
+		userID := c.Get(auth.UserIDKey).(string)
+
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "failed to read body"})
+		}
+
+		var peek struct {
+			Model   string `json:"model"`
+			Stream  *bool  `json:"stream"`
+			Options struct {
+				NumPredict *int64 `json:"num_predict"`
+			} `json:"options"`
+		}
+		_ = json.Unmarshal(body, &peek)
+		isEmbed := c.Request().URL.Path == "/api/embed"
+		isStream := !isEmbed && (peek.Stream == nil || *peek.Stream) // native API streams by default
+
+		// Reserve the worst case against the quota: estimated prompt plus the
+		// capped num_predict (embeddings have no completion).
+		promptTokens := estimatePromptTokens(body)
+		cost := int64(promptTokens)
+		if !isEmbed {
+			cost += completionBudget(peek.Options.NumPredict, lim.MaxTokensPerRequest(userID, peek.Model))
+		}
+		release, err := admitRequest(c, lim, userID, peek.Model, cost)
+		if release == nil {
+			return err
+		}
+		defer release()
+
+		// Enforce the per-request cap via options.num_predict (the native max_tokens).
+		// A negative num_predict means "unlimited" to Ollama, so it is capped too.
+		if cap := lim.MaxTokensPerRequest(userID, peek.Model); !isEmbed && cap != limiter.INF_TOKEN_PER_REQ {
+			var raw map[string]json.RawMessage
+			if err := json.Unmarshal(body, &raw); err == nil {
+				var opts map[string]interface{}
+				if val, ok := raw["options"]; ok {
+					_ = json.Unmarshal(val, &opts)
+				}
+				if opts == nil {
+					opts = make(map[string]interface{})
+				}
+				if n, ok := opts["num_predict"].(float64); !ok || n < 0 || int64(n) > cap {
+					opts["num_predict"] = cap
+					optsBytes, _ := json.Marshal(opts)
+					raw["options"] = optsBytes
+					if rewritten, err := json.Marshal(raw); err == nil {
+						body = rewritten
+					}
+				}
+			}
+		}
+
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+		c.Request().ContentLength = int64(len(body))
+		c.Request().Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+		ctx := contextWith(c.Request().Context(), userID, peek.Model, isStream)
+		c.SetRequest(c.Request().WithContext(withPromptEstimate(ctx, promptTokens)))
+
+		return serveProxy(c, proxy, pool, body)
+	}
+}
+
+// accountNativeDirect reads a non-streaming native response, restores the body
+// for the client, and records prompt_eval_count/eval_count in the background.
+// If the counters are missing, usage is estimated from the request and the
+// generated text.
+func accountNativeDirect(resp *http.Response, user, model string, s *store.Store, lim *limiter.Limiter) {
+	res := reservation(resp.Request.Context())
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		res.Release()
+		return
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	promptTokens := promptEstimate(resp.Request.Context())
+	ok := resp.StatusCode == http.StatusOK
+	elapsed := upstreamTime(resp)
+
+	go func() {
+		defer res.Release()
+		if ok {
+			recordCompute(s, lim, user, model, reportedCompute(body), elapsed)
+		}
+		if !hasNativeCounts(string(body)) {
+			var chunk nativeChunk
+			if ok && json.Unmarshal(body, &chunk) == nil {
+				recordEstimate(s, lim, res, user, model, promptTokens, chunk.generatedTokens(), false)
+			}
+			return
+		}
+		var p nativeUsagePayload
+		if err := json.Unmarshal(body, &p); err != nil {
+			return
+		}
+		s.Add(user, model, p.PromptEvalCount, p.EvalCount)
+		lim.ConsumeTokens(user, model, p.PromptEvalCount+p.EvalCount)
+	}()
+}
+
+// accountNativeEmbed records /api/embed prompt tokens as embedding usage.
+func accountNativeEmbed(resp *http.Response, user, model string, s *store.Store, lim *limiter.Limiter) {
+	res := reservation(resp.Request.Context())
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		res.Release()
+		return
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	ok := resp.StatusCode == http.StatusOK
+	elapsed := upstreamTime(resp)
+
+	go func() {
+		defer res.Release()
+		if ok {
+			recordCompute(s, lim, user, model, reportedCompute(body), elapsed)
+		}
+		var p nativeUsagePayload
+		if err := json.Unmarshal(body, &p); err != nil || p.PromptEvalCount == 0 {
+			return
+		}
+		s.AddEmbedding(user, model, p.PromptEvalCount)
+		lim.ConsumeTokens(user, model, p.PromptEvalCount)
+	}()
+}
+
+// nativeChunk is the generated text of one native streaming object.
+type nativeChunk struct {
+	Response string `json:"response"` // /api/generate
+	Message  struct {
+		Content string `json:"content"`
+	} `json:"message"` // /api/chat
+	Done bool `json:"done"`
+}
+
+func (c *nativeChunk) generatedTokens() int {
+	return estimateTokens(c.Response) + estimateTokens(c.Message.Content)
+}
+
+// hasNativeCounts reports whether a native response object carries token counters.
+func hasNativeCounts(line string) bool {
+	return strings.Contains(line, `"eval_count"`) || strings.Contains(line, `"prompt_eval_count"`)
+}
+
+// accountNDJSON taps a native streaming response. Ollama streams one JSON
+// object per line; only the final object (done: true) carries the token
+// counters, so that is the only line retained in memory. If the stream
+// finishes without counters, usage is estimated from the text streamed; a
+// stream cut off before the done object is billed the same way, as aborted.
+func accountNDJSON(resp *http.Response, user, model string, s *store.Store, lim *limiter.Limiter) {
+	res := reservation(resp.Request.Context())
+	promptTokens := promptEstimate(resp.Request.Context())
+	ok := resp.StatusCode == http.StatusOK // error bodies never carry generated tokens
+	tapLines(resp, func(scanner *bufio.Scanner) {
+		defer res.Release()
+		var lastCountLine string
+		var streamed int
+		var done bool
+		for scanner.Scan() {
+			line := scanner.Text()
+			if hasNativeCounts(line) {
+				lastCountLine = line
+			} else if lastCountLine == "" {
+				var chunk nativeChunk
+				if json.Unmarshal([]byte(line), &chunk) == nil {
+					streamed += chunk.generatedTokens()
+					done = done || chunk.Done
+				}
+			}
+		}
+		if ok {
+			recordCompute(s, lim, user, model, reportedCompute([]byte(lastCountLine)), upstreamTime(resp))
+		}
+
+		if lastCountLine == "" {
+			if ok {
+				recordEstimate(s, lim, res, user, model, promptTokens, streamed, !done)
+			}
+			return
+		}
+		var p nativeUsagePayload
+		if err := json.Unmarshal([]byte(lastCountLine), &p); err != nil {
+			return
+		}
+		s.Add(user, model, p.PromptEvalCount, p.EvalCount)
+		lim.ConsumeTokens(user, model, p.PromptEvalCount+p.EvalCount)
+	})
+}
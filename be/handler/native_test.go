@@ -0,0 +1,173 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"lb/auth"
+	"lb/handler"
+	"lb/limiter"
+	"lb/store"
+	"lb/upstream"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newNativeServer wires Native's /api/chat behind a stub auth middleware for user "alice".
+func newNativeServer(pool *upstream.Pool, s *store.Store, lim *limiter.Limiter) *echo.Echo {
+	e := echo.New()
+	e.POST("/api/chat", handler.Native(pool, s, lim), func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(auth.UserIDKey, "alice")
+			c.Set(auth.AdminCtxKey, false)
+			return next(c)
+		}
+	})
+	return e
+}
+
+// ndjsonOllama streams three message chunks and then final, one object per line.
+func ndjsonOllama(t *testing.T, final string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":"tok"},"done":false}`)
+		}
+		fmt.Fprintln(w, final)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// waitUsage polls until the background accounting has recorded alice's usage
+// of model, or a second has passed.
+func waitUsage(s *store.Store, model string, done func(store.ModelUsage) bool) store.ModelUsage {
+	var u store.ModelUsage
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if u = s.Get("alice")[model]; done(u) {
+			break
+		}
+	}
+	return u
+}
+
+func TestNativeChat_StreamBillsFinalCounters(t *testing.T) {
+	ollama := ndjsonOllama(t, `{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":5,"eval_count":7}`)
+	pool, _ := upstream.NewPool([]string{ollama.URL}, nil)
+	s := store.New()
+	lim := limiter.New()
+	e := newNativeServer(pool, s, lim)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{"model":"moondream","messages":[]}`))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200", rec.Code)
+	}
+	if n := strings.Count(rec.Body.String(), "\n"); n != 4 {
+		t.Errorf("client got %d lines, want all 4", n)
+	}
+
+	u := waitUsage(s, "moondream", func(u store.ModelUsage) bool { return u.CompletionTokens > 0 })
+	if u.PromptTokens != 5 || u.CompletionTokens != 7 {
+		t.Errorf("got prompt=%d completion=%d, want the reported 5 and 7", u.PromptTokens, u.CompletionTokens)
+	}
+	if u.EstimatedRequests != 0 || u.AbortedRequests != 0 {
+		t.Errorf("got estimated=%d aborted=%d, want 0 and 0", u.EstimatedRequests, u.AbortedRequests)
+	}
+	if used := lim.Limits("alice").UsedTokens; used != 12 {
+		t.Errorf("quota used: got %d, want 12", used)
+	}
+}
+
+func TestNativeChat_StreamEstimatesMissingCounters(t *testing.T) {
+	ollama := ndjsonOllama(t, `{"message":{"role":"assistant","content":""},"done":true}`)
+	pool, _ := upstream.NewPool([]string{ollama.URL}, nil)
+	s := store.New()
+	e := newNativeServer(pool, s, limiter.New())
+
+	body := `{"model":"moondream","messages":[{"role":"user","content":"sixteen chars!!!"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200", rec.Code)
+	}
+
+	u := waitUsage(s, "moondream", func(u store.ModelUsage) bool { return u.EstimatedRequests > 0 })
+	if u.EstimatedRequests != 1 || u.AbortedRequests != 0 {
+		t.Fatalf("got estimated=%d aborted=%d, want 1 and 0", u.EstimatedRequests, u.AbortedRequests)
+	}
+	if u.PromptTokens != 4 || u.CompletionTokens != 3 {
+		t.Errorf("got prompt=%d completion=%d, want estimates of 4 and 3", u.PromptTokens, u.CompletionTokens)
+	}
+}
+
+func TestNativeChat_ClientDisconnectBillsPartial(t *testing.T) {
+	cancelled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 0; i < 100; i++ {
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":"tok"},"done":false}`)
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				close(cancelled)
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}))
+	t.Cleanup(slow.Close)
+
+	pool, _ := upstream.NewPool([]string{slow.URL}, nil)
+	s := store.New()
+	proxy := httptest.NewServer(newNativeServer(pool, s, limiter.New()))
+	t.Cleanup(proxy.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body := `{"model":"moondream","messages":[{"role":"user","content":"sixteen chars!!!"}]}`
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, proxy.URL+"/api/chat", strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read three objects, then hang up before the done object.
+	r := bufio.NewReader(resp.Body)
+	for i := 0; i < 3; i++ {
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	resp.Body.Close()
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream request was not cancelled after client disconnect")
+	}
+
+	u := waitUsage(s, "moondream", func(u store.ModelUsage) bool { return u.AbortedRequests > 0 })
+	if u.AbortedRequests != 1 {
+		t.Fatalf("aborted requests: got %d, want 1", u.AbortedRequests)
+	}
+	if u.CompletionTokens < 3 {
+		t.Errorf("completion tokens: got %d, want at least the 3 objects read", u.CompletionTokens)
+	}
+	if u.PromptTokens != 4 {
+		t.Errorf("prompt tokens: got %d, want estimate of 4", u.PromptTokens)
+	}
+	if u.AbortedTokens != u.PromptTokens+u.CompletionTokens {
+		t.Errorf("aborted tokens: got %d, want %d", u.AbortedTokens, u.PromptTokens+u.CompletionTokens)
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"lb/auth"
+	"lb/limiter"
+	"lb/pb"
+	"lb/store"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// orgToPB converts an organization to its API shape, with the usage s
+// recorded for it.
+func orgToPB(info limiter.OrgInfo, s *store.Store) *pb.Org {
+	o := info.Limits
+	return &pb.Org{
+		Name:            info.Name,
+		Rps:             int32(o.RPS),
+		MaxTokens:       o.MaxTokens,
+		TokensPerMinute: o.Windows.TokensPerMinute,
+		TokensPerHour:   o.Windows.TokensPerHour,
+		TokensPerDay:    o.Windows.TokensPerDay,
+		TokensPerMonth:  o.Windows.TokensPerMonth,
+		RequestsPerDay:  o.Windows.RequestsPerDay,
+		QuotaPeriod:     o.QuotaPeriod,
+		BillingDay:      int32(o.BillingDay),
+		Members:         info.Members,
+		Quota:           quotaToPB(info.Quota),
+		ReservedTokens:  info.ReservedTokens,
+		WindowUsage:     info.WindowUsage,
+		UsageByModel:    usageToPB(s.Org(info.Name)),
+	}
+}
+
+// Orgs handles GET /admin/orgs.
+// Lists every organization with its limits, members, quota use and the
+// usage recorded for it per model.
+func Orgs(lim *limiter.Limiter, s *store.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		orgs := lim.Orgs()
+		resp := &pb.OrgsResponse{Orgs: make([]*pb.Org, 0, len(orgs))}
+		for _, o := range orgs {
+			resp.Orgs = append(resp.Orgs, orgToPB(o, s))
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+// SetOrg handles POST /admin/orgs.
+// Creates an organization or replaces its limits; usage already counted
+// against it is kept.
+func SetOrg(lim *limiter.Limiter, s *store.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// Defense-in-depth: verify admin context key was set by AdminAuthMiddleware.
+		if ok, isAdmin := c.Get(auth.AdminCtxKey).(bool); !ok || !isAdmin {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "admin access required"})
+		}
+		var req pb.Org
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid JSON body"})
+		}
+		info, err := lim.DefineOrg(req.Name, limiter.OrgLimits{
+			RPS:       int(req.Rps),
+			MaxTokens: req.MaxTokens,
+			Windows: limiter.WindowLimits{
+				TokensPerMinute: req.TokensPerMinute,
+				TokensPerHour:   req.TokensPerHour,
+				TokensPerDay:    req.TokensPerDay,
+				TokensPerMonth:  req.TokensPerMonth,
+				RequestsPerDay:  req.RequestsPerDay,
+			},
+			QuotaPeriod: req.QuotaPeriod,
+			BillingDay:  int(req.BillingDay),
+		})
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, orgToPB(info, s))
+	}
+}
+
+// SetUserOrg handles POST /admin/org.
+// Moves a user into an organization, or out of theirs when org is empty.
+func SetUserOrg(lim *limiter.Limiter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// Defense-in-depth: verify admin context key was set by AdminAuthMiddleware.
+		if ok, isAdmin := c.Get(auth.AdminCtxKey).(bool); !ok || !isAdmin {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "admin access required"})
+		}
+		var req pb.SetUserOrgRequest
+		if err := c.Bind(&req); err != nil || req.UserId == "" {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "user_id is required"})
+		}
+		if err := lim.SetOrg(req.UserId, req.Org); err != nil {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, &pb.SetUserOrgResponse{
+			UserId: req.UserId,
+			Org:    req.Org,
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"lb/auth"
+	"lb/handler"
+	"lb/limiter"
+	"lb/pb"
+	"lb/store"
+	"lb/upstream"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newOrgServer wires the organization endpoints behind a stub admin middleware.
+func newOrgServer(lim *limiter.Limiter, s *store.Store) *echo.Echo {
+	e := echo.New()
+	admin := e.Group("/admin", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(auth.UserIDKey, "admin")
+			c.Set(auth.AdminCtxKey, true)
+			return next(c)
+		}
+	})
+	admin.GET("/orgs", handler.Orgs(lim, s))
+	admin.POST("/orgs", handler.SetOrg(lim, s))
+	admin.POST("/org", handler.SetUserOrg(lim))
+	return e
+}
+
+// postAdmin sends body to path as JSON.
+func postAdmin(e *echo.Echo, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// listOrgs fetches GET /admin/orgs, keyed by organization name.
+func listOrgs(t *testing.T, e *echo.Echo) map[string]*pb.Org {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/orgs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /admin/orgs: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp pb.OrgsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	out := make(map[string]*pb.Org, len(resp.Orgs))
+	for _, o := range resp.Orgs {
+		out[o.Name] = o
+	}
+	return out
+}
+
+func TestOrgs_DefineAndAssign(t *testing.T) {
+	lim := limiter.New()
+	e := newOrgServer(lim, store.New())
+
+	rec := postAdmin(e, "/admin/orgs", `{"name": "acme", "rps": 50, "max_tokens": 1000, "requests_per_day": 20}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("define: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec = postAdmin(e, "/admin/org", `{"user_id": "alice", "org": "acme"}`); rec.Code != http.StatusOK {
+		t.Fatalf("assign: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	acme := listOrgs(t, e)["acme"]
+	if acme == nil {
+		t.Fatal("acme missing from GET /admin/orgs")
+	}
+	if acme.Rps != 50 || acme.MaxTokens != 1000 || acme.RequestsPerDay != 20 {
+		t.Errorf("rps = %d, max_tokens = %d, requests_per_day = %d; want 50, 1000 and 20",
+			acme.Rps, acme.MaxTokens, acme.RequestsPerDay)
+	}
+	if len(acme.Members) != 1 || acme.Members[0] != "alice" {
+		t.Errorf("members = %v, want [alice]", acme.Members)
+	}
+	if acme.Quota.GetRemainingTokens() != 1000 {
+		t.Errorf("quota remaining = %d, want 1000", acme.Quota.GetRemainingTokens())
+	}
+
+	// An empty org takes the user out again.
+	if rec = postAdmin(e, "/admin/org", `{"user_id": "alice", "org": ""}`); rec.Code != http.StatusOK {
+		t.Fatalf("leave: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if m := listOrgs(t, e)["acme"].Members; len(m) != 0 {
+		t.Errorf("members = %v, want none", m)
+	}
+	if org := lim.Org("alice"); org != "" {
+		t.Errorf("alice's org = %q, want none", org)
+	}
+}
+
+func TestOrgs_Rejected(t *testing.T) {
+	cases := []struct {
+		name, path, body string
+		want             int
+	}{
+		{"org without a name", "/admin/orgs", `{"max_tokens": 1000}`, http.StatusBadRequest},
+		{"assign without a user", "/admin/org", `{"org": "acme"}`, http.StatusBadRequest},
+		{"assign to an unknown org", "/admin/org", `{"user_id": "alice", "org": "globex"}`, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			lim := limiter.New()
+			lim.DefineOrg("acme", limiter.OrgLimits{RPS: limiter.INF_RPS, MaxTokens: limiter.INF_TOKENS})
+			e := newOrgServer(lim, store.New())
+
+			if rec := postAdmin(e, tc.path, tc.body); rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tc.want, rec.Body)
+			}
+			if org := lim.Org("alice"); org != "" {
+				t.Errorf("alice's org = %q, want none after a rejected request", org)
+			}
+		})
+	}
+}
+
+func TestOrgs_UsageStaysWithOrgWhenMemberMoves(t *testing.T) {
+	up, _ := fakeOllama(t, 0, false)
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	s := store.New()
+	lim := limiter.New()
+	s.AttributeOrgs(lim.Org)
+	admin := newOrgServer(lim, s)
+	e := newServer(pool, s, lim)
+
+	for _, body := range []string{`{"name": "acme", "rps": -1, "max_tokens": -1}`, `{"name": "globex", "rps": -1, "max_tokens": -1}`} {
+		if rec := postAdmin(admin, "/admin/orgs", body); rec.Code != http.StatusOK {
+			t.Fatalf("define: status = %d, body %s", rec.Code, rec.Body)
+		}
+	}
+	postAdmin(admin, "/admin/org", `{"user_id": "alice", "org": "acme"}`)
+	if rec := postCompletion(e); rec.Code != http.StatusOK {
+		t.Fatalf("first request: got %d", rec.Code)
+	}
+	waitUsage(s, "llama3.2:1b", func(u store.ModelUsage) bool { return u.PromptTokens == 3 })
+
+	postAdmin(admin, "/admin/org", `{"user_id": "alice", "org": "globex"}`)
+	if rec := postCompletion(e); rec.Code != http.StatusOK {
+		t.Fatalf("second request: got %d", rec.Code)
+	}
+	waitUsage(s, "llama3.2:1b", func(u store.ModelUsage) bool { return u.PromptTokens == 6 })
+
+	orgs := listOrgs(t, admin)
+	if u := orgs["acme"].UsageByModel["llama3.2:1b"]; u.GetPromptTokens() != 3 || u.GetCompletionTokens() != 4 {
+		t.Errorf("acme usage = %v, want only the request made while alice was a member", u)
+	}
+	if u := orgs["globex"].UsageByModel["llama3.2:1b"]; u.GetPromptTokens() != 3 || u.GetCompletionTokens() != 4 {
+		t.Errorf("globex usage = %v, want only the request made since alice joined", u)
+	}
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// paceBuffer bounds how many lines of a paced stream are read from upstream
+// ahead of the client.
+const paceBuffer = 256
+
+// pacedBody meters an OpenAI-style SSE body out to the client at no more than
+// tps generated tokens per second. A goroutine keeps reading upstream into a
+// bounded buffer, so generation is not slowed by the pace until the buffer
+// fills; the upstream's idle timeout is held meanwhile, since the stall is
+// the proxy's own. Lines that carry no tokens are sent as soon as the chunk
+// before them.
+//
+// If the client goes away, Read fails with the request context's cause and
+// Close stops the reader. When one of the proxy's deadlines fires instead,
+// whatever was read is flushed without further pacing, followed by the
+// timeout event (see deadlineBody).
+type pacedBody struct {
+	body    io.ReadCloser
+	ctx     context.Context
+	d       *deadline
+	tps     float64
+	lines   chan []byte   // lines read ahead, in order; closed after the last
+	err     error         // why reading stopped; set before lines is closed
+	quit    chan struct{} // closed by Close
+	exited  chan struct{} // closed once the reader has returned
+	stop    sync.Once
+	next    time.Time // when the latest token-carrying line was due
+	pending []byte    // part of the current line not yet returned
+	expired bool      // a deadline fired: stop pacing and drain
+}
+
+func newPacedBody(ctx context.Context, body io.ReadCloser, tps int64, d *deadline) *pacedBody {
+	b := &pacedBody{
+		body:   body,
+		ctx:    ctx,
+		d:      d,
+		tps:    float64(tps),
+		lines:  make(chan []byte, paceBuffer),
+		quit:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go b.readAhead()
+	return b
+}
+
+// readAhead copies upstream lines into b.lines until the body ends or Close
+// is called.
+func (b *pacedBody) readAhead() {
+	defer close(b.exited)
+	defer close(b.lines)
+	br := bufio.NewReader(b.body)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			select {
+			case b.lines <- line:
+			default:
+				// The client is behind the pace; wait for it without
+				// counting the wait against the upstream.
+				b.d.hold()
+				select {
+				case b.lines <- line:
+					b.d.progress()
+				case <-b.quit:
+					return
+				}
+			}
+		}
+		if err != nil {
+			b.err = err
+			return
+		}
+	}
+}
+
+func (b *pacedBody) Read(p []byte) (int, error) {
+	for len(b.pending) == 0 {
+		var line []byte
+		var ok bool
+		select {
+		case line, ok = <-b.lines:
+		case <-b.done():
+			if err := b.interrupted(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if !ok {
+			return 0, b.err
+		}
+		if err := b.wait(lineTokens(line)); err != nil {
+			return 0, err
+		}
+		b.pending = line
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+// done is the request context's Done channel, or nil once a deadline fired.
+func (b *pacedBody) done() <-chan struct{} {
+	if b.expired {
+		return nil
+	}
+	return b.ctx.Done()
+}
+
+// interrupted handles the request context ending: the client's disconnect is
+// returned as an error, while a fired deadline only switches pacing off.
+func (b *pacedBody) interrupted() error {
+	cause := context.Cause(b.ctx)
+	if _, ok := timeoutCode(cause); !ok {
+		return cause
+	}
+	b.expired = true
+	return nil
+}
+
+// wait blocks until a line carrying n generated tokens may be sent: n/tps
+// after the previous one was due, or now if the stream has fallen behind.
+func (b *pacedBody) wait(n int) error {
+	if n == 0 || b.expired {
+		return nil
+	}
+	now := time.Now()
+	if b.next.Before(now) {
+		b.next = now
+	}
+	b.next = b.next.Add(time.Duration(float64(n) / b.tps * float64(time.Second)))
+	t := time.NewTimer(b.next.Sub(now))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-b.done():
+		return b.interrupted()
+	}
+}
+
+// Close stops reading ahead and closes the upstream body. It returns once the
+// reader has exited, so the deadline is no longer touched.
+func (b *pacedBody) Close() error {
+	b.stop.Do(func() { close(b.quit) })
+	err := b.body.Close()
+	<-b.exited
+	return err
+}
+
+// lineTokens counts the completion tokens carried by one SSE line.
+func lineTokens(line []byte) int {
+	data, ok := bytes.CutPrefix(bytes.TrimRight(line, "\r\n"), []byte("data: "))
+	var chunk choicesPayload
+	if !ok || json.Unmarshal(data, &chunk) != nil {
+		return 0
+	}
+	return chunk.generatedTokens()
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"lb/auth"
+	"lb/limiter"
+	"lb/pb"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// planToPB converts a limiter plan to its API shape.
+func planToPB(info limiter.PlanInfo) *pb.Plan {
+	p := info.Plan
+	return &pb.Plan{
+		Name:                  info.Name,
+		Rps:                   int32(p.RPS),
+		MaxTokens:             p.MaxTokens,
+		MaxTokensPerRequest:   p.MaxTokensPerReq,
+		MaxConcurrentRequests: p.MaxConcurrent,
+		TokensPerMinute:       p.Windows.TokensPerMinute,
+		TokensPerHour:         p.Windows.TokensPerHour,
+		TokensPerDay:          p.Windows.TokensPerDay,
+		TokensPerMonth:        p.Windows.TokensPerMonth,
+		RequestsPerDay:        p.Windows.RequestsPerDay,
+		Users:                 int32(info.Users),
+		QuotaPeriod:           p.QuotaPeriod,
+		Weight:                int32(p.Weight),
+		StreamTokensPerSecond: p.StreamTokensPerSec,
+		MaxComputeSeconds:     p.MaxComputeSeconds,
+	}
+}
+
+// Plans handles GET /admin/plans.
+// Lists every plan with its limits and how many users are on it.
+func Plans(lim *limiter.Limiter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		plans := lim.Plans()
+		resp := &pb.PlansResponse{
+			Plans:       make([]*pb.Plan, 0, len(plans)),
+			DefaultPlan: lim.DefaultPlan(),
+		}
+		for _, p := range plans {
+			resp.Plans = append(resp.Plans, planToPB(p))
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+// SetPlan handles POST /admin/plans.
+// Creates or replaces a plan; everyone already on it gets the new limits
+// immediately, on top of which their own overrides still apply.
+func SetPlan(lim *limiter.Limiter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// Defense-in-depth: verify admin context key was set by AdminAuthMiddleware.
+		if ok, isAdmin := c.Get(auth.AdminCtxKey).(bool); !ok || !isAdmin {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "admin access required"})
+		}
+		var req pb.Plan
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid JSON body"})
+		}
+		p := limiter.Plan{
+			RPS:             int(req.Rps),
+			MaxTokens:       req.MaxTokens,
+			MaxTokensPerReq: req.MaxTokensPerRequest,
+			MaxConcurrent:   req.MaxConcurrentRequests,
+			Windows: limiter.WindowLimits{
+				TokensPerMinute: req.TokensPerMinute,
+				TokensPerHour:   req.TokensPerHour,
+				TokensPerDay:    req.TokensPerDay,
+				TokensPerMonth:  req.TokensPerMonth,
+				RequestsPerDay:  req.RequestsPerDay,
+			},
+			QuotaPeriod:        req.QuotaPeriod,
+			Weight:             int(req.Weight),
+			StreamTokensPerSec: req.StreamTokensPerSecond,
+			MaxComputeSeconds:  req.MaxComputeSeconds,
+		}
+		info, err := lim.DefinePlan(req.Name, p)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, &pb.SetPlanResponse{
+			Plan:         planToPB(info),
+			UsersUpdated: int32(info.Users),
+		})
+	}
+}
+
+// AssignPlan handles POST /admin/plan.
+// Moves a user onto a plan without touching their usage.
+func AssignPlan(lim *limiter.Limiter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// Defense-in-depth: verify admin context key was set by AdminAuthMiddleware.
+		if ok, isAdmin := c.Get(auth.AdminCtxKey).(bool); !ok || !isAdmin {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "admin access required"})
+		}
+		var req pb.AssignPlanRequest
+		if err := c.Bind(&req); err != nil || req.UserId == "" || req.Plan == "" {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "user_id and plan are required"})
+		}
+		if err := lim.AssignPlan(req.UserId, req.Plan, req.ClearOverrides); err != nil {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, &pb.AssignPlanResponse{
+			UserId: req.UserId,
+			Plan:   req.Plan,
+		})
+	}
+}
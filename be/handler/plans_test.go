@@ -0,0 +1,154 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"lb/auth"
+	"lb/handler"
+	"lb/limiter"
+	"lb/pb"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newPlanServer wires the plan endpoints behind a stub admin middleware.
+func newPlanServer(lim *limiter.Limiter) *echo.Echo {
+	e := echo.New()
+	admin := e.Group("/admin", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(auth.UserIDKey, "admin")
+			c.Set(auth.AdminCtxKey, true)
+			return next(c)
+		}
+	})
+	admin.GET("/plans", handler.Plans(lim))
+	admin.POST("/plans", handler.SetPlan(lim))
+	admin.POST("/plan", handler.AssignPlan(lim))
+	return e
+}
+
+// listPlans fetches GET /admin/plans, keyed by plan name.
+func listPlans(t *testing.T, e *echo.Echo) (map[string]*pb.Plan, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/plans", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /admin/plans: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp pb.PlansResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	out := make(map[string]*pb.Plan, len(resp.Plans))
+	for _, p := range resp.Plans {
+		out[p.Name] = p
+	}
+	return out, resp.DefaultPlan
+}
+
+func TestPlans_DefineListAndAssign(t *testing.T) {
+	lim := limiter.New()
+	e := newPlanServer(lim)
+
+	rec := postAdmin(e, "/admin/plans", `{"name": "team", "rps": 50, "max_tokens": 5000, "max_tokens_per_request": 500, "max_concurrent_requests": 3}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("define: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec = postAdmin(e, "/admin/plan", `{"user_id": "alice", "plan": "team"}`); rec.Code != http.StatusOK {
+		t.Fatalf("assign: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	plans, def := listPlans(t, e)
+	if def != limiter.FreePlanName {
+		t.Errorf("default_plan = %q, want %q", def, limiter.FreePlanName)
+	}
+	team := plans["team"]
+	if team == nil {
+		t.Fatal("team missing from GET /admin/plans")
+	}
+	if team.Rps != 50 || team.MaxTokens != 5000 || team.MaxTokensPerRequest != 500 || team.MaxConcurrentRequests != 3 {
+		t.Errorf("team = %v, want the limits it was defined with", team)
+	}
+	if team.Users != 1 {
+		t.Errorf("team users = %d, want 1", team.Users)
+	}
+	if team.TokensPerDay != limiter.INF_TOKENS {
+		t.Errorf("tokens_per_day = %d, want an omitted window to be unlimited", team.TokensPerDay)
+	}
+
+	info := lim.Limits("alice")
+	if info.Plan != "team" || info.MaxTokens != 5000 || info.MaxTokensPerReq != 500 {
+		t.Errorf("alice = plan %q, max_tokens %d, max_tokens_per_req %d; want team's limits",
+			info.Plan, info.MaxTokens, info.MaxTokensPerReq)
+	}
+}
+
+func TestPlans_EditAppliesLive(t *testing.T) {
+	lim := limiter.New()
+	e := newPlanServer(lim)
+	postAdmin(e, "/admin/plans", `{"name": "team", "rps": 50, "max_tokens": 5000, "max_tokens_per_request": 500, "max_concurrent_requests": 3}`)
+	postAdmin(e, "/admin/plan", `{"user_id": "alice", "plan": "team"}`)
+	lim.ConsumeTokens("alice", "", 100)
+
+	rec := postAdmin(e, "/admin/plans", `{"name": "team", "rps": 50, "max_tokens": 9000, "max_tokens_per_request": 500, "max_concurrent_requests": 3}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("edit: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp pb.SetPlanResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	if resp.UsersUpdated != 1 || resp.Plan.GetMaxTokens() != 9000 {
+		t.Errorf("users_updated = %d, max_tokens = %d; want 1 and 9000", resp.UsersUpdated, resp.Plan.GetMaxTokens())
+	}
+	if info := lim.Limits("alice"); info.MaxTokens != 9000 || info.UsedTokens != 100 {
+		t.Errorf("alice max_tokens = %d, used = %d; want the new 9000 and usage kept", info.MaxTokens, info.UsedTokens)
+	}
+}
+
+func TestPlans_AssignClearsOverrides(t *testing.T) {
+	lim := limiter.New()
+	e := newPlanServer(lim)
+	postAdmin(e, "/admin/plans", `{"name": "team", "rps": 50, "max_tokens": 5000, "max_tokens_per_request": 500, "max_concurrent_requests": 3}`)
+	lim.SetLimits("alice", 7, 100, 10)
+
+	postAdmin(e, "/admin/plan", `{"user_id": "alice", "plan": "team"}`)
+	if info := lim.Limits("alice"); info.MaxTokens != 100 {
+		t.Errorf("max_tokens = %d, want the override of 100 kept", info.MaxTokens)
+	}
+	postAdmin(e, "/admin/plan", `{"user_id": "alice", "plan": "team", "clear_overrides": true}`)
+	if info := lim.Limits("alice"); info.MaxTokens != 5000 || info.MaxTokensPerReq != 500 {
+		t.Errorf("max_tokens = %d, max_tokens_per_req = %d; want team's 5000 and 500", info.MaxTokens, info.MaxTokensPerReq)
+	}
+}
+
+func TestPlans_Rejected(t *testing.T) {
+	cases := []struct {
+		name, path, body string
+		want             int
+	}{
+		{"plan without rps", "/admin/plans", `{"name": "team", "max_tokens": 5000, "max_tokens_per_request": 500, "max_concurrent_requests": 3}`, http.StatusBadRequest},
+		{"plan without a name", "/admin/plans", `{"rps": 50, "max_tokens": 5000, "max_tokens_per_request": 500, "max_concurrent_requests": 3}`, http.StatusBadRequest},
+		{"assign without a user", "/admin/plan", `{"plan": "free"}`, http.StatusBadRequest},
+		{"assign an unknown plan", "/admin/plan", `{"user_id": "alice", "plan": "platinum"}`, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			lim := limiter.New()
+			e := newPlanServer(lim)
+
+			if rec := postAdmin(e, tc.path, tc.body); rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tc.want, rec.Body)
+			}
+			plans, _ := listPlans(t, e)
+			if _, ok := plans["team"]; ok {
+				t.Error("rejected plan was defined")
+			}
+			if plan := lim.Limits("alice").Plan; plan != limiter.FreePlanName {
+				t.Errorf("alice's plan = %q, want %q", plan, limiter.FreePlanName)
+			}
+		})
+	}
+}
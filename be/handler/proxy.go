@@ -0,0 +1,385 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lb/auth"
+	"lb/limiter"
+	"lb/upstream"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// attempt is the per-try state shared between serveProxy and the proxy
+// callbacks through the request context.
+type attempt struct {
+	last      bool      // final try: deliver whatever the upstream returns
+	err       error     // set when the try failed before anything was written to the client
+	deadline  *deadline // first-token and idle timers of this try
+	sent      time.Time // when the try was sent upstream, after queueing
+	accounted bool      // the response was handed to account, which now owns the reservation
+}
+
+// newUpstreamProxy builds a streaming reverse proxy over the upstream pool.
+// The target backend is chosen per attempt by serveProxy and carried in the
+// request context. account is invoked from ModifyResponse once a response is
+// accepted; the request context also carries the user/model/stream values
+// attached by contextWith.
+func newUpstreamProxy(pool *upstream.Pool, account func(resp *http.Response)) *httputil.ReverseProxy {
+	proxy := &httputil.ReverseProxy{
+		// Rewrite to the chosen backend, enforcing its Host while stripping
+		// headers that Ollama might reject (like Origin from extensions).
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			b := pr.In.Context().Value(ctxKeyBackend{}).(*upstream.Backend)
+			pr.SetURL(b.URL)
+			pr.SetXForwarded()
+			pr.Out.Header.Del("Origin")
+		},
+	}
+
+	// Disable response buffering — forward chunks immediately.
+	proxy.FlushInterval = -1
+
+	// ErrorHandler runs before any response bytes are written, so a failed
+	// attempt can still be retried. Only the final attempt surfaces a 502.
+	// A fired deadline is never retried: it answers 504 straight away.
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		cause := context.Cause(r.Context())
+		if code, ok := timeoutCode(cause); ok {
+			log.Printf("[timeout] %s: %v", code, cause)
+			writeTimeout(w, code, cause)
+			return
+		}
+		if a := r.Context().Value(ctxKeyAttempt{}).(*attempt); !a.last && r.Context().Err() == nil {
+			a.err = err
+			return
+		}
+		writeOpenAIError(w, http.StatusBadGateway, "server_error", "upstream_error", "upstream error: "+err.Error())
+	}
+
+	// ModifyResponse rejects retryable statuses (handing them to ErrorHandler)
+	// and otherwise puts the body under the attempt's deadlines and intercepts
+	// it for accounting.
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		a := resp.Request.Context().Value(ctxKeyAttempt{}).(*attempt)
+		if !a.last && pool.Retry.Retryable(resp.StatusCode) {
+			resp.Body.Close()
+			return fmt.Errorf("upstream returned %d", resp.StatusCode)
+		}
+		resp.Body = &deadlineBody{
+			ReadCloser:  resp.Body,
+			ctx:         resp.Request.Context(),
+			d:           a.deadline,
+			contentType: resp.Header.Get("Content-Type"),
+		}
+		a.accounted = true
+		account(resp)
+		return nil
+	}
+
+	return proxy
+}
+
+// serveProxy picks the least-loaded healthy backend serving the request's
+// model and proxies the buffered request body to it. While nothing has been
+// written to the client, failed attempts are retried per pool.Retry — on a
+// different backend when one is available. The backend's in-flight count
+// covers the whole response, including streamed bodies, because
+// ReverseProxy.ServeHTTP returns only once the body has been copied to the client.
+//
+// The user's and model's timeouts from pool.Timeouts apply throughout: the
+// total timeout spans every attempt and backoff, though not time spent pacing
+// a stream out (see totalTimer), while the first-token and idle timeouts are
+// armed afresh for each attempt.
+//
+// Each attempt first queues for a slot on its backend under pool.Queue, with
+// the user's fair-share weight and the request's priority lane from the
+// context (see withQueueWeight and withPriority). A request that queues longer
+// than the policy's MaxWait gets a 503.
+//
+// The request's reservation (see withReservation) is released by accounting
+// once the usage has been consumed; serveProxy releases it itself only when no
+// response ever reached accounting.
+func serveProxy(c echo.Context, proxy *httputil.ReverseProxy, pool *upstream.Pool, body []byte) error {
+	ctx := c.Request().Context()
+	var attempts []*attempt
+	defer func() {
+		for _, a := range attempts {
+			if a.accounted {
+				return
+			}
+		}
+		reservation(ctx).Release()
+	}()
+	user, _ := ctx.Value(ctxKeyUser{}).(string)
+	model, _ := ctx.Value(ctxKeyModel{}).(string)
+	weight, prio := queueWeight(ctx), priority(ctx)
+	policy := pool.Retry
+	timeouts := pool.Timeouts.For(user, model)
+	if stream, _ := ctx.Value(ctxKeyStream{}).(bool); !stream {
+		// A non-streamed reply only starts once generation is done, so its
+		// first byte says nothing about a hung upstream: total bounds it.
+		timeouts.FirstToken = 0
+	}
+	var total *totalTimer
+	if timeouts.Total > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		total = startTotal(timeouts.Total, func() { cancel(errTotalTimeout) })
+		defer total.stop()
+	}
+
+	var tried []*upstream.Backend
+	for n := 1; ; n++ {
+		b, err := pool.PickFor(model, tried...)
+		if errors.Is(err, upstream.ErrUnknownModel) {
+			return openAIError(c, http.StatusNotFound, "invalid_request_error", "model_not_found",
+				fmt.Sprintf("The model %q does not exist or is not served by this proxy.", model))
+		}
+		if err != nil {
+			return openAIError(c, http.StatusServiceUnavailable, "server_error", "no_healthy_upstream",
+				"No healthy upstream is available to serve the request; retry later.")
+		}
+
+		// Wait for the backend's concurrency budget before arming the
+		// first-token timer: time spent queued is not the upstream's fault.
+		if err := pool.Admit(ctx, b, user, weight, prio); err != nil {
+			if errors.Is(err, upstream.ErrQueueTimeout) {
+				log.Printf("[queue] %s (%s) gave up waiting for %s after %s", user, prio, b.URL, pool.Queue.MaxWait)
+				setRetryAfter(c, time.Second)
+				return openAIError(c, http.StatusServiceUnavailable, "server_error", "queue_timeout",
+					"The upstream is saturated and the request waited too long for capacity; retry later.")
+			}
+			if code, ok := timeoutCode(err); ok {
+				writeTimeout(c.Response(), code, err)
+			}
+			return nil // otherwise the client went away while queued
+		}
+
+		actx, d := newDeadline(ctx, timeouts, total)
+		a := &attempt{last: n >= policy.MaxAttempts, deadline: d, sent: time.Now()}
+		attempts = append(attempts, a)
+		req := c.Request().WithContext(context.WithValue(context.WithValue(actx,
+			ctxKeyBackend{}, b),
+			ctxKeyAttempt{}, a))
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+
+		b.RecordAttempt()
+		b.Acquire()
+		proxy.ServeHTTP(c.Response(), req)
+		b.Release()
+		pool.Done(b)
+		d.stop()
+
+		if a.err == nil {
+			return nil
+		}
+		b.RecordFailure()
+		tried = append(tried, b)
+
+		delay := policy.Delay(n)
+		log.Printf("[retry] %s attempt %d/%d to %s failed: %v; retrying in %s",
+			c.Request().URL.Path, n, policy.MaxAttempts, b.URL, a.err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			if code, ok := timeoutCode(context.Cause(ctx)); ok {
+				writeTimeout(c.Response(), code, context.Cause(ctx))
+			}
+			return nil // otherwise the client went away; nothing left to deliver
+		}
+	}
+}
+
+// admit rejects suspended users, then enforces RPS, token quota and
+// sliding-window limits for non-admin callers. RPS and quota limits scoped to
+// model apply alongside the user-wide ones. Either way the response
+// carries the caller's x-ratelimit-* headers, and a 429 its Retry-After.
+// When the request is rejected it writes the error response and returns false;
+// callers should return the accompanying error from their handler.
+func admit(c echo.Context, lim *limiter.Limiter, userID, model string) (bool, error) {
+	var serr *limiter.SuspendedError
+	if err := lim.CheckSuspended(userID); errors.As(err, &serr) {
+		return false, openAIError(c, http.StatusForbidden, "permission_error", "account_suspended", serr.Error())
+	}
+	if admin := c.Get(auth.AdminCtxKey).(bool); admin {
+		return true, nil
+	}
+	var rerr *limiter.RateLimitError
+	if err := lim.CheckRPS(userID, model); errors.As(err, &rerr) {
+		setRateLimitHeaders(c, lim, userID, model)
+		setRetryAfter(c, rerr.RetryAfter)
+		return false, openAIError(c, http.StatusTooManyRequests, "requests", "rate_limit_exceeded", rerr.Error())
+	}
+	if err := lim.CheckQuota(userID, model); err != nil {
+		setRateLimitHeaders(c, lim, userID, model)
+		return false, openAIError(c, http.StatusForbidden, "insufficient_quota", "insufficient_quota", err.Error())
+	}
+	if err := lim.CheckWindows(userID); err != nil {
+		return false, windowExceeded(c, lim, userID, model, err)
+	}
+	setRateLimitHeaders(c, lim, userID, model)
+	return true, nil
+}
+
+// windowExceeded writes the 429 for a *limiter.WindowError, naming the
+// window, with the caller's x-ratelimit-* headers and a Retry-After for when
+// the window has room again.
+func windowExceeded(c echo.Context, lim *limiter.Limiter, userID, model string, err error) error {
+	var werr *limiter.WindowError
+	if !errors.As(err, &werr) {
+		return err
+	}
+	setRateLimitHeaders(c, lim, userID, model)
+	setRetryAfter(c, time.Until(werr.ResetAt))
+	errType := "tokens"
+	if werr.Window == limiter.WindowRequestsPerDay {
+		errType = "requests"
+	}
+	return openAIError(c, http.StatusTooManyRequests, errType, "rate_limit_exceeded", werr.Error())
+}
+
+// admitRequest runs a request through every admission check: admit, the
+// model allowlist, the X-Priority header, a concurrency slot and a
+// reservation of cost tokens (see reserveTokens). Only once all of them have
+// passed is the request counted against requests_per_day (see
+// limiter.CountRequest), so a rejected request costs none of the daily
+// budget. On success the request
+// context carries the reservation, the queue lane and the user's queue
+// weight, and the returned release gives the slot back; the handler defers it
+// so the slot is held until the response, streamed body included, has been
+// fully proxied or the client has disconnected. When the request is rejected
+// the error response has been written, release is nil, and callers should
+// return err.
+func admitRequest(c echo.Context, lim *limiter.Limiter, userID, model string, cost int64) (release func(), err error) {
+	if ok, err := admit(c, lim, userID, model); !ok {
+		return nil, err
+	}
+	if ok, err := allowModel(c, lim, userID, model); !ok {
+		return nil, err
+	}
+	prio, ok, err := requestPriority(c, lim, userID)
+	if !ok {
+		return nil, err
+	}
+	if release, err = acquireSlot(c, lim, userID); release == nil {
+		return nil, err
+	}
+	res, ok, err := reserveTokens(c, lim, userID, model, cost)
+	if !ok {
+		release()
+		return nil, err
+	}
+	if admin := c.Get(auth.AdminCtxKey).(bool); !admin {
+		if err := lim.CountRequest(userID); err != nil {
+			res.Release()
+			release()
+			return nil, windowExceeded(c, lim, userID, model, err)
+		}
+	}
+	ctx := withReservation(c.Request().Context(), res)
+	ctx = withPriority(withQueueWeight(ctx, lim.QueueWeight(userID)), prio)
+	c.SetRequest(c.Request().WithContext(ctx))
+	return release, nil
+}
+
+// acquireSlot takes one of the user's concurrent-request slots for the
+// lifetime of the handler. It returns the function that gives the slot back,
+// or nil after writing a 429 when the user is at their limit (callers should
+// then return the accompanying error). Admins are not limited.
+func acquireSlot(c echo.Context, lim *limiter.Limiter, userID string) (release func(), err error) {
+	if admin := c.Get(auth.AdminCtxKey).(bool); admin {
+		return func() {}, nil
+	}
+	if err := lim.Acquire(userID); err != nil {
+		// No way to tell when a slot frees up; a second is a reasonable pace.
+		setRetryAfter(c, time.Second)
+		return nil, openAIError(c, http.StatusTooManyRequests, "requests", "concurrency_limit_exceeded", err.Error())
+	}
+	return func() { lim.Release(userID) }, nil
+}
+
+// reserveTokens sets aside the request's worst-case token cost — estimated
+// prompt plus capped completion budget — against the caller's quota, so that
+// concurrent requests cannot jointly overshoot it. When the cost does not fit
+// in what is left it writes a 403 and returns ok=false. Admins reserve nothing.
+// The x-ratelimit-*-tokens headers are refreshed to account for the reservation.
+//
+// Accounting releases the reservation once real usage is recorded, and
+// serveProxy releases it for responses that are never accounted.
+func reserveTokens(c echo.Context, lim *limiter.Limiter, userID, model string, cost int64) (res *limiter.Reservation, ok bool, err error) {
+	if admin := c.Get(auth.AdminCtxKey).(bool); admin {
+		return nil, true, nil
+	}
+	res, rerr := lim.Reserve(userID, model, cost)
+	setRateLimitHeaders(c, lim, userID, model)
+	if rerr != nil {
+		return nil, false, openAIError(c, http.StatusForbidden, "insufficient_quota", "insufficient_quota", rerr.Error())
+	}
+	return res, true, nil
+}
+
+// allowModel rejects the request with 403 if the model is outside the caller's
+// allowlist. Admins may use any model.
+func allowModel(c echo.Context, lim *limiter.Limiter, userID, model string) (bool, error) {
+	if admin := c.Get(auth.AdminCtxKey).(bool); admin || lim.ModelAllowed(userID, model) {
+		return true, nil
+	}
+	return false, openAIError(c, http.StatusForbidden, "invalid_request_error", "model_not_allowed",
+		fmt.Sprintf("model %q is not allowed for this API key", model))
+}
+
+// PriorityHeader lets a request ask for a lower queue priority than its API
+// key's: "batch" for work that can wait behind interactive traffic.
+const PriorityHeader = "X-Priority"
+
+// requestPriority resolves the upstream queue lane for the request: the key's
+// priority (limiter.Priority, interactive by default), lowered by the
+// X-Priority header if it asks for less. A header cannot raise a batch key to
+// interactive. An unknown header value gets a 400 and ok=false.
+func requestPriority(c echo.Context, lim *limiter.Limiter, userID string) (p upstream.Priority, ok bool, err error) {
+	p, _ = upstream.ParsePriority(lim.Priority(userID))
+	h := c.Request().Header.Get(PriorityHeader)
+	if h == "" {
+		return p, true, nil
+	}
+	asked, valid := upstream.ParsePriority(h)
+	if !valid {
+		return p, false, openAIError(c, http.StatusBadRequest, "invalid_request_error", "invalid_priority",
+			fmt.Sprintf("%s must be %q or %q; got %q", PriorityHeader,
+				upstream.PriorityInteractive, upstream.PriorityBatch, h))
+	}
+	return max(p, asked), true, nil
+}
+
+// openAIError writes an error in the OpenAI envelope that SDKs know how to
+// surface: {"error": {"message", "type", "code"}}.
+func openAIError(c echo.Context, status int, errType, code, message string) error {
+	return c.JSON(status, echo.Map{"error": echo.Map{
+		"message": message,
+		"type":    errType,
+		"code":    code,
+	}})
+}
+
+// writeOpenAIError is openAIError for callbacks that only have the
+// http.ResponseWriter, such as the reverse proxy's ErrorHandler.
+func writeOpenAIError(w http.ResponseWriter, status int, errType, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{
+		"message": message,
+		"type":    errType,
+		"code":    code,
+	}})
+}
@@ -0,0 +1,242 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"lb/auth"
+	"lb/handler"
+	"lb/limiter"
+	"lb/store"
+	"lb/upstream"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// fakeOllama serves a fixed non-streaming completion, or fails with status
+// while fail is set. hits counts completion requests.
+func fakeOllama(t *testing.T, status int, fail bool) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/version" {
+			w.Write([]byte(`{"version":"0.0.0"}`))
+			return
+		}
+		hits.Add(1)
+		io.ReadAll(r.Body)
+		if fail {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":4}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// newServer wires Completions, for both the chat and the legacy endpoint,
+// behind a stub auth middleware for user "alice".
+func newServer(pool *upstream.Pool, s *store.Store, lim *limiter.Limiter) *echo.Echo {
+	e := echo.New()
+	completions := handler.Completions(pool, s, lim)
+	alice := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(auth.UserIDKey, "alice")
+			c.Set(auth.AdminCtxKey, false)
+			return next(c)
+		}
+	}
+	e.POST("/v1/chat/completions", completions, alice)
+	e.POST("/v1/completions", completions, alice)
+	return e
+}
+
+func postCompletion(e *echo.Echo) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"llama3.2:1b","stream":false,"messages":[]}`))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompletions_FailsOverBeforeFirstByte(t *testing.T) {
+	bad, badHits := fakeOllama(t, http.StatusServiceUnavailable, true)
+	good, goodHits := fakeOllama(t, 0, false)
+	pool, _ := upstream.NewPool([]string{bad.URL, good.URL}, nil)
+	pool.Retry = upstream.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, RetryStatuses: []int{503}}
+	s := store.New()
+	e := newServer(pool, s, limiter.New())
+
+	for i := 0; i < 4; i++ {
+		if rec := postCompletion(e); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d, want 200 (body %q)", i, rec.Code, rec.Body.String())
+		}
+	}
+	if goodHits.Load() != 4 {
+		t.Errorf("healthy upstream hits: got %d, want 4", goodHits.Load())
+	}
+	if badHits.Load() == 0 {
+		t.Error("expected some attempts on the failing upstream")
+	}
+	if f := pool.Backends()[0].Failures(); f != badHits.Load() {
+		t.Errorf("failures recorded: got %d, want %d", f, badHits.Load())
+	}
+
+	// Only the delivered response is accounted.
+	time.Sleep(50 * time.Millisecond)
+	if u := s.Get("alice")["llama3.2:1b"]; u.PromptTokens != 12 {
+		t.Errorf("prompt tokens: got %d, want 12", u.PromptTokens)
+	}
+}
+
+func TestCompletions_RetriesConnectionRefused(t *testing.T) {
+	good, _ := fakeOllama(t, 0, false)
+	pool, _ := upstream.NewPool([]string{"http://127.0.0.1:1", good.URL}, nil)
+	pool.Retry = upstream.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}
+	e := newServer(pool, store.New(), limiter.New())
+
+	for i := 0; i < 4; i++ {
+		if rec := postCompletion(e); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d, want 200", i, rec.Code)
+		}
+	}
+}
+
+func TestCompletions_LastAttemptStatusIsDelivered(t *testing.T) {
+	bad, badHits := fakeOllama(t, http.StatusServiceUnavailable, true)
+	pool, _ := upstream.NewPool([]string{bad.URL}, nil)
+	pool.Retry = upstream.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, RetryStatuses: []int{503}}
+	e := newServer(pool, store.New(), limiter.New())
+
+	if rec := postCompletion(e); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got %d, want upstream 503 after retries are exhausted", rec.Code)
+	}
+	if badHits.Load() != 3 {
+		t.Errorf("attempts: got %d, want 3", badHits.Load())
+	}
+}
+
+func TestCompletions_ProxyErrorsUseOpenAIEnvelope(t *testing.T) {
+	pool, _ := upstream.NewPool([]string{"http://127.0.0.1:1"}, nil)
+	pool.Retry = upstream.RetryPolicy{MaxAttempts: 1}
+	e := newServer(pool, store.New(), limiter.New())
+
+	check := func(rec *httptest.ResponseRecorder, status int, code string) {
+		t.Helper()
+		var body struct {
+			Error struct{ Message, Type, Code string }
+		}
+		if rec.Code != status {
+			t.Fatalf("got %d, want %d", rec.Code, status)
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Code != code || body.Error.Message == "" {
+			t.Errorf("body %q: want an OpenAI error envelope with code %q", rec.Body, code)
+		}
+	}
+
+	// The backend refuses the connection on the only attempt.
+	check(postCompletion(e), http.StatusBadGateway, "upstream_error")
+
+	// Once health checks take it out of rotation, nothing is left to pick.
+	pool.CheckNow(context.Background())
+	check(postCompletion(e), http.StatusServiceUnavailable, "no_healthy_upstream")
+}
+
+func TestCompletions_RoutesByModel(t *testing.T) {
+	up, hits := fakeOllama(t, 0, false)
+	pool, _ := upstream.NewPool([]string{up.URL}, []upstream.Route{
+		{Model: "moondream:latest", Upstreams: []string{up.URL}},
+	})
+	e := newServer(pool, store.New(), limiter.New())
+
+	post := func(model string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"`+model+`","stream":false,"messages":[]}`))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// No route serves the model: 404 before any upstream is tried.
+	rec := post("llama3.2:1b")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unrouted model: got %d, want 404", rec.Code)
+	}
+	var body struct {
+		Error struct{ Message, Type, Code string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil ||
+		body.Error.Type != "invalid_request_error" || body.Error.Code != "model_not_found" {
+		t.Errorf("body %q: want an invalid_request_error with code model_not_found", rec.Body)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("unrouted model reached the upstream %d times", hits.Load())
+	}
+
+	// An untagged model is its :latest tag.
+	if rec := post("moondream"); rec.Code != http.StatusOK {
+		t.Errorf("untagged model: got %d, want 200 via the moondream:latest route", rec.Code)
+	}
+}
+
+func TestCompletions_PriorityHeader(t *testing.T) {
+	up, hits := fakeOllama(t, 0, false)
+	pool, _ := upstream.NewPool([]string{up.URL}, nil)
+	lim := limiter.New()
+	e := newServer(pool, store.New(), lim)
+
+	post := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"llama3.2:1b","stream":false,"messages":[]}`))
+		if header != "" {
+			req.Header.Set(handler.PriorityHeader, header)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	// admitted returns how many requests each lane has admitted so far.
+	admitted := func() (interactive, batch int64) {
+		lanes := pool.Backends()[0].Queue().Lanes
+		return lanes[upstream.PriorityInteractive.String()].Admitted, lanes[upstream.PriorityBatch.String()].Admitted
+	}
+
+	cases := []struct {
+		name, keyPriority, header  string
+		wantInteractive, wantBatch int64
+	}{
+		{"key's priority by default", "interactive", "", 1, 0},
+		{"header lowers to batch", "interactive", "batch", 1, 1},
+		{"header cannot raise a batch key", "batch", "interactive", 1, 2},
+	}
+	for _, tc := range cases {
+		lim.SetPriority("alice", tc.keyPriority)
+		if rec := post(tc.header); rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d, want 200", tc.name, rec.Code)
+		}
+		if i, b := admitted(); i != tc.wantInteractive || b != tc.wantBatch {
+			t.Errorf("%s: admitted interactive=%d batch=%d, want %d and %d", tc.name, i, b, tc.wantInteractive, tc.wantBatch)
+		}
+	}
+
+	rec := post("urgent")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown priority: got %d, want 400", rec.Code)
+	}
+	var body struct {
+		Error struct{ Message, Type, Code string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Code != "invalid_priority" {
+		t.Errorf("body %q: want an OpenAI error envelope with code invalid_priority", rec.Body)
+	}
+	if hits.Load() != 3 {
+		t.Errorf("upstream hits = %d, want 3: the rejected request must not reach it", hits.Load())
+	}
+}
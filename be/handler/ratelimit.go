@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"lb/limiter"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// setRateLimitHeaders reports the caller's remaining allowance for model in
+// OpenAI's x-ratelimit-* headers, which client SDKs use to pace themselves.
+// Headers for a side (requests or tokens) nothing limits are left out.
+func setRateLimitHeaders(c echo.Context, lim *limiter.Limiter, userID, model string) {
+	st := lim.Status(userID, model)
+	h := c.Response().Header()
+	if st.LimitRequests >= 0 {
+		h.Set("x-ratelimit-limit-requests", strconv.FormatInt(st.LimitRequests, 10))
+		h.Set("x-ratelimit-remaining-requests", strconv.FormatInt(st.RemainingRequests, 10))
+		h.Set("x-ratelimit-reset-requests", resetValue(st.ResetRequests))
+	}
+	if st.LimitTokens >= 0 {
+		h.Set("x-ratelimit-limit-tokens", strconv.FormatInt(st.LimitTokens, 10))
+		h.Set("x-ratelimit-remaining-tokens", strconv.FormatInt(st.RemainingTokens, 10))
+		if st.ResetTokens > 0 {
+			h.Set("x-ratelimit-reset-tokens", resetValue(st.ResetTokens))
+		}
+	}
+}
+
+// resetValue formats a reset delay the way OpenAI does: "1s", "6m0s", "20ms".
+func resetValue(d time.Duration) string {
+	return max(d, 0).Round(time.Millisecond).String()
+}
+
+// setRetryAfter sets Retry-After in whole seconds, rounded up, and at least 1.
+func setRetryAfter(c echo.Context, d time.Duration) {
+	secs := max(int64(math.Ceil(d.Seconds())), 1)
+	c.Response().Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+}
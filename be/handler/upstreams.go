@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"lb/pb"
+	"lb/upstream"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Upstreams handles GET /admin/upstreams.
+// Returns the health, in-flight request count, routed model patterns,
+// attempt/failure counters and admission queue state, overall and per
+// priority lane, of every Ollama backend.
+func Upstreams(pool *upstream.Pool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		resp := &pb.UpstreamsResponse{}
+		for _, b := range pool.Backends() {
+			q := b.Queue()
+			lanes := make(map[string]*pb.QueueLaneStatus, len(q.Lanes))
+			for name, l := range q.Lanes {
+				lanes[name] = &pb.QueueLaneStatus{
+					Queued:    int64(l.Queued),
+					Admitted:  l.Admitted,
+					AvgWaitMs: float64(l.AvgWait) / float64(time.Millisecond),
+					MaxWaitMs: float64(l.MaxWait) / float64(time.Millisecond),
+				}
+			}
+			resp.Upstreams = append(resp.Upstreams, &pb.UpstreamStatus{
+				Url:            b.URL.String(),
+				Healthy:        b.Healthy(),
+				InFlight:       b.InFlight(),
+				Routes:         b.Routes,
+				Attempts:       b.Attempts(),
+				Failures:       b.Failures(),
+				MaxConcurrent:  int64(pool.Queue.MaxConcurrent),
+				Queued:         int64(q.Queued),
+				QueueAdmitted:  q.Admitted,
+				AvgQueueWaitMs: float64(q.AvgWait) / float64(time.Millisecond),
+				MaxQueueWaitMs: float64(q.MaxWait) / float64(time.Millisecond),
+				Lanes:          lanes,
+				Promoted:       q.Promoted,
+			})
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
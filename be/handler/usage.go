@@ -2,33 +2,68 @@ package handler
 
 import (
 	"lb/auth"
+	"lb/limiter"
 	"lb/pb"
 	"lb/store"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
 // Usage handles GET /v1/usage.
-// Returns token usage for the authenticated user, keyed by model.
-func Usage(s *store.Store) echo.HandlerFunc {
+// Returns token usage for the authenticated user, keyed by model, along with
+// their token quota in the current billing period.
+func Usage(s *store.Store, lim *limiter.Limiter) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		userID, ok := auth.ResolveUser(auth.ExtractKey(c))
 		if !ok || userID == "" {
 			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid API key"})
 		}
 
-		usage := s.Get(userID)
 		resp := &pb.UsageResponse{
-			UsageByModel: make(map[string]*pb.ModelUsage, len(usage)),
+			UsageByModel: usageToPB(s.Get(userID)),
+			Quota:        quotaToPB(lim.Quota(userID)),
 		}
-		for model, u := range usage {
-			resp.UsageByModel[model] = &pb.ModelUsage{
-				PromptTokens:     int32(u.PromptTokens),
-				CompletionTokens: int32(u.CompletionTokens),
-			}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+// usageToPB converts per-model usage to its API shape.
+func usageToPB(usage map[string]store.ModelUsage) map[string]*pb.ModelUsage {
+	out := make(map[string]*pb.ModelUsage, len(usage))
+	for model, u := range usage {
+		out[model] = &pb.ModelUsage{
+			PromptTokens:      int32(u.PromptTokens),
+			CompletionTokens:  int32(u.CompletionTokens),
+			EmbeddingTokens:   int32(u.EmbeddingTokens),
+			AbortedRequests:   int32(u.AbortedRequests),
+			AbortedTokens:     int32(u.AbortedTokens),
+			EstimatedRequests: int32(u.EstimatedRequests),
+			EstimatedTokens:   int32(u.EstimatedTokens),
+			ComputeSeconds:    u.Compute.Seconds(),
+			WallClockRequests: int32(u.WallClockRequests),
 		}
+	}
+	return out
+}
 
-		return c.JSON(http.StatusOK, resp)
+// quotaToPB converts a quota snapshot to its API shape.
+func quotaToPB(q limiter.QuotaInfo) *pb.QuotaStatus {
+	out := &pb.QuotaStatus{
+		Period:          q.Period,
+		BillingDay:      int32(q.BillingDay),
+		MaxTokens:       q.MaxTokens,
+		UsedTokens:      q.UsedTokens,
+		RemainingTokens: q.Remaining,
+
+		MaxComputeSeconds:       q.MaxComputeSeconds,
+		UsedComputeSeconds:      q.UsedComputeSeconds,
+		RemainingComputeSeconds: q.RemainingComputeSeconds,
+	}
+	if !q.PeriodStart.IsZero() {
+		out.PeriodStart = q.PeriodStart.Format(time.RFC3339)
+		out.PeriodEnd = q.PeriodEnd.Format(time.RFC3339)
 	}
+	return out
 }
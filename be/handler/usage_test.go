@@ -0,0 +1,92 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"lb/handler"
+	"lb/limiter"
+	"lb/pb"
+	"lb/store"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getUsage fetches GET /v1/usage with the given API key and decodes a 200 reply.
+func getUsage(t *testing.T, s *store.Store, lim *limiter.Limiter, key string) (*httptest.ResponseRecorder, *pb.UsageResponse) {
+	t.Helper()
+	e := echo.New()
+	e.GET("/v1/usage", handler.Usage(s, lim))
+	req := httptest.NewRequest(http.MethodGet, "/v1/usage", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec, nil
+	}
+	var resp pb.UsageResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return rec, &resp
+}
+
+func TestUsage_QuotaInBillingPeriod(t *testing.T) {
+	s := store.New()
+	s.Add("alice", "llama3.2:1b", 200, 50)
+	lim := limiter.New()
+	lim.SetLimits("alice", 100, 1000, 100)
+	if err := lim.SetQuotaPeriod("alice", limiter.PeriodMonthly, 1); err != nil {
+		t.Fatal(err)
+	}
+	lim.ConsumeTokens("alice", "llama3.2:1b", 250)
+
+	rec, resp := getUsage(t, s, lim, "sk-alice-001")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if u := resp.UsageByModel["llama3.2:1b"]; u.GetPromptTokens() != 200 || u.GetCompletionTokens() != 50 {
+		t.Errorf("usage = %v, want 200 prompt and 50 completion tokens", u)
+	}
+	q := resp.Quota
+	if q.Period != limiter.PeriodMonthly || q.BillingDay != 1 {
+		t.Errorf("period = %q, billing_day = %d; want monthly and 1", q.Period, q.BillingDay)
+	}
+	if q.MaxTokens != 1000 || q.UsedTokens != 250 || q.RemainingTokens != 750 {
+		t.Errorf("max = %d, used = %d, remaining = %d; want 1000, 250 and 750", q.MaxTokens, q.UsedTokens, q.RemainingTokens)
+	}
+	start, err1 := time.Parse(time.RFC3339, q.PeriodStart)
+	end, err2 := time.Parse(time.RFC3339, q.PeriodEnd)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("period_start = %q, period_end = %q; want RFC 3339 times", q.PeriodStart, q.PeriodEnd)
+	}
+	if now := time.Now(); now.Before(start) || !now.Before(end) || start.Day() != 1 || end.Day() != 1 {
+		t.Errorf("period %s – %s should run from the 1st to the 1st and contain now", start, end)
+	}
+}
+
+func TestUsage_UnlimitedQuota(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("alice", 100, limiter.INF_TOKENS, 100)
+
+	rec, resp := getUsage(t, store.New(), lim, "sk-alice-001")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	q := resp.Quota
+	if q.MaxTokens != limiter.INF_TOKENS || q.RemainingTokens != limiter.INF_TOKENS {
+		t.Errorf("max = %d, remaining = %d; want both unlimited", q.MaxTokens, q.RemainingTokens)
+	}
+	if q.Period != limiter.PeriodNone || q.PeriodStart != "" || q.PeriodEnd != "" {
+		t.Errorf("period = %q from %q to %q; want a lifetime quota without period bounds", q.Period, q.PeriodStart, q.PeriodEnd)
+	}
+}
+
+func TestUsage_UnknownKey(t *testing.T) {
+	rec, _ := getUsage(t, store.New(), limiter.New(), "sk-nobody")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,15 @@
+package limiter
+
+import "time"
+
+// Internals exposed to the external limiter_test package.
+
+var PeriodBounds = periodBounds
+
+// EndPeriod moves the end of the user's current quota period to now, as if
+// the period boundary had just passed.
+func (l *Limiter) EndPeriod(user string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.users[user].periodEnd = time.Now()
+}
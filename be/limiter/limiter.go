@@ -3,8 +3,11 @@ package limiter
 import (
 	"fmt"
 	"lb/users"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"golang.org/x/time/rate"
 )
@@ -17,118 +20,509 @@ import (
 const tokenQuotaGrace = 5
 
 const (
-	FREE_TIER_RPS            = 1000
-	FREE_TIER_TOKENS         = 100000
-	FREE_TIER_TOKENS_PER_REQ = 4000
-
 	INF_RPS           = -1
 	INF_TOKENS        = -1
 	INF_TOKEN_PER_REQ = -1
+	INF_CONCURRENT    = -1
+	INF_COMPUTE       = -1
 )
 
-// userLimit holds rate + quota state for one user.
+// userLimit holds rate + quota state for one user. The limits are the user's
+// plan with their overrides applied on top; see apply.
 type userLimit struct {
-	limiter         *rate.Limiter
-	maxTokens       int64        // INF_TOKENS = unlimited
-	maxTokensPerReq int64        // INF_TOKEN_PER_REQ = unlimited; caps max_tokens per request
-	usedTokens      atomic.Int64 // total tokens consumed
+	plan      string    // name of the plan the limits derive from
+	overrides overrides // per-user limits set on top of the plan
+	// The limits below are rewritten by apply under Limiter.mu, including by
+	// live plan edits, and read without it on the request path.
+	limiter         atomic.Pointer[rate.Limiter]
+	maxTokens       atomic.Int64           // INF_TOKENS = unlimited
+	maxTokensPerReq atomic.Int64           // INF_TOKEN_PER_REQ = unlimited; caps max_tokens per request
+	maxConcurrent   atomic.Int64           // INF_CONCURRENT = unlimited; caps requests in flight at once
+	inFlight        atomic.Int64           // requests currently holding a slot
+	streamPace      atomic.Int64           // INF_TOKENS = unpaced; tokens per second streamed to the client
+	maxCompute      atomic.Int64           // INF_COMPUTE = unlimited; upstream seconds per quota period
+	usedCompute     atomic.Int64           // upstream time consumed in the current period, in nanoseconds
+	usedTokens      atomic.Int64           // total tokens consumed
+	reservedTokens  atomic.Int64           // tokens set aside for requests in flight (see Reserve)
+	allowedModels   map[string]bool        // nil = all models allowed; guarded by Limiter.mu
+	models          map[string]*modelLimit // per-model overrides; guarded by Limiter.mu
+	windows         *windowSet             // sliding-window token and request caps
+	suspension      *Suspension            // nil = not suspended; guarded by Limiter.mu
+	priority        string                 // queue lane for the user's requests; "" = interactive; guarded by Limiter.mu
+	org             string                 // organization the user belongs to; "" = none; guarded by Limiter.mu
+
+	// Quota period (see periodBounds); guarded by Limiter.mu.
+	period      string
+	billingDay  int       // 0 = default anchor
+	periodStart time.Time // zero under PeriodNone
+	periodEnd   time.Time
+}
+
+// overrides are the limits an admin set for one user on top of their plan.
+// Zero fields inherit the plan's value.
+type overrides struct {
+	rps             int
+	rpsSet          bool // rps overrides the plan, including 0 (blocks every request)
+	maxTokens       int64
+	maxTokensPerReq int64
+	maxConcurrent   int64
+	windows         WindowLimits
+	quotaPeriod     string // "" = plan's period
+	streamPace      int64
+	maxCompute      int64
+}
+
+// override returns v if it is set (non-zero), otherwise fallback.
+func override(v, fallback int64) int64 {
+	if v != 0 {
+		return v
+	}
+	return fallback
+}
+
+// apply recomputes the user's limits from plan p with their overrides on top.
+// Usage counters are kept, and the RPS bucket is only rebuilt when the rate
+// changes. Caller must hold Limiter.mu.
+func (u *userLimit) apply(p Plan) {
+	o := u.overrides
+	rps := p.RPS
+	if o.rpsSet {
+		rps = o.rps
+	}
+	r, burst := rate.Limit(rps), rps
+	if rps == INF_RPS {
+		r, burst = rate.Inf, 0
+	}
+	if lim := u.limiter.Load(); lim == nil || lim.Limit() != r || lim.Burst() != burst {
+		u.limiter.Store(rate.NewLimiter(r, burst))
+	}
+	u.maxTokens.Store(override(o.maxTokens, p.MaxTokens))
+	u.maxTokensPerReq.Store(override(o.maxTokensPerReq, p.MaxTokensPerReq))
+	u.maxConcurrent.Store(override(o.maxConcurrent, p.MaxConcurrent))
+	u.streamPace.Store(override(o.streamPace, p.StreamTokensPerSec))
+	u.maxCompute.Store(override(o.maxCompute, p.MaxComputeSeconds))
+	u.windows.set(WindowLimits{
+		TokensPerMinute: override(o.windows.TokensPerMinute, p.Windows.TokensPerMinute),
+		TokensPerHour:   override(o.windows.TokensPerHour, p.Windows.TokensPerHour),
+		TokensPerDay:    override(o.windows.TokensPerDay, p.Windows.TokensPerDay),
+		TokensPerMonth:  override(o.windows.TokensPerMonth, p.Windows.TokensPerMonth),
+		RequestsPerDay:  override(o.windows.RequestsPerDay, p.Windows.RequestsPerDay),
+	})
+	u.setPeriod(u.effectivePeriod(p), u.billingDay)
 }
 
 // Limiter manages per-user RPS and token quota limits.
 type Limiter struct {
-	mu    sync.Mutex
-	users map[string]*userLimit
+	mu          sync.Mutex
+	users       map[string]*userLimit
+	plans       map[string]Plan
+	defaultPlan string // plan new users start on
+	orgs        map[string]*orgLimit
 }
 
+// New returns a Limiter whose only plan is FreePlan, assigned to every new user.
 func New() *Limiter {
-	return &Limiter{users: make(map[string]*userLimit)}
+	return &Limiter{
+		users:       make(map[string]*userLimit),
+		plans:       map[string]Plan{FreePlanName: FreePlan},
+		defaultPlan: FreePlanName,
+		orgs:        make(map[string]*orgLimit),
+	}
 }
 
 func (l *Limiter) getOrCreate(user string) *userLimit {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	return l.userLocked(user)
+}
+
+// userLocked returns the user's state, creating it on the default plan.
+// Caller must hold l.mu.
+func (l *Limiter) userLocked(user string) *userLimit {
 	if u, ok := l.users[user]; ok {
+		u.roll(time.Now())
 		return u
 	}
-	// New users start on the free tier.
-	u := &userLimit{
-		limiter:         rate.NewLimiter(rate.Limit(FREE_TIER_RPS), FREE_TIER_RPS),
-		maxTokens:       FREE_TIER_TOKENS,
-		maxTokensPerReq: FREE_TIER_TOKENS_PER_REQ,
-	}
+	u := l.newUser()
 	l.users[user] = u
 	return u
 }
 
-// SetLimits updates RPS, total token quota, and per-request token cap for a user.
+// newUser builds the state of a user on the default plan without registering
+// it. Caller must hold l.mu.
+func (l *Limiter) newUser() *userLimit {
+	u := &userLimit{plan: l.defaultPlan, windows: newWindowSet()}
+	u.apply(l.plans[l.defaultPlan])
+	return u
+}
+
+// SetLimits overrides RPS, total token quota, and per-request token cap for a
+// user on top of their plan.
 // Use INF_RPS / INF_TOKENS / INF_TOKEN_PER_REQ (-1) to remove a limit.
-// Use 0 for any field to leave it unchanged.
+// Use 0 for the token fields to leave them unchanged; rps is always applied.
 // Takes effect immediately for all subsequent requests.
 func (l *Limiter) SetLimits(user string, rps int, maxTokens, maxTokensPerReq int64) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	u, ok := l.users[user]
-	if !ok {
-		u = &userLimit{}
-		l.users[user] = u
-	}
-	switch rps {
-	case INF_RPS:
-		u.limiter = rate.NewLimiter(rate.Inf, 0)
-	default:
-		u.limiter = rate.NewLimiter(rate.Limit(rps), rps)
-	}
-	if maxTokens != 0 {
-		u.maxTokens = maxTokens // INF_TOKENS (-1) stored as-is = unlimited
-	}
-	if maxTokensPerReq != 0 {
-		u.maxTokensPerReq = maxTokensPerReq // INF_TOKEN_PER_REQ (-1) stored as-is = unlimited
-	}
+	u := l.userLocked(user)
+	u.overrides.rps, u.overrides.rpsSet = rps, true
+	u.overrides.maxTokens = override(maxTokens, u.overrides.maxTokens)
+	u.overrides.maxTokensPerReq = override(maxTokensPerReq, u.overrides.maxTokensPerReq)
+	u.apply(l.plans[u.plan])
 	// Reset consumed token counter when limits are updated.
 	u.usedTokens.Store(0)
 }
 
-// MaxTokensPerRequest returns the per-request token cap for a user (INF_TOKEN_PER_REQ = unlimited).
-func (l *Limiter) MaxTokensPerRequest(user string) int64 {
+// MaxTokensPerRequest returns the per-request token cap for a user calling
+// model (INF_TOKEN_PER_REQ = unlimited): the tighter of the user-wide cap and
+// the one scoped to the model; model "" always returns the user-wide cap.
+func (l *Limiter) MaxTokensPerRequest(user, model string) int64 {
 	u := l.getOrCreate(user)
-	return u.maxTokensPerReq
+	if m := l.modelLimitFor(u, model); m != nil {
+		return tighterCap(u.maxTokensPerReq.Load(), m.maxTokensPerReq)
+	}
+	return u.maxTokensPerReq.Load()
 }
 
-// CheckRPS returns an error (429) if the user has exceeded their RPS limit.
-func (l *Limiter) CheckRPS(user string) error {
+// CheckRPS returns a *RateLimitError (429) if the user has exceeded their RPS
+// limit for model. The user-wide bucket always applies, as does an RPS limit
+// scoped to the model; model "" checks the user-wide bucket alone. Members of
+// an organization must also fit its RPS limit.
+func (l *Limiter) CheckRPS(user, model string) error {
 	u := l.getOrCreate(user)
-	if !u.limiter.Allow() {
-		return fmt.Errorf("rate limit exceeded")
+	buckets := []*rate.Limiter{u.limiter.Load()}
+	if m := l.modelLimitFor(u, model); m != nil && m.limiter != nil {
+		buckets = append(buckets, m.limiter)
 	}
-	return nil
+	if o := l.orgFor(u); o != nil {
+		buckets = append(buckets, o.limiter.Load())
+	}
+	return allow(buckets...)
+}
+
+// allow takes a request from every bucket, or from none of them when one is
+// empty, returning a *RateLimitError saying when they all next hold one.
+func allow(buckets ...*rate.Limiter) error {
+	now := time.Now()
+	taken := make([]*rate.Reservation, 0, len(buckets))
+	var wait time.Duration
+	blocked := false
+	for _, b := range buckets {
+		r := b.ReserveN(now, 1)
+		if !r.OK() { // a zero burst never holds a request
+			blocked = true
+			continue
+		}
+		taken = append(taken, r)
+		wait = max(wait, r.DelayFrom(now))
+	}
+	if !blocked && wait == 0 {
+		return nil
+	}
+	for _, r := range taken {
+		r.CancelAt(now)
+	}
+	return &RateLimitError{RetryAfter: wait}
 }
 
-// CheckQuota returns an error (403) if the user has exceeded their token quota.
+// CheckQuota returns an error (403) if the user has exceeded their token quota
+// for model: the user-wide quota, and a quota scoped to the model checked
+// against the tokens used on that model; model "" checks the user-wide one.
 // A grace of tokenQuotaGrace tokens is allowed beyond the configured limit to
 // account for async accounting — the common (under-quota) case never blocks.
-func (l *Limiter) CheckQuota(user string) error {
+// The user's compute quota, if any, must not be used up either. Members of an
+// organization must also be within its quota.
+func (l *Limiter) CheckQuota(user, model string) error {
 	u := l.getOrCreate(user)
-	if u.maxTokens == INF_TOKENS {
-		return nil // unlimited
-	}
-	if u.usedTokens.Load() >= u.maxTokens+tokenQuotaGrace {
+	if max := u.maxTokens.Load(); max != INF_TOKENS && u.usedTokens.Load() >= max+tokenQuotaGrace {
 		return fmt.Errorf("token quota exceeded")
 	}
+	if m := l.modelLimitFor(u, model); m != nil && m.maxTokens > 0 && m.usedTokens.Load() >= m.maxTokens+tokenQuotaGrace {
+		return fmt.Errorf("token quota for %s exceeded", model)
+	}
+	if limit := u.maxCompute.Load(); limit != INF_COMPUTE && time.Duration(u.usedCompute.Load()) >= time.Duration(limit)*time.Second {
+		return fmt.Errorf("compute quota exceeded")
+	}
+	if o := l.orgFor(u); o != nil && o.maxTokens.Load() != INF_TOKENS &&
+		o.usedTokens.Load() >= o.maxTokens.Load()+tokenQuotaGrace {
+		return fmt.Errorf("organization token quota exceeded")
+	}
 	return nil
 }
 
-// ConsumeTokens atomically records token usage after inference.
-func (l *Limiter) ConsumeTokens(user string, n int) {
+// ConsumeTokens atomically records token usage after inference, against the
+// user-wide total and, if the model has its own limits, the model's total.
+// Usage also counts against the user's organization, if any.
+func (l *Limiter) ConsumeTokens(user, model string, n int) {
 	u := l.getOrCreate(user)
 	u.usedTokens.Add(int64(n))
+	if m := l.modelLimitFor(u, model); m != nil {
+		m.usedTokens.Add(int64(n))
+	}
+	u.windows.consume(time.Now(), int64(n))
+	if o := l.orgFor(u); o != nil {
+		o.usedTokens.Add(int64(n))
+		o.windows.consume(time.Now(), int64(n))
+	}
+}
+
+// ConsumeCompute records upstream time spent on one of the user's requests
+// against their compute quota.
+func (l *Limiter) ConsumeCompute(user string, d time.Duration) {
+	l.getOrCreate(user).usedCompute.Add(int64(d))
+}
+
+// SetMaxConcurrent overrides how many requests the user may have in flight at
+// once. Use INF_CONCURRENT (-1) to remove the limit and 0 to leave it unchanged.
+// Requests already in flight are unaffected.
+func (l *Limiter) SetMaxConcurrent(user string, n int64) {
+	if n == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.userLocked(user)
+	u.overrides.maxConcurrent = n
+	u.apply(l.plans[u.plan])
+}
+
+// MaxConcurrent returns the user's concurrent-request cap (INF_CONCURRENT = unlimited).
+func (l *Limiter) MaxConcurrent(user string) int64 {
+	u := l.getOrCreate(user)
+	return u.maxConcurrent.Load()
+}
+
+// StreamPace returns how many generated tokens per second may be streamed to
+// the user (INF_TOKENS = unpaced).
+func (l *Limiter) StreamPace(user string) int64 {
+	u := l.getOrCreate(user)
+	return u.streamPace.Load()
+}
+
+// Acquire takes one of the user's concurrent-request slots. It returns an
+// error (429) if the user already has their maximum in flight. Every
+// successful Acquire must be paired with a Release once the response —
+// streamed body included — has finished or the client has gone away.
+func (l *Limiter) Acquire(user string) error {
+	u := l.getOrCreate(user)
+	for {
+		n := u.inFlight.Load()
+		if max := u.maxConcurrent.Load(); max != INF_CONCURRENT && n >= max {
+			return fmt.Errorf("too many concurrent requests (limit %d)", max)
+		}
+		if u.inFlight.CompareAndSwap(n, n+1) {
+			return nil
+		}
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (l *Limiter) Release(user string) {
+	u := l.getOrCreate(user)
+	u.inFlight.Add(-1)
+}
+
+// SetWindows overrides the user's sliding-window caps. Use 0 for any field to
+// leave it unchanged and INF_TOKENS / INF_REQUESTS (-1) to remove it.
+// Usage already inside the windows is kept.
+func (l *Limiter) SetWindows(user string, w WindowLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.userLocked(user)
+	o := &u.overrides.windows
+	o.TokensPerMinute = override(w.TokensPerMinute, o.TokensPerMinute)
+	o.TokensPerHour = override(w.TokensPerHour, o.TokensPerHour)
+	o.TokensPerDay = override(w.TokensPerDay, o.TokensPerDay)
+	o.TokensPerMonth = override(w.TokensPerMonth, o.TokensPerMonth)
+	o.RequestsPerDay = override(w.RequestsPerDay, o.RequestsPerDay)
+	u.apply(l.plans[u.plan])
+}
+
+// Windows returns the user's sliding-window caps (-1 = unlimited).
+func (l *Limiter) Windows(user string) WindowLimits {
+	u := l.getOrCreate(user)
+	return u.windows.limits()
+}
+
+// CheckWindows returns a *WindowError (429) if any of the user's sliding
+// windows is exhausted, without counting the request; see CountRequest.
+// Token windows are checked like the quota: a request is admitted while the
+// window is below its cap, and its tokens are added after inference.
+// Members of an organization must also fit its windows; the error then names
+// the organization.
+func (l *Limiter) CheckWindows(user string) error {
+	u := l.getOrCreate(user)
+	now := time.Now()
+	if o := l.orgFor(u); o != nil {
+		if err := o.windows.check(now); err != nil {
+			err.Org = l.Org(user)
+			return err
+		}
+	}
+	if err := u.windows.check(now); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CountRequest counts an admitted request against requests_per_day, the
+// user's and their organization's. Call it last, once every other check has
+// passed, so that rejected requests never use up the daily budget. It checks
+// the windows again as it counts, and returns a *WindowError without
+// counting if a concurrent request took the last one since CheckWindows.
+func (l *Limiter) CountRequest(user string) error {
+	u := l.getOrCreate(user)
+	now := time.Now()
+	o := l.orgFor(u)
+	if o != nil {
+		// Check the organization first so a rejected request counts against neither.
+		if err := o.windows.check(now); err != nil {
+			err.Org = l.Org(user)
+			return err
+		}
+	}
+	if err := u.windows.admit(now); err != nil {
+		return err
+	}
+	if o != nil {
+		o.windows.count(now)
+	}
+	return nil
+}
+
+// SetAllowedModels restricts a user to the given models.
+// An entry without a tag (e.g. "llama3.2") matches every tag of that model
+// ("llama3.2:1b", "llama3.2:latest"). An empty list removes the restriction.
+func (l *Limiter) SetAllowedModels(user string, models []string) {
+	u := l.getOrCreate(user)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(models) == 0 {
+		u.allowedModels = nil
+		return
+	}
+	u.allowedModels = make(map[string]bool, len(models))
+	for _, m := range models {
+		u.allowedModels[m] = true
+	}
+}
+
+// ModelAllowed reports whether the user may use the given model. An untagged
+// allowlist entry covers every tag of the model, and a model without a tag is
+// the same as its ":latest" tag, as it is to Ollama.
+func (l *Limiter) ModelAllowed(user, model string) bool {
+	u := l.getOrCreate(user)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if u.allowedModels == nil {
+		return true
+	}
+	if u.allowedModels[model] {
+		return true
+	}
+	name, _, tagged := strings.Cut(model, ":")
+	if !tagged {
+		return u.allowedModels[name+":latest"]
+	}
+	return u.allowedModels[name]
+}
+
+// AllowedModels returns the user's model allowlist, sorted. nil = all models allowed.
+func (l *Limiter) AllowedModels(user string) []string {
+	u := l.getOrCreate(user)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return u.sortedAllowedModels()
+}
+
+// sortedAllowedModels returns the allowlist as a sorted slice. Caller must hold Limiter.mu.
+func (u *userLimit) sortedAllowedModels() []string {
+	if u.allowedModels == nil {
+		return nil
+	}
+	out := make([]string, 0, len(u.allowedModels))
+	for m := range u.allowedModels {
+		out = append(out, m)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// SetPriority sets the upstream queue lane the user's requests go to
+// ("interactive" or "batch"; "" restores the default). The proxy validates the
+// name; requests may ask for a lower priority than this, never a higher one.
+func (l *Limiter) SetPriority(user, priority string) {
+	u := l.getOrCreate(user)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u.priority = priority
+}
+
+// Priority returns the user's queue lane as set by SetPriority; "" = default.
+func (l *Limiter) Priority(user string) string {
+	u := l.getOrCreate(user)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return u.priority
 }
 
 // LimitInfo holds limit config for one user (used by admin UI).
 type LimitInfo struct {
+	Plan            string
 	MaxTokens       int64
 	MaxTokensPerReq int64
 	UsedTokens      int64
-	RPS             float64
+	ReservedTokens  int64
+	RPS             float64                   // INF_RPS = unlimited
+	AllowedModels   []string                  // nil = all models allowed
+	Models          map[string]ModelLimitInfo // per-model overrides; nil = none
+	MaxConcurrent   int64
+	InFlight        int64
+	Windows         WindowLimits
+	WindowUsage     map[string]int64 // window name → consumed within it
+	Suspension      *Suspension      // nil = not suspended
+	Quota           QuotaInfo        // quota period and use within it
+	Priority        string           // queue lane; "" = default
+	Org             string           // organization; "" = none
+	StreamPace      int64            // tokens per second streamed to the client; INF_TOKENS = unpaced
+}
+
+// info snapshots the user's limits and usage. Caller must hold Limiter.mu.
+func (u *userLimit) info() LimitInfo {
+	lim := u.limiter.Load()
+	rps := float64(lim.Limit())
+	if lim.Limit() == rate.Inf {
+		rps = INF_RPS
+	}
+	return LimitInfo{
+		Plan:            u.plan,
+		MaxTokens:       u.maxTokens.Load(),
+		MaxTokensPerReq: u.maxTokensPerReq.Load(),
+		UsedTokens:      u.usedTokens.Load(),
+		ReservedTokens:  u.reservedTokens.Load(),
+		RPS:             rps,
+		AllowedModels:   u.sortedAllowedModels(),
+		Models:          u.modelLimitInfos(),
+		MaxConcurrent:   u.maxConcurrent.Load(),
+		InFlight:        u.inFlight.Load(),
+		Windows:         u.windows.limits(),
+		WindowUsage:     u.windows.usage(time.Now()),
+		Suspension:      u.activeSuspension(),
+		Quota:           u.quota(),
+		Priority:        u.priority,
+		Org:             u.org,
+		StreamPace:      u.streamPace.Load(),
+	}
+}
+
+// activeSuspension returns a copy of the user's suspension, or nil if there is
+// none in force. Caller must hold Limiter.mu.
+func (u *userLimit) activeSuspension() *Suspension {
+	if !u.suspension.active(time.Now()) {
+		return nil
+	}
+	s := *u.suspension
+	return &s
 }
 
 func (l *Limiter) GetAllLimits() map[string]LimitInfo {
@@ -137,23 +531,15 @@ func (l *Limiter) GetAllLimits() map[string]LimitInfo {
 	out := make(map[string]LimitInfo)
 
 	// Seed all registered users, using their current limiter state if known
-	// or free-tier defaults if they haven't made a request yet.
+	// or the default plan if they haven't made a request yet.
 	for _, u := range users.All() {
-		if lu, ok := l.users[u.ID]; ok {
-			out[u.ID] = LimitInfo{
-				MaxTokens:       lu.maxTokens,
-				MaxTokensPerReq: lu.maxTokensPerReq,
-				UsedTokens:      lu.usedTokens.Load(),
-				RPS:             float64(lu.limiter.Limit()),
-			}
+		lu, ok := l.users[u.ID]
+		if ok {
+			lu.roll(time.Now())
 		} else {
-			out[u.ID] = LimitInfo{
-				MaxTokens:       FREE_TIER_TOKENS,
-				MaxTokensPerReq: FREE_TIER_TOKENS_PER_REQ,
-				UsedTokens:      0,
-				RPS:             FREE_TIER_RPS,
-			}
+			lu = l.newUser()
 		}
+		out[u.ID] = lu.info()
 	}
 	return out
 }
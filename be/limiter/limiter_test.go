@@ -1,6 +1,7 @@
 package limiter_test
 
 import (
+	"errors"
 	"lb/limiter"
 	"testing"
 	"time"
@@ -12,7 +13,7 @@ func TestCheckRPS_ExplicitUnlimited(t *testing.T) {
 	lim.SetLimits("user-a", limiter.INF_RPS, 0, 0)
 	// With unlimited RPS, 100 rapid calls should all pass.
 	for i := 0; i < 100; i++ {
-		if err := lim.CheckRPS("user-a"); err != nil {
+		if err := lim.CheckRPS("user-a", ""); err != nil {
 			t.Fatalf("expected no error on call %d, got: %v", i, err)
 		}
 	}
@@ -23,21 +24,21 @@ func TestCheckRPS_LimitEnforced(t *testing.T) {
 	lim.SetLimits("user-b", 2, 0, 0) // 2 RPS, no token quota, no per-request cap
 
 	// First two calls should pass (burst = RPS).
-	if err := lim.CheckRPS("user-b"); err != nil {
+	if err := lim.CheckRPS("user-b", ""); err != nil {
 		t.Fatalf("call 1 should pass: %v", err)
 	}
-	if err := lim.CheckRPS("user-b"); err != nil {
+	if err := lim.CheckRPS("user-b", ""); err != nil {
 		t.Fatalf("call 2 should pass: %v", err)
 	}
 
 	// Third call in the same instant should be rejected.
-	if err := lim.CheckRPS("user-b"); err == nil {
+	if err := lim.CheckRPS("user-b", ""); err == nil {
 		t.Fatal("call 3 should have been rate-limited")
 	}
 
 	// After 1 second, bucket refills — should pass again.
 	time.Sleep(1100 * time.Millisecond)
-	if err := lim.CheckRPS("user-b"); err != nil {
+	if err := lim.CheckRPS("user-b", ""); err != nil {
 		t.Fatalf("call after refill should pass: %v", err)
 	}
 }
@@ -46,14 +47,14 @@ func TestCheckQuota_LimitEnforced(t *testing.T) {
 	lim := limiter.New()
 	lim.SetLimits("user-c", 0, 10, 0) // unlimited RPS, 10 token quota, no per-request cap
 
-	if err := lim.CheckQuota("user-c"); err != nil {
+	if err := lim.CheckQuota("user-c", ""); err != nil {
 		t.Fatalf("should pass before consuming tokens: %v", err)
 	}
 
 	// Consume exactly quota + grace; should now be rejected.
-	lim.ConsumeTokens("user-c", 15) // 10 quota + 5 grace
+	lim.ConsumeTokens("user-c", "", 15) // 10 quota + 5 grace
 
-	if err := lim.CheckQuota("user-c"); err == nil {
+	if err := lim.CheckQuota("user-c", ""); err == nil {
 		t.Fatal("should be rejected after quota + grace exhausted")
 	}
 }
@@ -62,20 +63,20 @@ func TestConsumeTokens_Accumulates(t *testing.T) {
 	lim := limiter.New()
 	lim.SetLimits("user-d", 0, 100, 0)
 
-	lim.ConsumeTokens("user-d", 30)
-	lim.ConsumeTokens("user-d", 30)
-	lim.ConsumeTokens("user-d", 30)
+	lim.ConsumeTokens("user-d", "", 30)
+	lim.ConsumeTokens("user-d", "", 30)
+	lim.ConsumeTokens("user-d", "", 30)
 
 	// 90 used, 10 remaining + 5 grace — should still pass
-	if err := lim.CheckQuota("user-d"); err != nil {
+	if err := lim.CheckQuota("user-d", ""); err != nil {
 		t.Fatalf("90/100 used, should still pass: %v", err)
 	}
 
 	// Consume remaining quota + grace (10 + 5 = 15) to trigger rejection
-	lim.ConsumeTokens("user-d", 15)
+	lim.ConsumeTokens("user-d", "", 15)
 
 	// 105/100 — exceeds quota + grace, should fail
-	if err := lim.CheckQuota("user-d"); err == nil {
+	if err := lim.CheckQuota("user-d", ""); err == nil {
 		t.Fatal("105/100 used, should be rejected")
 	}
 }
@@ -83,16 +84,562 @@ func TestConsumeTokens_Accumulates(t *testing.T) {
 func TestSetLimits_ResetsUsage(t *testing.T) {
 	lim := limiter.New()
 	lim.SetLimits("user-e", 0, 5, 0)
-	lim.ConsumeTokens("user-e", 10) // consume quota (5) + grace (5)
+	lim.ConsumeTokens("user-e", "", 10) // consume quota (5) + grace (5)
 
 	// Quota + grace exhausted — should be rejected
-	if err := lim.CheckQuota("user-e"); err == nil {
+	if err := lim.CheckQuota("user-e", ""); err == nil {
 		t.Fatal("should be rejected after quota + grace consumed")
 	}
 
 	// Admin resets limits — usage counter is reset
 	lim.SetLimits("user-e", 0, 100, 0)
-	if err := lim.CheckQuota("user-e"); err != nil {
+	if err := lim.CheckQuota("user-e", ""); err != nil {
 		t.Fatalf("after limit reset, should pass: %v", err)
 	}
 }
+
+func TestModelAllowed(t *testing.T) {
+	lim := limiter.New()
+
+	// No allowlist configured — every model is allowed.
+	if !lim.ModelAllowed("user-f", "moondream") {
+		t.Fatal("expected all models allowed by default")
+	}
+
+	lim.SetAllowedModels("user-f", []string{"llama3.2", "moondream:latest"})
+	cases := map[string]bool{
+		"llama3.2":         true,
+		"llama3.2:1b":      true, // untagged entry matches every tag
+		"moondream:latest": true,
+		"moondream":        true,  // an untagged model is its :latest tag
+		"moondream:1.8b":   false, // tagged entry only matches that tag
+		"mistral":          false,
+	}
+	for model, want := range cases {
+		if got := lim.ModelAllowed("user-f", model); got != want {
+			t.Errorf("ModelAllowed(%q) = %v, want %v", model, got, want)
+		}
+	}
+
+	// Clearing the allowlist restores access to every model.
+	lim.SetAllowedModels("user-f", nil)
+	if !lim.ModelAllowed("user-f", "mistral") {
+		t.Fatal("expected all models allowed after clearing allowlist")
+	}
+}
+
+func TestCheckWindows_TokensPerMinute(t *testing.T) {
+	lim := limiter.New()
+	lim.SetWindows("user-g", limiter.WindowLimits{TokensPerMinute: 100})
+
+	lim.ConsumeTokens("user-g", "", 60)
+	if err := lim.CheckWindows("user-g"); err != nil {
+		t.Fatalf("60/100 tokens this minute, should pass: %v", err)
+	}
+	lim.ConsumeTokens("user-g", "", 40)
+
+	err := lim.CheckWindows("user-g")
+	var werr *limiter.WindowError
+	if !errors.As(err, &werr) {
+		t.Fatalf("expected *WindowError, got %v", err)
+	}
+	if werr.Window != limiter.WindowTokensPerMinute || werr.Limit != 100 {
+		t.Errorf("got window %s limit %d, want tokens_per_minute 100", werr.Window, werr.Limit)
+	}
+	if until := time.Until(werr.ResetAt); until <= 0 || until > time.Minute {
+		t.Errorf("reset should be within the next minute, got %s", until)
+	}
+}
+
+func TestCheckWindows_RequestsPerDay(t *testing.T) {
+	lim := limiter.New()
+	lim.SetWindows("user-h", limiter.WindowLimits{RequestsPerDay: 2, TokensPerHour: limiter.INF_TOKENS})
+
+	// Checking alone never uses up the budget; only counted requests do.
+	for i := 0; i < 5; i++ {
+		if err := lim.CheckWindows("user-h"); err != nil {
+			t.Fatalf("check %d should pass: %v", i, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := lim.CountRequest("user-h"); err != nil {
+			t.Fatalf("request %d should pass: %v", i, err)
+		}
+	}
+	var werr *limiter.WindowError
+	if err := lim.CheckWindows("user-h"); !errors.As(err, &werr) || werr.Window != limiter.WindowRequestsPerDay {
+		t.Fatalf("third request should hit requests_per_day, got %v", err)
+	}
+	if err := lim.CountRequest("user-h"); !errors.As(err, &werr) {
+		t.Fatalf("a third count should be refused too, got %v", err)
+	}
+
+	// 0 leaves a cap unchanged; -1 removes it.
+	lim.SetWindows("user-h", limiter.WindowLimits{RequestsPerDay: limiter.INF_REQUESTS})
+	if err := lim.CountRequest("user-h"); err != nil {
+		t.Fatalf("unlimited requests_per_day should pass: %v", err)
+	}
+	if got := lim.Windows("user-h"); got.RequestsPerDay != -1 || got.TokensPerMinute != -1 {
+		t.Errorf("unexpected windows after update: %+v", got)
+	}
+}
+
+func TestAcquire_MaxConcurrent(t *testing.T) {
+	lim := limiter.New()
+	lim.SetMaxConcurrent("user-i", 2)
+
+	for i := 0; i < 2; i++ {
+		if err := lim.Acquire("user-i"); err != nil {
+			t.Fatalf("slot %d should be granted: %v", i, err)
+		}
+	}
+	if err := lim.Acquire("user-i"); err == nil {
+		t.Fatal("third concurrent request should be rejected")
+	}
+	lim.Release("user-i")
+	if err := lim.Acquire("user-i"); err != nil {
+		t.Fatalf("released slot should be reusable: %v", err)
+	}
+
+	lim.SetMaxConcurrent("user-i", limiter.INF_CONCURRENT)
+	if err := lim.Acquire("user-i"); err != nil {
+		t.Fatalf("unlimited concurrency should pass: %v", err)
+	}
+}
+
+func TestModelLimits_ApplyOnTopOfUserWide(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("user-j", 100, 20, 500)
+	lim.SetModelLimits("user-j", "moondream", 0, 10, 50) // no RPS limit of its own
+
+	if got := lim.MaxTokensPerRequest("user-j", "moondream:latest"); got != 50 {
+		t.Errorf("moondream per-request cap: got %d, want 50", got)
+	}
+	if got := lim.MaxTokensPerRequest("user-j", "llama3.2:1b"); got != 500 {
+		t.Errorf("llama3.2 per-request cap: got %d, want user-wide 500", got)
+	}
+
+	lim.ConsumeTokens("user-j", "moondream:latest", 15) // model quota 10 + grace 5
+	if err := lim.CheckQuota("user-j", "moondream:latest"); err == nil {
+		t.Error("moondream quota should be exhausted")
+	}
+	if err := lim.CheckQuota("user-j", "llama3.2:1b"); err != nil {
+		t.Errorf("other models use the user-wide quota, should pass: %v", err)
+	}
+	lim.ConsumeTokens("user-j", "llama3.2:1b", 10) // 25 user-wide = quota 20 + grace 5
+	if err := lim.CheckQuota("user-j", "llama3.2:1b"); err == nil {
+		t.Error("moondream usage should count towards the user-wide quota")
+	}
+
+	// Looser model limits do not lift the user-wide ones.
+	lim.SetModelLimits("user-j", "moondream", 0, 1000, 5000)
+	if got := lim.MaxTokensPerRequest("user-j", "moondream"); got != 500 {
+		t.Errorf("moondream per-request cap above the user-wide one: got %d, want 500", got)
+	}
+	if err := lim.CheckQuota("user-j", "moondream"); err == nil {
+		t.Error("a larger model quota should not lift the exhausted user-wide quota")
+	}
+
+	// All zero removes the model's limits.
+	lim.SetModelLimits("user-j", "moondream", 0, 0, 0)
+	if got := lim.MaxTokensPerRequest("user-j", "moondream:latest"); got != 500 {
+		t.Errorf("after removal moondream uses the user-wide cap, got %d", got)
+	}
+}
+
+func TestModelLimits_RPS(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("user-k", limiter.INF_RPS, 0, 0)
+	lim.SetModelLimits("user-k", "moondream", 1, 0, 0)
+
+	if err := lim.CheckRPS("user-k", "moondream"); err != nil {
+		t.Fatalf("first moondream call should pass: %v", err)
+	}
+	if err := lim.CheckRPS("user-k", "moondream"); err == nil {
+		t.Fatal("second moondream call within a second should be rejected")
+	}
+	if err := lim.CheckRPS("user-k", "llama3.2:1b"); err != nil {
+		t.Fatalf("other models use the unlimited user-wide bucket: %v", err)
+	}
+
+	// The user-wide bucket applies to the model too, and a request the model
+	// bucket rejects takes nothing from the user-wide one.
+	lim.SetLimits("user-k", 1, 0, 0)
+	lim.SetModelLimits("user-k", "moondream", 100, 0, 0)
+	if err := lim.CheckRPS("user-k", "moondream"); err != nil {
+		t.Fatalf("first call under both buckets should pass: %v", err)
+	}
+	if err := lim.CheckRPS("user-k", "moondream"); err == nil {
+		t.Fatal("the user-wide bucket of 1 should reject the second moondream call")
+	}
+
+	lim.SetLimits("user-s", 2, 0, 0)
+	lim.SetModelLimits("user-s", "moondream", 1, 0, 0)
+	lim.CheckRPS("user-s", "moondream")
+	if err := lim.CheckRPS("user-s", "moondream"); err == nil {
+		t.Fatal("the moondream bucket of 1 should reject the second call")
+	}
+	if err := lim.CheckRPS("user-s", "llama3.2:1b"); err != nil {
+		t.Fatalf("the rejected moondream call should not use up the user-wide bucket: %v", err)
+	}
+}
+
+func TestReserve_ModelAndUserWideQuota(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("user-t", 0, 100, 0)
+	lim.SetModelLimits("user-t", "moondream", 0, 40, 0)
+
+	res, err := lim.Reserve("user-t", "moondream", 30)
+	if err != nil {
+		t.Fatalf("30 fits both quotas: %v", err)
+	}
+	if _, err := lim.Reserve("user-t", "moondream", 20); err == nil {
+		t.Fatal("30 reserved + 20 exceeds the moondream quota of 40, should be rejected")
+	}
+	if _, err := lim.Reserve("user-t", "llama3.2:1b", 80); err == nil {
+		t.Fatal("moondream's 30 reserved count towards the user-wide quota, 80 should not fit")
+	}
+	if got := lim.Allowance("user-t", "moondream", res); got != 40 {
+		t.Errorf("moondream allowance: got %d, want the model's 40", got)
+	}
+	if got := lim.Status("user-t", "moondream").RemainingTokens; got != 10 {
+		t.Errorf("moondream remaining tokens: got %d, want 10", got)
+	}
+
+	lim.ConsumeTokens("user-t", "moondream", 30)
+	res.Release()
+	if got := lim.Status("user-t", "llama3.2:1b").RemainingTokens; got != 70 {
+		t.Errorf("user-wide remaining tokens: got %d, want 70", got)
+	}
+}
+
+func TestReserve_RejectsWhatCannotFit(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("user-l", 0, 100, 0)
+
+	first, err := lim.Reserve("user-l", "", 60)
+	if err != nil {
+		t.Fatalf("60/100 should fit: %v", err)
+	}
+	if _, err := lim.Reserve("user-l", "", 50); err == nil {
+		t.Fatal("60 reserved + 50 exceeds the quota of 100, should be rejected")
+	}
+
+	// Reconcile: the request actually used 20 tokens.
+	lim.ConsumeTokens("user-l", "", 20)
+	first.Release()
+	first.Release() // idempotent
+
+	if _, err := lim.Reserve("user-l", "", 80); err != nil {
+		t.Fatalf("20 used leaves 80, should fit: %v", err)
+	}
+	if _, err := lim.Reserve("user-l", "", 1); err == nil {
+		t.Fatal("quota fully reserved, should be rejected")
+	}
+}
+
+func TestReserve_Unlimited(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("user-m", 0, limiter.INF_TOKENS, 0)
+	res, err := lim.Reserve("user-m", "", 1<<40)
+	if err != nil {
+		t.Fatalf("unlimited quota should always fit: %v", err)
+	}
+	res.Release() // nil reservation is a no-op
+}
+
+func TestAllowance_QuotaAndWindows(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("user-z", 0, 100, 0)
+	lim.ConsumeTokens("user-z", "", 30)
+	res, err := lim.Reserve("user-z", "", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Its own reservation is available to the request; others' are not.
+	if _, err := lim.Reserve("user-z", "", 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := lim.Allowance("user-z", "", res); got != 60 {
+		t.Errorf("allowance = %d, want 60", got)
+	}
+	lim.SetWindows("user-z", limiter.WindowLimits{TokensPerMinute: 40})
+	if got := lim.Allowance("user-z", "", res); got != 10 {
+		t.Errorf("allowance under tokens_per_minute = %d, want 10", got)
+	}
+}
+
+func TestPlans_LiveUpdateKeepsOverrides(t *testing.T) {
+	lim := limiter.New()
+	if _, err := lim.DefinePlan("pro", limiter.Plan{RPS: 10, MaxTokens: 1000, MaxTokensPerReq: 100, MaxConcurrent: 5}); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{"user-n", "user-o"} {
+		if err := lim.AssignPlan(u, "pro", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lim.ConsumeTokens("user-n", "", 40)
+	lim.SetMaxConcurrent("user-o", 1) // override on top of the plan
+
+	info, err := lim.DefinePlan("pro", limiter.Plan{RPS: 10, MaxTokens: 2000, MaxTokensPerReq: 200, MaxConcurrent: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Users != 2 {
+		t.Errorf("users updated = %d, want 2", info.Users)
+	}
+	if got := lim.MaxTokensPerRequest("user-n", ""); got != 200 {
+		t.Errorf("per-request cap after plan change = %d, want 200", got)
+	}
+	if _, err := lim.Reserve("user-n", "", 1961); err == nil {
+		t.Error("plan change should keep usage: 40 used leaves 1960 of 2000")
+	}
+	if got := lim.MaxConcurrent("user-n"); got != 8 {
+		t.Errorf("concurrency from plan = %d, want 8", got)
+	}
+	if got := lim.MaxConcurrent("user-o"); got != 1 {
+		t.Errorf("override should survive the plan change; concurrency = %d, want 1", got)
+	}
+
+	if err := lim.AssignPlan("user-o", "pro", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := lim.MaxConcurrent("user-o"); got != 8 {
+		t.Errorf("cleared overrides should restore the plan; concurrency = %d, want 8", got)
+	}
+}
+
+func TestPlans_Unknown(t *testing.T) {
+	lim := limiter.New()
+	if err := lim.AssignPlan("user-p", "gold", false); err == nil {
+		t.Error("assigning an undefined plan should fail")
+	}
+	if _, err := lim.DefinePlan("broken", limiter.Plan{RPS: 1}); err == nil {
+		t.Error("a plan with zero limits should be rejected")
+	}
+}
+
+func TestSuspend_KeepsLimitsAndExpires(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("user-q", 5, 100, 0)
+	lim.ConsumeTokens("user-q", "", 30)
+
+	lim.Suspend("user-q", limiter.Suspension{Reason: "abuse", By: "admin", At: time.Now()})
+	var serr *limiter.SuspendedError
+	if err := lim.CheckSuspended("user-q"); !errors.As(err, &serr) || serr.Reason != "abuse" {
+		t.Fatalf("expected SuspendedError with reason, got %v", err)
+	}
+	if !lim.Unsuspend("user-q") {
+		t.Fatal("Unsuspend should report the user was suspended")
+	}
+	if err := lim.CheckSuspended("user-q"); err != nil {
+		t.Fatalf("unsuspended user should pass: %v", err)
+	}
+	// Limits and usage are as they were before the suspension.
+	if _, err := lim.Reserve("user-q", "", 71); err == nil {
+		t.Error("30 of 100 used should still be counted after unsuspend")
+	}
+
+	lim.Suspend("user-q", limiter.Suspension{At: time.Now(), Until: time.Now().Add(-time.Second)})
+	if err := lim.CheckSuspended("user-q"); err != nil {
+		t.Errorf("expired suspension should not block: %v", err)
+	}
+	if lim.Unsuspend("user-q") {
+		t.Error("Unsuspend of an expired suspension should report false")
+	}
+}
+
+func TestPeriodBounds(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	cases := []struct {
+		period     string
+		billingDay int
+		now        string
+		start, end string
+	}{
+		{limiter.PeriodDaily, 0, "2025-03-10T15:04:05Z", "2025-03-10T00:00:00Z", "2025-03-11T00:00:00Z"},
+		// 2025-03-12 is a Wednesday; default anchor is Monday.
+		{limiter.PeriodWeekly, 0, "2025-03-12T08:00:00Z", "2025-03-10T00:00:00Z", "2025-03-17T00:00:00Z"},
+		{limiter.PeriodWeekly, 7, "2025-03-12T08:00:00Z", "2025-03-09T00:00:00Z", "2025-03-16T00:00:00Z"},
+		{limiter.PeriodMonthly, 0, "2025-03-12T08:00:00Z", "2025-03-01T00:00:00Z", "2025-04-01T00:00:00Z"},
+		{limiter.PeriodMonthly, 15, "2025-03-12T08:00:00Z", "2025-02-15T00:00:00Z", "2025-03-15T00:00:00Z"},
+		// Day 31 clamps to the end of shorter months, across a year boundary.
+		{limiter.PeriodMonthly, 31, "2025-01-05T00:00:00Z", "2024-12-31T00:00:00Z", "2025-01-31T00:00:00Z"},
+		{limiter.PeriodMonthly, 31, "2025-02-28T12:00:00Z", "2025-02-28T00:00:00Z", "2025-03-31T00:00:00Z"},
+	}
+	for _, c := range cases {
+		start, end := limiter.PeriodBounds(c.period, c.billingDay, at(c.now))
+		if !start.Equal(at(c.start)) || !end.Equal(at(c.end)) {
+			t.Errorf("%s/%d at %s: got [%s, %s), want [%s, %s)", c.period, c.billingDay, c.now,
+				start.Format(time.RFC3339), end.Format(time.RFC3339), c.start, c.end)
+		}
+	}
+	if start, end := limiter.PeriodBounds(limiter.PeriodNone, 0, time.Now()); !start.IsZero() || !end.IsZero() {
+		t.Error("PeriodNone should have no bounds")
+	}
+}
+
+func TestQuotaPeriod_RollsOverUsage(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("user-r", 5, 100, 0)
+	if err := lim.SetQuotaPeriod("user-r", limiter.PeriodMonthly, 15); err != nil {
+		t.Fatal(err)
+	}
+	lim.ConsumeTokens("user-r", "", 100)
+	if err := lim.CheckQuota("user-r", ""); err != nil {
+		t.Fatalf("within grace: %v", err)
+	}
+	q := lim.Quota("user-r")
+	if q.UsedTokens != 100 || q.Remaining != 0 || q.PeriodStart.Day() != 15 {
+		t.Fatalf("unexpected quota before rollover: %+v", q)
+	}
+
+	lim.EndPeriod("user-r")
+	q = lim.Quota("user-r")
+	if q.UsedTokens != 0 || q.Remaining != 100 {
+		t.Errorf("usage should reset at the period boundary: %+v", q)
+	}
+	if !q.PeriodEnd.After(time.Now()) {
+		t.Errorf("new period should end in the future, got %s", q.PeriodEnd)
+	}
+
+	if err := lim.SetQuotaPeriod("user-r", limiter.PeriodWeekly, 0); err == nil {
+		t.Error("billing day 15 is not a weekday; switching to weekly should fail")
+	}
+}
+
+func TestOrg_LimitsSharedByMembers(t *testing.T) {
+	lim := limiter.New()
+	if _, err := lim.DefineOrg("acme", limiter.OrgLimits{RPS: 3, MaxTokens: 100}); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{"org-a", "org-b"} {
+		if err := lim.SetOrg(u, "acme"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each member is well within their own limits; together they exhaust the org's.
+	lim.ConsumeTokens("org-a", "", 60)
+	if _, err := lim.Reserve("org-b", "", 50); err == nil {
+		t.Error("reservation past the org quota should fail")
+	}
+	lim.ConsumeTokens("org-b", "", 50)
+	if err := lim.CheckQuota("org-a", ""); err == nil {
+		t.Error("org quota exhausted by both members should block org-a")
+	}
+	if err := lim.CheckQuota("outsider", ""); err != nil {
+		t.Errorf("non-members are unaffected: %v", err)
+	}
+
+	var rejected int
+	for _, u := range []string{"org-a", "org-b", "org-a", "org-b"} {
+		if lim.CheckRPS(u, "") != nil {
+			rejected++
+		}
+	}
+	if rejected != 1 {
+		t.Errorf("org RPS burst of 3 across members: %d of 4 rejected, want 1", rejected)
+	}
+
+	info := lim.Orgs()[0]
+	if info.Quota.UsedTokens != 110 || len(info.Members) != 2 {
+		t.Errorf("org info = %+v", info)
+	}
+	if err := lim.SetOrg("org-a", ""); err != nil || lim.CheckQuota("org-a", "") != nil {
+		t.Errorf("leaving the org should lift its limits: %v", err)
+	}
+}
+
+func TestOrg_WindowErrorNamesOrg(t *testing.T) {
+	lim := limiter.New()
+	if _, err := lim.DefineOrg("acme", limiter.OrgLimits{RPS: -1, MaxTokens: -1, Windows: limiter.WindowLimits{RequestsPerDay: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	lim.SetOrg("org-c", "acme")
+	lim.SetOrg("org-d", "acme")
+	if err := lim.CountRequest("org-c"); err != nil {
+		t.Fatal(err)
+	}
+	var werr *limiter.WindowError
+	if err := lim.CheckWindows("org-d"); !errors.As(err, &werr) || werr.Org != "acme" {
+		t.Fatalf("expected the org's window to reject org-d, got %v", err)
+	}
+}
+
+func TestUpdateLimits_OnlyTouchesWhatIsSet(t *testing.T) {
+	lim := limiter.New()
+	lim.SetLimits("user-u", 5, 100, 20)
+	lim.ConsumeTokens("user-u", "", 40)
+
+	tpm := int64(1000)
+	info, err := lim.UpdateLimits("user-u", limiter.LimitUpdate{TokensPerMinute: &tpm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.RPS != 5 || info.MaxTokens != 100 || info.MaxTokensPerReq != 20 || info.Windows.TokensPerMinute != 1000 {
+		t.Errorf("only tokens_per_minute should change: %+v", info)
+	}
+	if info.UsedTokens != 40 {
+		t.Errorf("usage should be kept, got %d", info.UsedTokens)
+	}
+
+	inf := int64(limiter.INF_TOKENS)
+	info, err = lim.UpdateLimits("user-u", limiter.LimitUpdate{MaxTokens: &inf, ResetUsage: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MaxTokens != limiter.INF_TOKENS || info.UsedTokens != 0 || info.Windows.TokensPerMinute != 1000 {
+		t.Errorf("unexpected limits after removing the quota: %+v", info)
+	}
+
+	zero, day := int64(0), 40
+	if _, err := lim.UpdateLimits("user-u", limiter.LimitUpdate{MaxTokensPerReq: &zero}); err == nil {
+		t.Error("max_tokens_per_request 0 should be rejected")
+	}
+	period := limiter.PeriodMonthly
+	if _, err := lim.UpdateLimits("user-u", limiter.LimitUpdate{TokensPerMinute: &inf, QuotaPeriod: &period, BillingDay: &day}); err == nil {
+		t.Error("billing day 40 should be rejected")
+	}
+	if got := lim.Limits("user-u"); got.Windows.TokensPerMinute != 1000 {
+		t.Errorf("a rejected update must change nothing: %+v", got)
+	}
+}
+
+func TestComputeQuota(t *testing.T) {
+	lim := limiter.New()
+	if _, err := lim.DefinePlan("metered", limiter.Plan{RPS: -1, MaxTokens: -1, MaxTokensPerReq: -1, MaxConcurrent: -1,
+		MaxComputeSeconds: 2, QuotaPeriod: limiter.PeriodDaily}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lim.AssignPlan("user-m", "metered", false); err != nil {
+		t.Fatal(err)
+	}
+
+	lim.ConsumeCompute("user-m", 1500*time.Millisecond)
+	if err := lim.CheckQuota("user-m", ""); err != nil {
+		t.Fatalf("1.5 of 2 compute seconds used, should pass: %v", err)
+	}
+	lim.ConsumeCompute("user-m", 600*time.Millisecond)
+	if err := lim.CheckQuota("user-m", ""); err == nil {
+		t.Fatal("compute quota exhausted, should be rejected")
+	}
+	if q := lim.Quota("user-m"); q.MaxComputeSeconds != 2 || q.RemainingComputeSeconds != 0 {
+		t.Errorf("unexpected quota: %+v", q)
+	}
+
+	lim.EndPeriod("user-m")
+	if err := lim.CheckQuota("user-m", ""); err != nil {
+		t.Errorf("compute usage should reset with the quota period: %v", err)
+	}
+	if q := lim.Quota("outsider"); q.MaxComputeSeconds != limiter.INF_COMPUTE {
+		t.Errorf("the free plan has no compute quota, got %d", q.MaxComputeSeconds)
+	}
+}
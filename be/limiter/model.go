@@ -0,0 +1,101 @@
+package limiter
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+)
+
+// modelLimit scopes a user's RPS, token quota and per-request cap to one
+// model, on top of the user-wide limits: a request for the model must fit
+// both. A zero or unlimited field adds no limit of its own.
+type modelLimit struct {
+	limiter         *rate.Limiter // nil = only the user-wide RPS applies
+	maxTokens       int64         // 0 or INF_TOKENS = only the user-wide quota applies
+	maxTokensPerReq int64         // 0 or INF_TOKEN_PER_REQ = only the user-wide cap applies
+	usedTokens      atomic.Int64  // tokens consumed on this model since its limits were set
+	reservedTokens  atomic.Int64  // tokens set aside for requests in flight on this model
+}
+
+// SetModelLimits scopes RPS, token quota and per-request cap to one model for
+// a user. The model is matched like the allowlist: an untagged name
+// ("moondream") covers every tag. The model's limits apply in addition to the
+// user-wide ones, which keep counting the model's requests and tokens. A 0 or
+// -1 field leaves that limit to the user-wide one alone; setting all three to
+// 0 removes the model's limits entirely. The model's usage counter is reset.
+func (l *Limiter) SetModelLimits(user, model string, rps int, maxTokens, maxTokensPerReq int64) {
+	u := l.getOrCreate(user)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rps == 0 && maxTokens == 0 && maxTokensPerReq == 0 {
+		delete(u.models, model)
+		return
+	}
+	m := &modelLimit{maxTokens: maxTokens, maxTokensPerReq: maxTokensPerReq}
+	switch {
+	case rps == INF_RPS:
+		m.limiter = rate.NewLimiter(rate.Inf, 0)
+	case rps > 0:
+		m.limiter = rate.NewLimiter(rate.Limit(rps), rps)
+	}
+	if u.models == nil {
+		u.models = make(map[string]*modelLimit)
+	}
+	u.models[model] = m
+}
+
+// tighterCap returns the tighter of a user-wide cap (INF_* = unlimited) and a
+// model-scoped one, which adds no cap when 0 or INF_*.
+func tighterCap(user, model int64) int64 {
+	if model <= 0 || (user != -1 && user <= model) {
+		return user
+	}
+	return model
+}
+
+// modelLimitFor returns the limits scoped to model, or nil if there are none.
+func (l *Limiter) modelLimitFor(u *userLimit, model string) *modelLimit {
+	if model == "" {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if m, ok := u.models[model]; ok {
+		return m
+	}
+	name, _, _ := strings.Cut(model, ":")
+	return u.models[name]
+}
+
+// ModelLimitInfo holds the limits scoped to one model (used by admin UI).
+// They apply on top of the user-wide limits; 0 and -1 add no limit.
+type ModelLimitInfo struct {
+	MaxTokens       int64
+	MaxTokensPerReq int64
+	UsedTokens      int64
+	RPS             float64
+}
+
+// modelLimitInfos snapshots a user's model limits. Caller must hold Limiter.mu.
+func (u *userLimit) modelLimitInfos() map[string]ModelLimitInfo {
+	if len(u.models) == 0 {
+		return nil
+	}
+	out := make(map[string]ModelLimitInfo, len(u.models))
+	for model, m := range u.models {
+		info := ModelLimitInfo{
+			MaxTokens:       m.maxTokens,
+			MaxTokensPerReq: m.maxTokensPerReq,
+			UsedTokens:      m.usedTokens.Load(),
+		}
+		if m.limiter != nil {
+			info.RPS = float64(m.limiter.Limit())
+			if m.limiter.Limit() == rate.Inf {
+				info.RPS = INF_RPS
+			}
+		}
+		out[model] = info
+	}
+	return out
+}
@@ -0,0 +1,200 @@
+package limiter
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// OrgLimits are an organization's own limits. They are shared by all of its
+// members and enforced together with each member's individual limits: a
+// request must fit both. -1 (INF_*) removes a limit; window caps of 0 are not
+// enforced.
+type OrgLimits struct {
+	RPS         int
+	MaxTokens   int64
+	Windows     WindowLimits
+	QuotaPeriod string // how often MaxTokens resets; "" = PeriodNone
+	BillingDay  int    // anchor of QuotaPeriod (see periodBounds); 0 = default
+}
+
+// Validate checks the limits like Plan.Validate, and the billing day against
+// the quota period.
+func (o OrgLimits) Validate() error {
+	if o.RPS == 0 || o.RPS < -1 {
+		return fmt.Errorf("field \"rps\" must be > 0 or -1 (unlimited); got %d", o.RPS)
+	}
+	if o.MaxTokens == 0 || o.MaxTokens < -1 {
+		return fmt.Errorf("field \"max_tokens\" must be > 0 or -1 (unlimited); got %d", o.MaxTokens)
+	}
+	if !validPeriod(o.QuotaPeriod) {
+		return fmt.Errorf("quota_period must be one of %q, %q, %q or %q; got %q",
+			PeriodNone, PeriodDaily, PeriodWeekly, PeriodMonthly, o.QuotaPeriod)
+	}
+	if o.BillingDay < 0 || o.BillingDay > 31 || (o.QuotaPeriod == PeriodWeekly && o.BillingDay > 7) {
+		return fmt.Errorf("billing_day %d is out of range for a %q period", o.BillingDay, o.QuotaPeriod)
+	}
+	// Windows share the plan rules.
+	return Plan{RPS: 1, MaxTokens: 1, MaxTokensPerReq: 1, MaxConcurrent: 1, Windows: o.Windows}.Validate()
+}
+
+// orgLimit holds rate + quota state for one organization.
+type orgLimit struct {
+	limits         OrgLimits                    // normalized; guarded by Limiter.mu
+	limiter        atomic.Pointer[rate.Limiter] // readable without Limiter.mu
+	maxTokens      atomic.Int64                 // limits.MaxTokens, readable without Limiter.mu
+	usedTokens     atomic.Int64                 // tokens consumed by every member in the current period
+	reservedTokens atomic.Int64                 // tokens set aside for members' requests in flight
+	windows        *windowSet
+	periodStart    time.Time // zero under PeriodNone; guarded by Limiter.mu
+	periodEnd      time.Time
+}
+
+// apply sets the organization's limits, keeping its usage. The RPS bucket is
+// only rebuilt when the rate changes. Caller must hold Limiter.mu.
+func (o *orgLimit) apply(ol OrgLimits) {
+	p := Plan{Windows: ol.Windows, QuotaPeriod: ol.QuotaPeriod}.normalized()
+	ol.Windows, ol.QuotaPeriod = p.Windows, p.QuotaPeriod
+	r, burst := rate.Limit(ol.RPS), ol.RPS
+	if ol.RPS == INF_RPS {
+		r, burst = rate.Inf, 0
+	}
+	if lim := o.limiter.Load(); lim == nil || lim.Limit() != r || lim.Burst() != burst {
+		o.limiter.Store(rate.NewLimiter(r, burst))
+	}
+	if ol.QuotaPeriod != o.limits.QuotaPeriod || ol.BillingDay != o.limits.BillingDay || o.windows == nil {
+		o.periodStart, o.periodEnd = periodBounds(ol.QuotaPeriod, ol.BillingDay, time.Now())
+	}
+	if o.windows == nil {
+		o.windows = newWindowSet()
+	}
+	o.windows.set(ol.Windows)
+	o.maxTokens.Store(ol.MaxTokens)
+	o.limits = ol
+}
+
+// roll starts a new quota period once the current one has ended. Caller must
+// hold Limiter.mu.
+func (o *orgLimit) roll(now time.Time) {
+	if o.periodEnd.IsZero() || now.Before(o.periodEnd) {
+		return
+	}
+	o.periodStart, o.periodEnd = periodBounds(o.limits.QuotaPeriod, o.limits.BillingDay, now)
+	o.usedTokens.Store(0)
+}
+
+// quota snapshots the organization's quota. Caller must hold Limiter.mu.
+func (o *orgLimit) quota() QuotaInfo {
+	q := QuotaInfo{
+		Period:      o.limits.QuotaPeriod,
+		BillingDay:  o.limits.BillingDay,
+		PeriodStart: o.periodStart,
+		PeriodEnd:   o.periodEnd,
+		MaxTokens:   o.limits.MaxTokens,
+		UsedTokens:  o.usedTokens.Load(),
+		Remaining:   INF_TOKENS,
+
+		MaxComputeSeconds:       INF_COMPUTE, // organizations have no compute quota
+		RemainingComputeSeconds: INF_COMPUTE,
+	}
+	if q.MaxTokens != INF_TOKENS {
+		q.Remaining = max(q.MaxTokens-q.UsedTokens, 0)
+	}
+	return q
+}
+
+// orgFor returns the organization the user belongs to, or nil.
+func (l *Limiter) orgFor(u *userLimit) *orgLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	o := l.orgs[u.org]
+	if o != nil {
+		o.roll(time.Now())
+	}
+	return o
+}
+
+// DefineOrg creates the named organization or replaces its limits. Usage
+// already counted against it is kept.
+func (l *Limiter) DefineOrg(name string, ol OrgLimits) (OrgInfo, error) {
+	if name == "" {
+		return OrgInfo{}, fmt.Errorf("organization name is required")
+	}
+	if err := ol.Validate(); err != nil {
+		return OrgInfo{}, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	o, ok := l.orgs[name]
+	if !ok {
+		o = &orgLimit{}
+		l.orgs[name] = o
+	}
+	o.apply(ol)
+	return l.orgInfoLocked(name, o), nil
+}
+
+// SetOrg makes the user a member of the named organization, leaving any
+// other; "" removes them from their organization. Tokens the user already
+// consumed stay with the organization they were consumed under.
+func (l *Limiter) SetOrg(user, org string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.orgs[org]; org != "" && !ok {
+		return fmt.Errorf("organization %q does not exist", org)
+	}
+	l.userLocked(user).org = org
+	return nil
+}
+
+// Org returns the name of the user's organization; "" = none.
+func (l *Limiter) Org(user string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.userLocked(user).org
+}
+
+// OrgInfo describes one organization (used by admin UI).
+type OrgInfo struct {
+	Name           string
+	Limits         OrgLimits
+	Members        []string // sorted
+	ReservedTokens int64
+	WindowUsage    map[string]int64 // window name → consumed within it
+	Quota          QuotaInfo
+}
+
+// orgInfoLocked snapshots one organization. Caller must hold Limiter.mu.
+func (l *Limiter) orgInfoLocked(name string, o *orgLimit) OrgInfo {
+	o.roll(time.Now())
+	info := OrgInfo{
+		Name:           name,
+		Limits:         o.limits,
+		Members:        []string{},
+		ReservedTokens: o.reservedTokens.Load(),
+		WindowUsage:    o.windows.usage(time.Now()),
+		Quota:          o.quota(),
+	}
+	for id, u := range l.users {
+		if u.org == name {
+			info.Members = append(info.Members, id)
+		}
+	}
+	sort.Strings(info.Members)
+	return info
+}
+
+// Orgs returns every organization, sorted by name.
+func (l *Limiter) Orgs() []OrgInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]OrgInfo, 0, len(l.orgs))
+	for name, o := range l.orgs {
+		out = append(out, l.orgInfoLocked(name, o))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,180 @@
+package limiter
+
+import (
+	"fmt"
+	"time"
+)
+
+// Quota periods. Under PeriodNone the token quota never resets on its own.
+const (
+	PeriodNone    = "none"
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+)
+
+// validPeriod reports whether p names a quota period ("" = inherit/none).
+func validPeriod(p string) bool {
+	switch p {
+	case "", PeriodNone, PeriodDaily, PeriodWeekly, PeriodMonthly:
+		return true
+	}
+	return false
+}
+
+// periodBounds returns the quota period that contains now, in UTC. Periods
+// start at midnight. billingDay anchors weekly periods to an ISO weekday
+// (1 = Monday … 7 = Sunday) and monthly ones to a day of the month (1–31,
+// clamped to the month's last day); 0 means 1. Under PeriodNone both bounds
+// are zero.
+func periodBounds(period string, billingDay int, now time.Time) (start, end time.Time) {
+	now = now.UTC()
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	day := max(billingDay, 1)
+	switch period {
+	case PeriodDaily:
+		return today, today.AddDate(0, 0, 1)
+	case PeriodWeekly:
+		back := (int(today.Weekday()) - day%7 + 7) % 7
+		start = today.AddDate(0, 0, -back)
+		return start, start.AddDate(0, 0, 7)
+	case PeriodMonthly:
+		anchor := func(y int, m time.Month) time.Time {
+			last := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+			return time.Date(y, m, min(day, last), 0, 0, 0, 0, time.UTC)
+		}
+		start = anchor(y, m)
+		if now.Before(start) {
+			start = anchor(y, m-1)
+		}
+		sy, sm, _ := start.Date()
+		return start, anchor(sy, sm+1)
+	}
+	return time.Time{}, time.Time{}
+}
+
+// roll starts a new quota period once the current one has ended, zeroing the
+// consumed tokens — user-wide and per model — and compute time. Tokens reserved by requests in
+// flight carry over. Caller must hold Limiter.mu.
+func (u *userLimit) roll(now time.Time) {
+	if u.periodEnd.IsZero() || now.Before(u.periodEnd) {
+		return
+	}
+	u.periodStart, u.periodEnd = periodBounds(u.period, u.billingDay, now)
+	u.usedTokens.Store(0)
+	u.usedCompute.Store(0)
+	for _, m := range u.models {
+		m.usedTokens.Store(0)
+	}
+}
+
+// setPeriod switches the user's quota period, recomputing its bounds when the
+// period or billing day changed. Usage is kept. Caller must hold Limiter.mu.
+func (u *userLimit) setPeriod(period string, billingDay int) {
+	if period == u.period && billingDay == u.billingDay && !u.periodEnd.IsZero() {
+		return
+	}
+	u.period, u.billingDay = period, billingDay
+	u.periodStart, u.periodEnd = periodBounds(period, billingDay, time.Now())
+}
+
+// SetQuotaPeriod overrides the period after which the user's token quota
+// resets, on top of their plan, and anchors it to billingDay (see
+// periodBounds). Use "" to leave the period unchanged and PeriodNone to stop
+// resets; billingDay 0 leaves the anchor unchanged. Usage is kept.
+func (l *Limiter) SetQuotaPeriod(user, period string, billingDay int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.userLocked(user)
+	eff, day, err := l.resolvePeriod(u, period, billingDay)
+	if err != nil {
+		return err
+	}
+	if period != "" {
+		u.overrides.quotaPeriod = period
+	}
+	u.setPeriod(eff, day)
+	return nil
+}
+
+// resolvePeriod validates a SetQuotaPeriod request and returns the period and
+// billing day it results in. Caller must hold Limiter.mu.
+func (l *Limiter) resolvePeriod(u *userLimit, period string, billingDay int) (eff string, day int, err error) {
+	if !validPeriod(period) {
+		return "", 0, fmt.Errorf("quota_period must be one of %q, %q, %q or %q; got %q",
+			PeriodNone, PeriodDaily, PeriodWeekly, PeriodMonthly, period)
+	}
+	if billingDay < 0 || billingDay > 31 {
+		return "", 0, fmt.Errorf("billing_day must be between 1 and 31; got %d", billingDay)
+	}
+	eff, day = period, u.billingDay
+	if eff == "" {
+		eff = u.effectivePeriod(l.plans[u.plan])
+	}
+	if billingDay != 0 {
+		day = billingDay
+	}
+	if eff == PeriodWeekly && day > 7 {
+		return "", 0, fmt.Errorf("billing_day for a weekly period must be a weekday between 1 (Monday) and 7 (Sunday); got %d", day)
+	}
+	return eff, day, nil
+}
+
+// effectivePeriod is the user's quota period under plan p: their override,
+// else the plan's. Caller must hold Limiter.mu.
+func (u *userLimit) effectivePeriod(p Plan) string {
+	if u.overrides.quotaPeriod != "" {
+		return u.overrides.quotaPeriod
+	}
+	if p.QuotaPeriod != "" {
+		return p.QuotaPeriod
+	}
+	return PeriodNone
+}
+
+// QuotaInfo describes the user's token and compute quotas in the current period.
+type QuotaInfo struct {
+	Period      string
+	BillingDay  int
+	PeriodStart time.Time // zero under PeriodNone
+	PeriodEnd   time.Time
+	MaxTokens   int64 // INF_TOKENS = unlimited
+	UsedTokens  int64 // consumed in the current period
+	Remaining   int64 // INF_TOKENS = unlimited
+
+	MaxComputeSeconds       int64   // INF_COMPUTE = unlimited
+	UsedComputeSeconds      float64 // upstream time consumed in the current period
+	RemainingComputeSeconds float64 // INF_COMPUTE = unlimited
+}
+
+// Quota returns the user's token quota and its use in the current period.
+func (l *Limiter) Quota(user string) QuotaInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.userLocked(user).quota()
+}
+
+// quota snapshots the user's quota. Caller must hold Limiter.mu.
+func (u *userLimit) quota() QuotaInfo {
+	q := QuotaInfo{
+		Period:      u.period,
+		BillingDay:  u.billingDay,
+		PeriodStart: u.periodStart,
+		PeriodEnd:   u.periodEnd,
+		MaxTokens:   u.maxTokens.Load(),
+		UsedTokens:  u.usedTokens.Load(),
+		Remaining:   INF_TOKENS,
+
+		MaxComputeSeconds:       u.maxCompute.Load(),
+		UsedComputeSeconds:      time.Duration(u.usedCompute.Load()).Seconds(),
+		RemainingComputeSeconds: INF_COMPUTE,
+	}
+	if q.MaxTokens != INF_TOKENS {
+		q.Remaining = max(q.MaxTokens-q.UsedTokens, 0)
+	}
+	if q.MaxComputeSeconds != INF_COMPUTE {
+		q.RemainingComputeSeconds = max(float64(q.MaxComputeSeconds)-q.UsedComputeSeconds, 0)
+	}
+	return q
+}
@@ -0,0 +1,206 @@
+package limiter
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FreePlanName is the plan every Limiter starts with and assigns to new users
+// until another default is set.
+const FreePlanName = "free"
+
+// FreePlan is the built-in free tier. Configuration may redefine it.
+var FreePlan = Plan{
+	RPS:             1000,
+	MaxTokens:       100000,
+	MaxTokensPerReq: 4000,
+	MaxConcurrent:   INF_CONCURRENT,
+	Windows: WindowLimits{
+		TokensPerMinute: INF_TOKENS,
+		TokensPerHour:   INF_TOKENS,
+		TokensPerDay:    INF_TOKENS,
+		TokensPerMonth:  INF_TOKENS,
+		RequestsPerDay:  INF_REQUESTS,
+	},
+	QuotaPeriod:        PeriodNone,
+	Weight:             1,
+	StreamTokensPerSec: INF_TOKENS,
+	MaxComputeSeconds:  INF_COMPUTE,
+}
+
+// Plan is a named set of limits shared by every user assigned to it.
+// -1 (INF_*) removes a limit. Sliding-window caps, the stream pace and the
+// compute quota of 0 are stored as -1: a plan does not have to set them.
+type Plan struct {
+	RPS             int
+	MaxTokens       int64
+	MaxTokensPerReq int64
+	MaxConcurrent   int64
+	Windows         WindowLimits
+	QuotaPeriod     string // how often MaxTokens resets; "" = PeriodNone
+	Weight          int    // share of a saturated upstream relative to other plans; 0 = 1
+
+	// StreamTokensPerSec paces streamed completions: generated tokens are
+	// sent to the client no faster than this, however fast the upstream is.
+	StreamTokensPerSec int64
+
+	// MaxComputeSeconds caps the upstream time a user's requests may take
+	// per quota period (see ConsumeCompute).
+	MaxComputeSeconds int64
+}
+
+// Validate checks that every limit is either positive or -1 (unlimited), and
+// that window caps are not below -1.
+func (p Plan) Validate() error {
+	type field struct {
+		name  string
+		value int64
+	}
+	for _, f := range []field{
+		{"rps", int64(p.RPS)},
+		{"max_tokens", p.MaxTokens},
+		{"max_tokens_per_request", p.MaxTokensPerReq},
+		{"max_concurrent_requests", p.MaxConcurrent},
+	} {
+		if f.value == 0 || f.value < -1 {
+			return fmt.Errorf("field %q must be > 0 or -1 (unlimited); got %d", f.name, f.value)
+		}
+	}
+	if p.MaxComputeSeconds < -1 {
+		return fmt.Errorf("field \"max_compute_seconds\" must be > 0, 0 or -1 (unlimited); got %d", p.MaxComputeSeconds)
+	}
+	if p.StreamTokensPerSec < -1 {
+		return fmt.Errorf("field \"stream_tokens_per_second\" must be > 0, 0 or -1 (unpaced); got %d", p.StreamTokensPerSec)
+	}
+	if p.Weight < 0 {
+		return fmt.Errorf("field \"weight\" must be > 0, or 0 for the default of 1; got %d", p.Weight)
+	}
+	if !validPeriod(p.QuotaPeriod) {
+		return fmt.Errorf("quota_period must be one of %q, %q, %q or %q; got %q",
+			PeriodNone, PeriodDaily, PeriodWeekly, PeriodMonthly, p.QuotaPeriod)
+	}
+	for _, f := range []field{
+		{WindowTokensPerMinute, p.Windows.TokensPerMinute},
+		{WindowTokensPerHour, p.Windows.TokensPerHour},
+		{WindowTokensPerDay, p.Windows.TokensPerDay},
+		{WindowTokensPerMonth, p.Windows.TokensPerMonth},
+		{WindowRequestsPerDay, p.Windows.RequestsPerDay},
+	} {
+		if f.value < -1 {
+			return fmt.Errorf("field %q must be > 0, 0 or -1 (uncapped); got %d", f.name, f.value)
+		}
+	}
+	return nil
+}
+
+// normalized returns p with its uncapped windows, stream pace and compute
+// quota stored as -1, and its quota period and weight spelled out.
+func (p Plan) normalized() Plan {
+	w := &p.Windows
+	w.TokensPerMinute = override(w.TokensPerMinute, INF_TOKENS)
+	w.TokensPerHour = override(w.TokensPerHour, INF_TOKENS)
+	w.TokensPerDay = override(w.TokensPerDay, INF_TOKENS)
+	w.TokensPerMonth = override(w.TokensPerMonth, INF_TOKENS)
+	w.RequestsPerDay = override(w.RequestsPerDay, INF_REQUESTS)
+	p.StreamTokensPerSec = override(p.StreamTokensPerSec, INF_TOKENS)
+	p.MaxComputeSeconds = override(p.MaxComputeSeconds, INF_COMPUTE)
+	if p.QuotaPeriod == "" {
+		p.QuotaPeriod = PeriodNone
+	}
+	p.Weight = max(p.Weight, 1)
+	return p
+}
+
+// DefinePlan creates or replaces the named plan. Users already on the plan
+// pick up its new limits immediately, keeping their usage and overrides.
+// The returned PlanInfo counts the users that were updated.
+func (l *Limiter) DefinePlan(name string, p Plan) (PlanInfo, error) {
+	if name == "" {
+		return PlanInfo{}, fmt.Errorf("plan name is required")
+	}
+	if err := p.Validate(); err != nil {
+		return PlanInfo{}, err
+	}
+	p = p.normalized()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.plans[name] = p
+	n := 0
+	for _, u := range l.users {
+		if u.plan == name {
+			u.apply(p)
+			n++
+		}
+	}
+	return PlanInfo{Name: name, Plan: p, Users: n}, nil
+}
+
+// AssignPlan moves the user onto the named plan. Their usage is kept; their
+// overrides are kept too unless clearOverrides is set, in which case the
+// plan's limits apply unmodified.
+func (l *Limiter) AssignPlan(user, name string, clearOverrides bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, ok := l.plans[name]
+	if !ok {
+		return fmt.Errorf("plan %q does not exist", name)
+	}
+	u := l.userLocked(user)
+	u.plan = name
+	if clearOverrides {
+		u.overrides = overrides{}
+	}
+	u.apply(p)
+	return nil
+}
+
+// SetDefaultPlan sets the plan new users start on. Users the limiter already
+// knows keep their plan.
+func (l *Limiter) SetDefaultPlan(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.plans[name]; !ok {
+		return fmt.Errorf("plan %q does not exist", name)
+	}
+	l.defaultPlan = name
+	return nil
+}
+
+// DefaultPlan returns the name of the plan new users start on.
+func (l *Limiter) DefaultPlan() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.defaultPlan
+}
+
+// QueueWeight returns the user's fair-share weight in upstream queues: their
+// plan's weight.
+func (l *Limiter) QueueWeight(user string) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.userLocked(user)
+	return float64(l.plans[u.plan].Weight)
+}
+
+// PlanInfo describes one plan (used by admin UI).
+type PlanInfo struct {
+	Name  string
+	Plan  Plan
+	Users int // users the limiter knows on this plan
+}
+
+// Plans returns every plan, sorted by name.
+func (l *Limiter) Plans() []PlanInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	members := make(map[string]int, len(l.plans))
+	for _, u := range l.users {
+		members[u.plan]++
+	}
+	out := make([]PlanInfo, 0, len(l.plans))
+	for name, p := range l.plans {
+		out = append(out, PlanInfo{Name: name, Plan: p, Users: members[name]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,125 @@
+package limiter
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Reservation is a slice of a user's token quota set aside at admission for a
+// request in flight, so concurrent requests cannot jointly overshoot the quota.
+// The same tokens are also set aside from the model's quota, when the model
+// has one, and for members of an organization from the organization's quota.
+// A nil *Reservation is valid and reserves nothing.
+type Reservation struct {
+	reserved      *atomic.Int64 // counter the tokens were added to; nil = user quota unlimited
+	modelReserved *atomic.Int64 // the model's counter; nil = no model quota
+	orgReserved   *atomic.Int64 // the organization's counter; nil = none or unlimited
+	n             int64
+	released      atomic.Bool
+}
+
+// Release returns the reserved tokens to the quota. Record the request's
+// actual usage with ConsumeTokens before releasing, so the tokens are never
+// counted as free in between. Release is idempotent.
+func (r *Reservation) Release() {
+	if r == nil || !r.released.CompareAndSwap(false, true) {
+		return
+	}
+	for _, c := range []*atomic.Int64{r.reserved, r.modelReserved, r.orgReserved} {
+		if c != nil {
+			c.Add(-r.n)
+		}
+	}
+}
+
+// reserveOn adds n to reserved if it fits in quota after used and reserved
+// tokens, returning what was left otherwise.
+func reserveOn(quota int64, used, reserved *atomic.Int64, n int64) (left int64, ok bool) {
+	for {
+		r := reserved.Load()
+		if left := quota - used.Load() - r; n > left {
+			return max(left, 0), false
+		}
+		if reserved.CompareAndSwap(r, r+n) {
+			return 0, true
+		}
+	}
+}
+
+// Reserve sets aside n tokens of the quotas CheckQuota checks for model — the
+// user-wide quota and the model's own, if set — and of the user's
+// organization's quota. It returns an error (403) if n does not fit in what
+// is left of any of them after consumed and already reserved tokens. When no
+// quota is limited nothing is reserved and the Reservation is nil.
+func (l *Limiter) Reserve(user, model string, n int64) (*Reservation, error) {
+	u := l.getOrCreate(user)
+	res := &Reservation{n: n}
+	if quota := u.maxTokens.Load(); quota != INF_TOKENS {
+		if left, ok := reserveOn(quota, &u.usedTokens, &u.reservedTokens, n); !ok {
+			return nil, fmt.Errorf("token quota exceeded: request may use up to %d tokens, %d remaining", n, left)
+		}
+		res.reserved = &u.reservedTokens
+	}
+	if m := l.modelLimitFor(u, model); m != nil && m.maxTokens > 0 {
+		if left, ok := reserveOn(m.maxTokens, &m.usedTokens, &m.reservedTokens, n); !ok {
+			res.Release()
+			return nil, fmt.Errorf("token quota for %s exceeded: request may use up to %d tokens, %d remaining", model, n, left)
+		}
+		res.modelReserved = &m.reservedTokens
+	}
+	if o := l.orgFor(u); o != nil && o.maxTokens.Load() != INF_TOKENS {
+		if left, ok := reserveOn(o.maxTokens.Load(), &o.usedTokens, &o.reservedTokens, n); !ok {
+			res.Release()
+			return nil, fmt.Errorf("organization token quota exceeded: request may use up to %d tokens, %d remaining", n, left)
+		}
+		res.orgReserved = &o.reservedTokens
+	}
+	if res.reserved == nil && res.modelReserved == nil && res.orgReserved == nil {
+		return nil, nil
+	}
+	return res, nil
+}
+
+// Allowance returns how many tokens, prompt included, the request holding res
+// may use in all before it exhausts the user-wide quota, the model's quota,
+// their organization's quota, or one of the user's or organization's token
+// windows; -1 if nothing limits it. res's own tokens count as available to it. Streams check it as they go,
+// so that a request admitted just under quota cannot run far past it.
+func (l *Limiter) Allowance(user, model string, res *Reservation) int64 {
+	u := l.getOrCreate(user)
+	left := int64(-1)
+	tighten := func(n int64) {
+		if left == -1 || n < left {
+			left = max(n, 0)
+		}
+	}
+	// own returns the part of reserved that res holds.
+	own := func(reserved *atomic.Int64) int64 {
+		if res != nil && !res.released.Load() &&
+			(res.reserved == reserved || res.modelReserved == reserved || res.orgReserved == reserved) {
+			return res.n
+		}
+		return 0
+	}
+	now := time.Now()
+	if quota := u.maxTokens.Load(); quota != INF_TOKENS {
+		tighten(quota - u.usedTokens.Load() - u.reservedTokens.Load() + own(&u.reservedTokens))
+	}
+	if m := l.modelLimitFor(u, model); m != nil && m.maxTokens > 0 {
+		tighten(m.maxTokens - m.usedTokens.Load() - m.reservedTokens.Load() + own(&m.reservedTokens))
+	}
+	windows := u.windows.status(now)
+	if o := l.orgFor(u); o != nil {
+		if o.maxTokens.Load() != INF_TOKENS {
+			tighten(o.maxTokens.Load() - o.usedTokens.Load() - o.reservedTokens.Load() + own(&o.reservedTokens))
+		}
+		windows = append(windows, o.windows.status(now)...)
+	}
+	for _, w := range windows {
+		if w.name != WindowRequestsPerDay {
+			tighten(w.remaining)
+		}
+	}
+	return left
+}
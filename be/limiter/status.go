@@ -0,0 +1,107 @@
+package limiter
+
+import (
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitError is returned by CheckRPS (429) when the user's request bucket
+// is empty.
+type RateLimitError struct {
+	RetryAfter time.Duration // until the bucket holds a request again
+}
+
+func (e *RateLimitError) Error() string { return "rate limit exceeded" }
+
+// RateStatus is what is left of a user's request and token allowance, in the
+// terms of OpenAI's x-ratelimit-* headers. Where several limits apply, each
+// half reports the one with the least remaining. Limit and Remaining are -1
+// when nothing limits the user; a zero Reset means no scheduled reset.
+type RateStatus struct {
+	LimitRequests     int64
+	RemainingRequests int64
+	ResetRequests     time.Duration // until the binding request limit has refilled
+	LimitTokens       int64
+	RemainingTokens   int64         // after consumed and reserved tokens
+	ResetTokens       time.Duration // until the binding token limit frees capacity
+}
+
+// tighter replaces the status' request or token half with (limit, remaining,
+// reset) if that leaves less remaining than what it holds.
+func tighter(limit, remaining *int64, reset *time.Duration, l, r int64, d time.Duration) {
+	if *limit == -1 || r < *remaining {
+		*limit, *remaining, *reset = l, r, d
+	}
+}
+
+// Status reports the user's remaining allowance for model: the RPS bucket and
+// requests_per_day for requests, the token quota and token windows for tokens.
+// Limits scoped to the model count alongside the user-wide ones, as in
+// admission, and so do the limits of the user's organization.
+func (l *Limiter) Status(user, model string) RateStatus {
+	u := l.getOrCreate(user)
+	now := time.Now()
+	st := RateStatus{LimitRequests: -1, RemainingRequests: -1, LimitTokens: -1, RemainingTokens: -1}
+
+	var reset time.Duration // model quotas roll over with the user's period
+	l.mu.Lock()
+	if !u.periodEnd.IsZero() {
+		reset = u.periodEnd.Sub(now)
+	}
+	l.mu.Unlock()
+	st.bucket(u.limiter.Load(), now)
+	if quota := u.maxTokens.Load(); quota != INF_TOKENS {
+		tighter(&st.LimitTokens, &st.RemainingTokens, &st.ResetTokens,
+			quota, max(quota-u.usedTokens.Load()-u.reservedTokens.Load(), 0), reset)
+	}
+	if m := l.modelLimitFor(u, model); m != nil {
+		if m.limiter != nil {
+			st.bucket(m.limiter, now)
+		}
+		if m.maxTokens > 0 {
+			tighter(&st.LimitTokens, &st.RemainingTokens, &st.ResetTokens,
+				m.maxTokens, max(m.maxTokens-m.usedTokens.Load()-m.reservedTokens.Load(), 0), reset)
+		}
+	}
+	windows := u.windows.status(now)
+	if o := l.orgFor(u); o != nil {
+		st.bucket(o.limiter.Load(), now)
+		if q := o.maxTokens.Load(); q != INF_TOKENS {
+			var orgReset time.Duration
+			l.mu.Lock()
+			if !o.periodEnd.IsZero() {
+				orgReset = o.periodEnd.Sub(now)
+			}
+			l.mu.Unlock()
+			tighter(&st.LimitTokens, &st.RemainingTokens, &st.ResetTokens,
+				q, max(q-o.usedTokens.Load()-o.reservedTokens.Load(), 0), orgReset)
+		}
+		windows = append(windows, o.windows.status(now)...)
+	}
+	for _, w := range windows {
+		if w.name == WindowRequestsPerDay {
+			tighter(&st.LimitRequests, &st.RemainingRequests, &st.ResetRequests, w.limit, w.remaining, w.resetAt.Sub(now))
+		} else {
+			tighter(&st.LimitTokens, &st.RemainingTokens, &st.ResetTokens, w.limit, w.remaining, w.resetAt.Sub(now))
+		}
+	}
+	return st
+}
+
+// bucket folds an RPS bucket into the request half of the status.
+func (st *RateStatus) bucket(b *rate.Limiter, now time.Time) {
+	r := b.Limit()
+	if r == rate.Inf {
+		return
+	}
+	burst := float64(b.Burst())
+	tokens := math.Max(b.TokensAt(now), 0)
+	var refill time.Duration
+	if r > 0 {
+		refill = time.Duration((burst - tokens) / float64(r) * float64(time.Second))
+	}
+	tighter(&st.LimitRequests, &st.RemainingRequests, &st.ResetRequests,
+		int64(burst), int64(tokens), refill)
+}
@@ -0,0 +1,76 @@
+package limiter
+
+import (
+	"fmt"
+	"time"
+)
+
+// Suspension blocks a user's requests without touching their limits or usage,
+// so unsuspending leaves them exactly where they were.
+type Suspension struct {
+	Reason string
+	By     string    // admin who suspended the user
+	At     time.Time // when the suspension started
+	Until  time.Time // zero = until lifted with Unsuspend
+}
+
+// active reports whether the suspension is still in force at now.
+func (s *Suspension) active(now time.Time) bool {
+	return s != nil && (s.Until.IsZero() || now.Before(s.Until))
+}
+
+// SuspendedError is returned by CheckSuspended (403) for a suspended user.
+type SuspendedError struct {
+	Suspension
+}
+
+func (e *SuspendedError) Error() string {
+	msg := "account suspended"
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	if !e.Until.IsZero() {
+		msg += fmt.Sprintf(" (until %s)", e.Until.UTC().Format(time.RFC3339))
+	}
+	return msg
+}
+
+// Suspend blocks every request from the user until Unsuspend is called or,
+// if s.Until is set, until then. Suspending an already suspended user
+// replaces the suspension.
+func (l *Limiter) Suspend(user string, s Suspension) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.userLocked(user)
+	u.suspension = &s
+}
+
+// Unsuspend lifts the user's suspension. It reports false if the user was not
+// suspended (or the suspension had already expired).
+func (l *Limiter) Unsuspend(user string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u, ok := l.users[user]
+	if !ok {
+		return false
+	}
+	active := u.suspension.active(time.Now())
+	u.suspension = nil
+	return active
+}
+
+// CheckSuspended returns a *SuspendedError (403) if the user is suspended.
+// An expired suspension is cleared on the way.
+func (l *Limiter) CheckSuspended(user string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u, ok := l.users[user]
+	if !ok || u.suspension == nil {
+		return nil
+	}
+	if !u.suspension.active(time.Now()) {
+		u.suspension = nil
+		return nil
+	}
+	return &SuspendedError{Suspension: *u.suspension}
+}
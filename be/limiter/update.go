@@ -0,0 +1,113 @@
+package limiter
+
+import "fmt"
+
+// LimitUpdate is a partial change to a user's overrides on top of their plan.
+// Nil fields are left unchanged. Limits take a value > 0 or INF_* (-1) to
+// remove the limit; RPS may also be 0, which blocks every request.
+type LimitUpdate struct {
+	RPS             *int
+	MaxTokens       *int64
+	MaxTokensPerReq *int64
+	MaxConcurrent   *int64
+	TokensPerMinute *int64
+	TokensPerHour   *int64
+	TokensPerDay    *int64
+	TokensPerMonth  *int64
+	RequestsPerDay  *int64
+	StreamPace      *int64  // tokens per second; see Plan.StreamTokensPerSec
+	MaxCompute      *int64  // seconds per quota period; see Plan.MaxComputeSeconds
+	QuotaPeriod     *string // as in SetQuotaPeriod
+	BillingDay      *int    // as in SetQuotaPeriod
+	ResetUsage      bool    // zero the tokens consumed against the quota, user-wide and per model, and compute time
+}
+
+// Validate checks every field that is set.
+func (up LimitUpdate) Validate() error {
+	if up.RPS != nil && *up.RPS < -1 {
+		return fmt.Errorf("field \"rps\" must be >= 0 or -1 (unlimited); got %d", *up.RPS)
+	}
+	for _, f := range []struct {
+		name  string
+		value *int64
+	}{
+		{"max_tokens", up.MaxTokens},
+		{"max_tokens_per_request", up.MaxTokensPerReq},
+		{"max_concurrent_requests", up.MaxConcurrent},
+		{WindowTokensPerMinute, up.TokensPerMinute},
+		{WindowTokensPerHour, up.TokensPerHour},
+		{WindowTokensPerDay, up.TokensPerDay},
+		{WindowTokensPerMonth, up.TokensPerMonth},
+		{WindowRequestsPerDay, up.RequestsPerDay},
+		{"stream_tokens_per_second", up.StreamPace},
+		{"max_compute_seconds", up.MaxCompute},
+	} {
+		if f.value != nil && (*f.value == 0 || *f.value < -1) {
+			return fmt.Errorf("field %q must be > 0 or -1 (unlimited); got %d", f.name, *f.value)
+		}
+	}
+	return nil
+}
+
+// UpdateLimits applies a partial update to the user's limits, all or nothing.
+// Unlike SetLimits it leaves RPS and usage alone unless asked to. It returns
+// the user's resulting limits.
+func (l *Limiter) UpdateLimits(user string, up LimitUpdate) (LimitInfo, error) {
+	if err := up.Validate(); err != nil {
+		return LimitInfo{}, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.userLocked(user)
+	period, billingDay := "", 0
+	if up.QuotaPeriod != nil {
+		period = *up.QuotaPeriod
+	}
+	if up.BillingDay != nil {
+		billingDay = *up.BillingDay
+	}
+	eff, day, err := l.resolvePeriod(u, period, billingDay)
+	if err != nil {
+		return LimitInfo{}, err
+	}
+
+	o := &u.overrides
+	if up.RPS != nil {
+		o.rps, o.rpsSet = *up.RPS, true
+	}
+	set := func(dst *int64, v *int64) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	set(&o.maxTokens, up.MaxTokens)
+	set(&o.maxTokensPerReq, up.MaxTokensPerReq)
+	set(&o.maxConcurrent, up.MaxConcurrent)
+	set(&o.windows.TokensPerMinute, up.TokensPerMinute)
+	set(&o.windows.TokensPerHour, up.TokensPerHour)
+	set(&o.windows.TokensPerDay, up.TokensPerDay)
+	set(&o.windows.TokensPerMonth, up.TokensPerMonth)
+	set(&o.windows.RequestsPerDay, up.RequestsPerDay)
+	set(&o.streamPace, up.StreamPace)
+	set(&o.maxCompute, up.MaxCompute)
+	if period != "" {
+		o.quotaPeriod = period
+	}
+	u.apply(l.plans[u.plan])
+	u.setPeriod(eff, day)
+	if up.ResetUsage {
+		u.usedTokens.Store(0)
+		u.usedCompute.Store(0)
+		for _, m := range u.models {
+			m.usedTokens.Store(0)
+		}
+	}
+	return u.info(), nil
+}
+
+// Limits returns the user's limits and usage.
+func (l *Limiter) Limits(user string) LimitInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.userLocked(user).info()
+}
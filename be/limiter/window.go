@@ -0,0 +1,250 @@
+package limiter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Names of the sliding windows, as used in the API and in WindowError.
+const (
+	WindowTokensPerMinute = "tokens_per_minute"
+	WindowTokensPerHour   = "tokens_per_hour"
+	WindowTokensPerDay    = "tokens_per_day"
+	WindowTokensPerMonth  = "tokens_per_month"
+	WindowRequestsPerDay  = "requests_per_day"
+)
+
+// INF_REQUESTS removes a request-count window limit.
+const INF_REQUESTS = -1
+
+// windowBuckets is the resolution of every sliding window: usage falls out of
+// the window one bucket (span/windowBuckets) at a time.
+const windowBuckets = 60
+
+// monthSpan is the span of the monthly window: a rolling 30 days.
+const monthSpan = 30 * 24 * time.Hour
+
+// WindowLimits are a user's sliding-window caps. In SetWindows, 0 leaves a
+// cap unchanged and INF_TOKENS / INF_REQUESTS (-1) removes it.
+type WindowLimits struct {
+	TokensPerMinute int64
+	TokensPerHour   int64
+	TokensPerDay    int64
+	TokensPerMonth  int64
+	RequestsPerDay  int64
+}
+
+// WindowError reports the sliding window a request was rejected by.
+type WindowError struct {
+	Window  string    // e.g. WindowTokensPerHour
+	Limit   int64     // configured cap
+	ResetAt time.Time // when enough usage has aged out to admit a request again
+	Org     string    // set when the window is the user's organization's
+}
+
+func (e *WindowError) Error() string {
+	msg := fmt.Sprintf("%s limit of %d exceeded; resets at %s",
+		e.Window, e.Limit, e.ResetAt.UTC().Format(time.RFC3339))
+	if e.Org != "" {
+		msg = fmt.Sprintf("organization %q %s", e.Org, msg)
+	}
+	return msg
+}
+
+// window is a sliding-window counter over span, kept as a ring of
+// windowBuckets fixed buckets.
+type window struct {
+	name   string
+	span   time.Duration
+	limit  int64 // -1 = unlimited
+	counts [windowBuckets]int64
+	epochs [windowBuckets]int64 // bucket number each slot currently holds
+}
+
+func newWindow(name string, span time.Duration) *window {
+	return &window{name: name, span: span, limit: -1}
+}
+
+func (w *window) width() time.Duration { return w.span / windowBuckets }
+
+// add records n units at now.
+func (w *window) add(now time.Time, n int64) {
+	epoch := now.UnixNano() / int64(w.width())
+	i := epoch % windowBuckets
+	if w.epochs[i] != epoch {
+		w.epochs[i], w.counts[i] = epoch, 0
+	}
+	w.counts[i] += n
+}
+
+// used sums the buckets still inside the window at now.
+func (w *window) used(now time.Time) int64 {
+	oldest := now.UnixNano()/int64(w.width()) - windowBuckets + 1
+	var sum int64
+	for i := range w.counts {
+		if w.epochs[i] >= oldest {
+			sum += w.counts[i]
+		}
+	}
+	return sum
+}
+
+// check returns a WindowError if usage at now has reached the limit.
+func (w *window) check(now time.Time) *WindowError {
+	if w.limit < 0 {
+		return nil
+	}
+	used := w.used(now)
+	if used < w.limit {
+		return nil
+	}
+	return &WindowError{Window: w.name, Limit: w.limit, ResetAt: w.resetAt(now, used)}
+}
+
+// resetAt is the earliest time usage drops below the limit again, found by
+// letting the oldest buckets expire one by one.
+func (w *window) resetAt(now time.Time, used int64) time.Time {
+	width := int64(w.width())
+	current := now.UnixNano() / width
+	for epoch := current - windowBuckets + 1; epoch <= current; epoch++ {
+		i := epoch % windowBuckets
+		if w.epochs[i] == epoch {
+			used -= w.counts[i]
+		}
+		if used < w.limit {
+			return time.Unix(0, (epoch+windowBuckets)*width)
+		}
+	}
+	return time.Unix(0, (current+windowBuckets)*width)
+}
+
+// setLimit applies a SetWindows value: 0 = unchanged, negative = unlimited.
+func (w *window) setLimit(v int64) {
+	if v != 0 {
+		w.limit = v
+	}
+}
+
+// windowSet holds one user's sliding windows.
+type windowSet struct {
+	mu                                   sync.Mutex
+	minute, hour, day, month, dailyCalls *window
+}
+
+func newWindowSet() *windowSet {
+	return &windowSet{
+		minute:     newWindow(WindowTokensPerMinute, time.Minute),
+		hour:       newWindow(WindowTokensPerHour, time.Hour),
+		day:        newWindow(WindowTokensPerDay, 24*time.Hour),
+		month:      newWindow(WindowTokensPerMonth, monthSpan),
+		dailyCalls: newWindow(WindowRequestsPerDay, 24*time.Hour),
+	}
+}
+
+func (ws *windowSet) tokens() []*window {
+	return []*window{ws.minute, ws.hour, ws.day, ws.month}
+}
+
+// admit checks every window and, if none is exhausted, counts the request.
+func (ws *windowSet) admit(now time.Time) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if err := ws.checkLocked(now); err != nil {
+		return err
+	}
+	ws.dailyCalls.add(now, 1)
+	return nil
+}
+
+// check is admit without counting the request.
+func (ws *windowSet) check(now time.Time) *WindowError {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.checkLocked(now)
+}
+
+// count records one request against requests_per_day.
+func (ws *windowSet) count(now time.Time) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.dailyCalls.add(now, 1)
+}
+
+func (ws *windowSet) checkLocked(now time.Time) *WindowError {
+	for _, w := range append(ws.tokens(), ws.dailyCalls) {
+		if err := w.check(now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ws *windowSet) consume(now time.Time, n int64) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for _, w := range ws.tokens() {
+		w.add(now, n)
+	}
+}
+
+func (ws *windowSet) set(l WindowLimits) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.minute.setLimit(l.TokensPerMinute)
+	ws.hour.setLimit(l.TokensPerHour)
+	ws.day.setLimit(l.TokensPerDay)
+	ws.month.setLimit(l.TokensPerMonth)
+	ws.dailyCalls.setLimit(l.RequestsPerDay)
+}
+
+func (ws *windowSet) limits() WindowLimits {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return WindowLimits{
+		TokensPerMinute: ws.minute.limit,
+		TokensPerHour:   ws.hour.limit,
+		TokensPerDay:    ws.day.limit,
+		TokensPerMonth:  ws.month.limit,
+		RequestsPerDay:  ws.dailyCalls.limit,
+	}
+}
+
+// usage returns the amount consumed in each window at now, keyed by window name.
+func (ws *windowSet) usage(now time.Time) map[string]int64 {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	out := make(map[string]int64, 5)
+	for _, w := range append(ws.tokens(), ws.dailyCalls) {
+		out[w.name] = w.used(now)
+	}
+	return out
+}
+
+// windowStatus is the state of one capped window.
+type windowStatus struct {
+	name      string
+	limit     int64
+	remaining int64
+	resetAt   time.Time // when usage next ages out
+}
+
+// status reports every capped window at now.
+func (ws *windowSet) status(now time.Time) []windowStatus {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	var out []windowStatus
+	for _, w := range append(ws.tokens(), ws.dailyCalls) {
+		if w.limit < 0 {
+			continue
+		}
+		used := w.used(now)
+		out = append(out, windowStatus{
+			name:      w.name,
+			limit:     w.limit,
+			remaining: max(w.limit-used, 0),
+			resetAt:   w.resetAt(now, used),
+		})
+	}
+	return out
+}
@@ -1,15 +1,15 @@
 package main
 
 import (
-	"encoding/json"
 	"lb/auth"
 	"lb/handler"
 	"lb/limiter"
 	"lb/store"
 	"lb/ui"
+	"lb/upstream"
 	"log"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -17,22 +17,22 @@ import (
 )
 
 func main() {
-	var config struct {
-		OllamaURL string `json:"ollama_url"`
-		Port      string `json:"port"`
-	}
-	// Fallback defaults
-	config.OllamaURL = "http://localhost:11434"
-	config.Port = ":8000"
-
-	if b, err := os.ReadFile("config.json"); err == nil {
-		json.Unmarshal(b, &config)
-	} else {
-		log.Println("warn: config.json not found, using defaults")
+	config := loadConfig("config.json")
+
+	pool, err := upstream.NewPool(config.OllamaURLs, config.Routes)
+	if err != nil {
+		log.Fatal(err)
 	}
+	pool.Retry = config.retryPolicy()
+	pool.Timeouts = config.timeoutPolicy()
+	pool.Queue = config.queuePolicy()
+	pool.Start(time.Duration(config.HealthCheckInterval))
+	defer pool.Stop()
 
 	s := store.New()
 	lim := limiter.New()
+	s.AttributeOrgs(lim.Org)
+	config.applyPlans(lim)
 
 	e := echo.New()
 	e.HideBanner = true
@@ -44,7 +44,7 @@ func main() {
 	}))
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"http://localhost:3000", "http://127.0.0.1:3000", "*"},
-		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
 		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
 	}))
 
@@ -53,11 +53,21 @@ func main() {
 		return c.NoContent(http.StatusOK)
 	})
 
-	// Inference
-	e.POST("/v1/chat/completions", handler.Completions(config.OllamaURL, s, lim), auth.AuthMiddleware)
+	// Inference — chat and legacy text completions share one proxy and accounting path
+	completions := handler.Completions(pool, s, lim)
+	e.POST("/v1/chat/completions", completions, auth.AuthMiddleware)
+	e.POST("/v1/completions", completions, auth.AuthMiddleware)
+	e.POST("/v1/embeddings", handler.Embeddings(pool, s, lim), auth.AuthMiddleware)
+	e.GET("/v1/models", handler.Models(pool, lim), auth.AuthMiddleware)
+
+	// Native Ollama API passthrough — same limits, NDJSON accounting
+	native := handler.Native(pool, s, lim)
+	e.POST("/api/chat", native, auth.AuthMiddleware)
+	e.POST("/api/generate", native, auth.AuthMiddleware)
+	e.POST("/api/embed", native, auth.AuthMiddleware)
 
 	// User API
-	e.GET("/v1/usage", handler.Usage(s), auth.AuthMiddleware)
+	e.GET("/v1/usage", handler.Usage(s, lim), auth.AuthMiddleware)
 
 	// Auth
 	e.POST("/auth/login", handler.Login())
@@ -65,9 +75,20 @@ func main() {
 	// Admin APIs — auth enforced at group level
 	admin := e.Group("/admin", auth.AdminAuthMiddleware)
 	admin.POST("/limits", handler.SetLimits(lim))
+	admin.PATCH("/limits/:user", handler.PatchLimits(lim))
 	admin.POST("/suspend", handler.SuspendUser(lim))
-	admin.GET("/usage", handler.AllUsage(s))
+	admin.POST("/unsuspend", handler.UnsuspendUser(lim))
+	admin.POST("/models", handler.SetAllowedModels(lim))
+	admin.POST("/priority", handler.SetPriority(lim))
+	admin.GET("/plans", handler.Plans(lim))
+	admin.POST("/plans", handler.SetPlan(lim))
+	admin.POST("/plan", handler.AssignPlan(lim))
+	admin.GET("/orgs", handler.Orgs(lim, s))
+	admin.POST("/orgs", handler.SetOrg(lim, s))
+	admin.POST("/org", handler.SetUserOrg(lim))
+	admin.GET("/usage", handler.AllUsage(s, lim))
 	admin.GET("/limits", handler.AllLimits(lim))
+	admin.GET("/upstreams", handler.Upstreams(pool))
 	admin.GET("/ui", ui.Dashboard(s, lim))
 
 	// Catch-all: explicit 404
@@ -75,7 +96,7 @@ func main() {
 		return c.JSON(http.StatusNotFound, echo.Map{"error": "not found"})
 	})
 
-	log.Printf("Proxy listening on %s  →  Ollama at %s\n", config.Port, config.OllamaURL)
+	log.Printf("Proxy listening on %s  →  Ollama at %s\n", config.Port, strings.Join(config.OllamaURLs, ", "))
 	if err := e.Start(config.Port); err != nil {
 		log.Fatal(err)
 	}
@@ -139,8 +139,24 @@ type SetLimitsRequest struct {
 	Rps                 int32                  `protobuf:"varint,2,opt,name=rps,proto3" json:"rps,omitempty"`
 	MaxTokens           int64                  `protobuf:"varint,3,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
 	MaxTokensPerRequest int64                  `protobuf:"varint,4,opt,name=max_tokens_per_request,json=maxTokensPerRequest,proto3" json:"max_tokens_per_request,omitempty"`
-	unknownFields       protoimpl.UnknownFields
-	sizeCache           protoimpl.SizeCache
+	// Sliding windows: 0 = leave unchanged, -1 = unlimited
+	TokensPerMinute       int64 `protobuf:"varint,5,opt,name=tokens_per_minute,json=tokensPerMinute,proto3" json:"tokens_per_minute,omitempty"`
+	TokensPerHour         int64 `protobuf:"varint,6,opt,name=tokens_per_hour,json=tokensPerHour,proto3" json:"tokens_per_hour,omitempty"`
+	TokensPerDay          int64 `protobuf:"varint,7,opt,name=tokens_per_day,json=tokensPerDay,proto3" json:"tokens_per_day,omitempty"`
+	TokensPerMonth        int64 `protobuf:"varint,8,opt,name=tokens_per_month,json=tokensPerMonth,proto3" json:"tokens_per_month,omitempty"` // rolling 30 days
+	RequestsPerDay        int64 `protobuf:"varint,9,opt,name=requests_per_day,json=requestsPerDay,proto3" json:"requests_per_day,omitempty"`
+	MaxConcurrentRequests int64 `protobuf:"varint,10,opt,name=max_concurrent_requests,json=maxConcurrentRequests,proto3" json:"max_concurrent_requests,omitempty"` // 0 = leave unchanged, -1 = unlimited
+	// Scopes rps, max_tokens and max_tokens_per_request to one model, on top
+	// of the user-wide limits. For a model, 0 or -1 leaves the limit to the
+	// user-wide one alone; all three 0 removes the model's limits.
+	Model string `protobuf:"bytes,11,opt,name=model,proto3" json:"model,omitempty"`
+	// Token quota reset cycle: "" = leave unchanged, "none", "daily", "weekly"
+	// or "monthly"; anchored to billing_day (weekday 1-7 from Monday for weekly,
+	// day of month 1-31 for monthly; 0 = leave unchanged)
+	QuotaPeriod   string `protobuf:"bytes,12,opt,name=quota_period,json=quotaPeriod,proto3" json:"quota_period,omitempty"`
+	BillingDay    int32  `protobuf:"varint,13,opt,name=billing_day,json=billingDay,proto3" json:"billing_day,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SetLimitsRequest) Reset() {
@@ -201,14 +217,87 @@ func (x *SetLimitsRequest) GetMaxTokensPerRequest() int64 {
 	return 0
 }
 
+func (x *SetLimitsRequest) GetTokensPerMinute() int64 {
+	if x != nil {
+		return x.TokensPerMinute
+	}
+	return 0
+}
+
+func (x *SetLimitsRequest) GetTokensPerHour() int64 {
+	if x != nil {
+		return x.TokensPerHour
+	}
+	return 0
+}
+
+func (x *SetLimitsRequest) GetTokensPerDay() int64 {
+	if x != nil {
+		return x.TokensPerDay
+	}
+	return 0
+}
+
+func (x *SetLimitsRequest) GetTokensPerMonth() int64 {
+	if x != nil {
+		return x.TokensPerMonth
+	}
+	return 0
+}
+
+func (x *SetLimitsRequest) GetRequestsPerDay() int64 {
+	if x != nil {
+		return x.RequestsPerDay
+	}
+	return 0
+}
+
+func (x *SetLimitsRequest) GetMaxConcurrentRequests() int64 {
+	if x != nil {
+		return x.MaxConcurrentRequests
+	}
+	return 0
+}
+
+func (x *SetLimitsRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *SetLimitsRequest) GetQuotaPeriod() string {
+	if x != nil {
+		return x.QuotaPeriod
+	}
+	return ""
+}
+
+func (x *SetLimitsRequest) GetBillingDay() int32 {
+	if x != nil {
+		return x.BillingDay
+	}
+	return 0
+}
+
 type SetLimitsResponse struct {
 	state               protoimpl.MessageState `protogen:"open.v1"`
 	UserId              string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	Rps                 int32                  `protobuf:"varint,2,opt,name=rps,proto3" json:"rps,omitempty"`
 	MaxTokens           int64                  `protobuf:"varint,3,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
 	MaxTokensPerRequest int64                  `protobuf:"varint,4,opt,name=max_tokens_per_request,json=maxTokensPerRequest,proto3" json:"max_tokens_per_request,omitempty"`
-	unknownFields       protoimpl.UnknownFields
-	sizeCache           protoimpl.SizeCache
+	// Sliding-window caps in effect after the update; -1 = unlimited
+	TokensPerMinute       int64  `protobuf:"varint,5,opt,name=tokens_per_minute,json=tokensPerMinute,proto3" json:"tokens_per_minute,omitempty"`
+	TokensPerHour         int64  `protobuf:"varint,6,opt,name=tokens_per_hour,json=tokensPerHour,proto3" json:"tokens_per_hour,omitempty"`
+	TokensPerDay          int64  `protobuf:"varint,7,opt,name=tokens_per_day,json=tokensPerDay,proto3" json:"tokens_per_day,omitempty"`
+	TokensPerMonth        int64  `protobuf:"varint,8,opt,name=tokens_per_month,json=tokensPerMonth,proto3" json:"tokens_per_month,omitempty"`
+	RequestsPerDay        int64  `protobuf:"varint,9,opt,name=requests_per_day,json=requestsPerDay,proto3" json:"requests_per_day,omitempty"`
+	MaxConcurrentRequests int64  `protobuf:"varint,10,opt,name=max_concurrent_requests,json=maxConcurrentRequests,proto3" json:"max_concurrent_requests,omitempty"` // -1 = unlimited
+	Model                 string `protobuf:"bytes,11,opt,name=model,proto3" json:"model,omitempty"`                                                                 // set when the limits were scoped to a model
+	QuotaPeriod           string `protobuf:"bytes,12,opt,name=quota_period,json=quotaPeriod,proto3" json:"quota_period,omitempty"`
+	BillingDay            int32  `protobuf:"varint,13,opt,name=billing_day,json=billingDay,proto3" json:"billing_day,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
 }
 
 func (x *SetLimitsResponse) Reset() {
@@ -233,64 +322,1858 @@ func (x *SetLimitsResponse) ProtoReflect() protoreflect.Message {
 		}
 		return ms
 	}
-	return mi.MessageOf(x)
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLimitsResponse.ProtoReflect.Descriptor instead.
+func (*SetLimitsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SetLimitsResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetLimitsResponse) GetRps() int32 {
+	if x != nil {
+		return x.Rps
+	}
+	return 0
+}
+
+func (x *SetLimitsResponse) GetMaxTokens() int64 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *SetLimitsResponse) GetMaxTokensPerRequest() int64 {
+	if x != nil {
+		return x.MaxTokensPerRequest
+	}
+	return 0
+}
+
+func (x *SetLimitsResponse) GetTokensPerMinute() int64 {
+	if x != nil {
+		return x.TokensPerMinute
+	}
+	return 0
+}
+
+func (x *SetLimitsResponse) GetTokensPerHour() int64 {
+	if x != nil {
+		return x.TokensPerHour
+	}
+	return 0
+}
+
+func (x *SetLimitsResponse) GetTokensPerDay() int64 {
+	if x != nil {
+		return x.TokensPerDay
+	}
+	return 0
+}
+
+func (x *SetLimitsResponse) GetTokensPerMonth() int64 {
+	if x != nil {
+		return x.TokensPerMonth
+	}
+	return 0
+}
+
+func (x *SetLimitsResponse) GetRequestsPerDay() int64 {
+	if x != nil {
+		return x.RequestsPerDay
+	}
+	return 0
+}
+
+func (x *SetLimitsResponse) GetMaxConcurrentRequests() int64 {
+	if x != nil {
+		return x.MaxConcurrentRequests
+	}
+	return 0
+}
+
+func (x *SetLimitsResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *SetLimitsResponse) GetQuotaPeriod() string {
+	if x != nil {
+		return x.QuotaPeriod
+	}
+	return ""
+}
+
+func (x *SetLimitsResponse) GetBillingDay() int32 {
+	if x != nil {
+		return x.BillingDay
+	}
+	return 0
+}
+
+// PATCH /admin/limits/{user_id} changes only the fields present. Limits take
+// a value > 0 or -1 (unlimited), rps also 0 (blocks every request); fields
+// named in unlimited are removed instead. The response is the user's full
+// resulting LimitInfo.
+type PatchLimitsRequest struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Rps                   *int32                 `protobuf:"varint,1,opt,name=rps,proto3,oneof" json:"rps,omitempty"`
+	MaxTokens             *int64                 `protobuf:"varint,2,opt,name=max_tokens,json=maxTokens,proto3,oneof" json:"max_tokens,omitempty"`
+	MaxTokensPerRequest   *int64                 `protobuf:"varint,3,opt,name=max_tokens_per_request,json=maxTokensPerRequest,proto3,oneof" json:"max_tokens_per_request,omitempty"`
+	MaxConcurrentRequests *int64                 `protobuf:"varint,4,opt,name=max_concurrent_requests,json=maxConcurrentRequests,proto3,oneof" json:"max_concurrent_requests,omitempty"`
+	TokensPerMinute       *int64                 `protobuf:"varint,5,opt,name=tokens_per_minute,json=tokensPerMinute,proto3,oneof" json:"tokens_per_minute,omitempty"`
+	TokensPerHour         *int64                 `protobuf:"varint,6,opt,name=tokens_per_hour,json=tokensPerHour,proto3,oneof" json:"tokens_per_hour,omitempty"`
+	TokensPerDay          *int64                 `protobuf:"varint,7,opt,name=tokens_per_day,json=tokensPerDay,proto3,oneof" json:"tokens_per_day,omitempty"`
+	TokensPerMonth        *int64                 `protobuf:"varint,8,opt,name=tokens_per_month,json=tokensPerMonth,proto3,oneof" json:"tokens_per_month,omitempty"`
+	RequestsPerDay        *int64                 `protobuf:"varint,9,opt,name=requests_per_day,json=requestsPerDay,proto3,oneof" json:"requests_per_day,omitempty"`
+	QuotaPeriod           *string                `protobuf:"bytes,10,opt,name=quota_period,json=quotaPeriod,proto3,oneof" json:"quota_period,omitempty"`
+	BillingDay            *int32                 `protobuf:"varint,11,opt,name=billing_day,json=billingDay,proto3,oneof" json:"billing_day,omitempty"`
+	Unlimited             []string               `protobuf:"bytes,12,rep,name=unlimited,proto3" json:"unlimited,omitempty"`                      // field names to set to unlimited, e.g. ["max_tokens", "tokens_per_day"]
+	ResetUsage            bool                   `protobuf:"varint,13,opt,name=reset_usage,json=resetUsage,proto3" json:"reset_usage,omitempty"` // also zero the tokens consumed against the quota
+	StreamTokensPerSecond *int64                 `protobuf:"varint,14,opt,name=stream_tokens_per_second,json=streamTokensPerSecond,proto3,oneof" json:"stream_tokens_per_second,omitempty"`
+	MaxComputeSeconds     *int64                 `protobuf:"varint,15,opt,name=max_compute_seconds,json=maxComputeSeconds,proto3,oneof" json:"max_compute_seconds,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *PatchLimitsRequest) Reset() {
+	*x = PatchLimitsRequest{}
+	mi := &file_api_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchLimitsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchLimitsRequest) ProtoMessage() {}
+
+func (x *PatchLimitsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchLimitsRequest.ProtoReflect.Descriptor instead.
+func (*PatchLimitsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PatchLimitsRequest) GetRps() int32 {
+	if x != nil && x.Rps != nil {
+		return *x.Rps
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetMaxTokens() int64 {
+	if x != nil && x.MaxTokens != nil {
+		return *x.MaxTokens
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetMaxTokensPerRequest() int64 {
+	if x != nil && x.MaxTokensPerRequest != nil {
+		return *x.MaxTokensPerRequest
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetMaxConcurrentRequests() int64 {
+	if x != nil && x.MaxConcurrentRequests != nil {
+		return *x.MaxConcurrentRequests
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetTokensPerMinute() int64 {
+	if x != nil && x.TokensPerMinute != nil {
+		return *x.TokensPerMinute
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetTokensPerHour() int64 {
+	if x != nil && x.TokensPerHour != nil {
+		return *x.TokensPerHour
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetTokensPerDay() int64 {
+	if x != nil && x.TokensPerDay != nil {
+		return *x.TokensPerDay
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetTokensPerMonth() int64 {
+	if x != nil && x.TokensPerMonth != nil {
+		return *x.TokensPerMonth
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetRequestsPerDay() int64 {
+	if x != nil && x.RequestsPerDay != nil {
+		return *x.RequestsPerDay
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetQuotaPeriod() string {
+	if x != nil && x.QuotaPeriod != nil {
+		return *x.QuotaPeriod
+	}
+	return ""
+}
+
+func (x *PatchLimitsRequest) GetBillingDay() int32 {
+	if x != nil && x.BillingDay != nil {
+		return *x.BillingDay
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetUnlimited() []string {
+	if x != nil {
+		return x.Unlimited
+	}
+	return nil
+}
+
+func (x *PatchLimitsRequest) GetResetUsage() bool {
+	if x != nil {
+		return x.ResetUsage
+	}
+	return false
+}
+
+func (x *PatchLimitsRequest) GetStreamTokensPerSecond() int64 {
+	if x != nil && x.StreamTokensPerSecond != nil {
+		return *x.StreamTokensPerSecond
+	}
+	return 0
+}
+
+func (x *PatchLimitsRequest) GetMaxComputeSeconds() int64 {
+	if x != nil && x.MaxComputeSeconds != nil {
+		return *x.MaxComputeSeconds
+	}
+	return 0
+}
+
+type SuspendUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`     // shown to the user in the 403
+	Duration      string                 `protobuf:"bytes,3,opt,name=duration,proto3" json:"duration,omitempty"` // Go duration, e.g. "24h"; empty = until unsuspended
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SuspendUserRequest) Reset() {
+	*x = SuspendUserRequest{}
+	mi := &file_api_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuspendUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuspendUserRequest) ProtoMessage() {}
+
+func (x *SuspendUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuspendUserRequest.ProtoReflect.Descriptor instead.
+func (*SuspendUserRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SuspendUserRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SuspendUserRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *SuspendUserRequest) GetDuration() string {
+	if x != nil {
+		return x.Duration
+	}
+	return ""
+}
+
+// An account suspension; times are RFC 3339
+type SuspensionInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	SuspendedBy   string                 `protobuf:"bytes,2,opt,name=suspended_by,json=suspendedBy,proto3" json:"suspended_by,omitempty"`
+	SuspendedAt   string                 `protobuf:"bytes,3,opt,name=suspended_at,json=suspendedAt,proto3" json:"suspended_at,omitempty"`
+	Until         string                 `protobuf:"bytes,4,opt,name=until,proto3" json:"until,omitempty"` // empty = until unsuspended
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SuspensionInfo) Reset() {
+	*x = SuspensionInfo{}
+	mi := &file_api_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuspensionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuspensionInfo) ProtoMessage() {}
+
+func (x *SuspensionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuspensionInfo.ProtoReflect.Descriptor instead.
+func (*SuspensionInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SuspensionInfo) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *SuspensionInfo) GetSuspendedBy() string {
+	if x != nil {
+		return x.SuspendedBy
+	}
+	return ""
+}
+
+func (x *SuspensionInfo) GetSuspendedAt() string {
+	if x != nil {
+		return x.SuspendedAt
+	}
+	return ""
+}
+
+func (x *SuspensionInfo) GetUntil() string {
+	if x != nil {
+		return x.Until
+	}
+	return ""
+}
+
+type SuspendUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Suspension    *SuspensionInfo        `protobuf:"bytes,3,opt,name=suspension,proto3" json:"suspension,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SuspendUserResponse) Reset() {
+	*x = SuspendUserResponse{}
+	mi := &file_api_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuspendUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuspendUserResponse) ProtoMessage() {}
+
+func (x *SuspendUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuspendUserResponse.ProtoReflect.Descriptor instead.
+func (*SuspendUserResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SuspendUserResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SuspendUserResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SuspendUserResponse) GetSuspension() *SuspensionInfo {
+	if x != nil {
+		return x.Suspension
+	}
+	return nil
+}
+
+type UnsuspendUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnsuspendUserRequest) Reset() {
+	*x = UnsuspendUserRequest{}
+	mi := &file_api_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsuspendUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsuspendUserRequest) ProtoMessage() {}
+
+func (x *UnsuspendUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsuspendUserRequest.ProtoReflect.Descriptor instead.
+func (*UnsuspendUserRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UnsuspendUserRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type UnsuspendUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnsuspendUserResponse) Reset() {
+	*x = UnsuspendUserResponse{}
+	mi := &file_api_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsuspendUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsuspendUserResponse) ProtoMessage() {}
+
+func (x *UnsuspendUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsuspendUserResponse.ProtoReflect.Descriptor instead.
+func (*UnsuspendUserResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UnsuspendUserResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UnsuspendUserResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+// A named set of limits; -1 = unlimited. Window caps of 0 are not enforced.
+type Plan struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Name                  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Rps                   int32                  `protobuf:"varint,2,opt,name=rps,proto3" json:"rps,omitempty"`
+	MaxTokens             int64                  `protobuf:"varint,3,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	MaxTokensPerRequest   int64                  `protobuf:"varint,4,opt,name=max_tokens_per_request,json=maxTokensPerRequest,proto3" json:"max_tokens_per_request,omitempty"`
+	MaxConcurrentRequests int64                  `protobuf:"varint,5,opt,name=max_concurrent_requests,json=maxConcurrentRequests,proto3" json:"max_concurrent_requests,omitempty"`
+	TokensPerMinute       int64                  `protobuf:"varint,6,opt,name=tokens_per_minute,json=tokensPerMinute,proto3" json:"tokens_per_minute,omitempty"`
+	TokensPerHour         int64                  `protobuf:"varint,7,opt,name=tokens_per_hour,json=tokensPerHour,proto3" json:"tokens_per_hour,omitempty"`
+	TokensPerDay          int64                  `protobuf:"varint,8,opt,name=tokens_per_day,json=tokensPerDay,proto3" json:"tokens_per_day,omitempty"`
+	TokensPerMonth        int64                  `protobuf:"varint,9,opt,name=tokens_per_month,json=tokensPerMonth,proto3" json:"tokens_per_month,omitempty"`
+	RequestsPerDay        int64                  `protobuf:"varint,10,opt,name=requests_per_day,json=requestsPerDay,proto3" json:"requests_per_day,omitempty"`
+	Users                 int32                  `protobuf:"varint,11,opt,name=users,proto3" json:"users,omitempty"`                                                                  // users currently on the plan (responses only)
+	QuotaPeriod           string                 `protobuf:"bytes,12,opt,name=quota_period,json=quotaPeriod,proto3" json:"quota_period,omitempty"`                                    // "none" (default), "daily", "weekly" or "monthly"
+	Weight                int32                  `protobuf:"varint,13,opt,name=weight,proto3" json:"weight,omitempty"`                                                                // share of a saturated upstream relative to other plans; 0 = 1
+	StreamTokensPerSecond int64                  `protobuf:"varint,14,opt,name=stream_tokens_per_second,json=streamTokensPerSecond,proto3" json:"stream_tokens_per_second,omitempty"` // pace of streamed completions; 0 or -1 = unpaced
+	MaxComputeSeconds     int64                  `protobuf:"varint,15,opt,name=max_compute_seconds,json=maxComputeSeconds,proto3" json:"max_compute_seconds,omitempty"`               // upstream seconds per quota period; 0 or -1 = unlimited
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *Plan) Reset() {
+	*x = Plan{}
+	mi := &file_api_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Plan) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Plan) ProtoMessage() {}
+
+func (x *Plan) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Plan.ProtoReflect.Descriptor instead.
+func (*Plan) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Plan) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Plan) GetRps() int32 {
+	if x != nil {
+		return x.Rps
+	}
+	return 0
+}
+
+func (x *Plan) GetMaxTokens() int64 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *Plan) GetMaxTokensPerRequest() int64 {
+	if x != nil {
+		return x.MaxTokensPerRequest
+	}
+	return 0
+}
+
+func (x *Plan) GetMaxConcurrentRequests() int64 {
+	if x != nil {
+		return x.MaxConcurrentRequests
+	}
+	return 0
+}
+
+func (x *Plan) GetTokensPerMinute() int64 {
+	if x != nil {
+		return x.TokensPerMinute
+	}
+	return 0
+}
+
+func (x *Plan) GetTokensPerHour() int64 {
+	if x != nil {
+		return x.TokensPerHour
+	}
+	return 0
+}
+
+func (x *Plan) GetTokensPerDay() int64 {
+	if x != nil {
+		return x.TokensPerDay
+	}
+	return 0
+}
+
+func (x *Plan) GetTokensPerMonth() int64 {
+	if x != nil {
+		return x.TokensPerMonth
+	}
+	return 0
+}
+
+func (x *Plan) GetRequestsPerDay() int64 {
+	if x != nil {
+		return x.RequestsPerDay
+	}
+	return 0
+}
+
+func (x *Plan) GetUsers() int32 {
+	if x != nil {
+		return x.Users
+	}
+	return 0
+}
+
+func (x *Plan) GetQuotaPeriod() string {
+	if x != nil {
+		return x.QuotaPeriod
+	}
+	return ""
+}
+
+func (x *Plan) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *Plan) GetStreamTokensPerSecond() int64 {
+	if x != nil {
+		return x.StreamTokensPerSecond
+	}
+	return 0
+}
+
+func (x *Plan) GetMaxComputeSeconds() int64 {
+	if x != nil {
+		return x.MaxComputeSeconds
+	}
+	return 0
+}
+
+// POST /admin/plans takes a Plan and updates everyone on it live
+type SetPlanResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Plan          *Plan                  `protobuf:"bytes,1,opt,name=plan,proto3" json:"plan,omitempty"`
+	UsersUpdated  int32                  `protobuf:"varint,2,opt,name=users_updated,json=usersUpdated,proto3" json:"users_updated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPlanResponse) Reset() {
+	*x = SetPlanResponse{}
+	mi := &file_api_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPlanResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPlanResponse) ProtoMessage() {}
+
+func (x *SetPlanResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPlanResponse.ProtoReflect.Descriptor instead.
+func (*SetPlanResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SetPlanResponse) GetPlan() *Plan {
+	if x != nil {
+		return x.Plan
+	}
+	return nil
+}
+
+func (x *SetPlanResponse) GetUsersUpdated() int32 {
+	if x != nil {
+		return x.UsersUpdated
+	}
+	return 0
+}
+
+// GET /admin/plans
+type PlansResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Plans         []*Plan                `protobuf:"bytes,1,rep,name=plans,proto3" json:"plans,omitempty"`
+	DefaultPlan   string                 `protobuf:"bytes,2,opt,name=default_plan,json=defaultPlan,proto3" json:"default_plan,omitempty"` // plan new users start on
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlansResponse) Reset() {
+	*x = PlansResponse{}
+	mi := &file_api_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlansResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlansResponse) ProtoMessage() {}
+
+func (x *PlansResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlansResponse.ProtoReflect.Descriptor instead.
+func (*PlansResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PlansResponse) GetPlans() []*Plan {
+	if x != nil {
+		return x.Plans
+	}
+	return nil
+}
+
+func (x *PlansResponse) GetDefaultPlan() string {
+	if x != nil {
+		return x.DefaultPlan
+	}
+	return ""
+}
+
+// POST /admin/plan assigns a user to a plan
+type AssignPlanRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	UserId         string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Plan           string                 `protobuf:"bytes,2,opt,name=plan,proto3" json:"plan,omitempty"`
+	ClearOverrides bool                   `protobuf:"varint,3,opt,name=clear_overrides,json=clearOverrides,proto3" json:"clear_overrides,omitempty"` // drop the user's own limits so the plan applies as-is
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AssignPlanRequest) Reset() {
+	*x = AssignPlanRequest{}
+	mi := &file_api_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AssignPlanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AssignPlanRequest) ProtoMessage() {}
+
+func (x *AssignPlanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AssignPlanRequest.ProtoReflect.Descriptor instead.
+func (*AssignPlanRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *AssignPlanRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *AssignPlanRequest) GetPlan() string {
+	if x != nil {
+		return x.Plan
+	}
+	return ""
+}
+
+func (x *AssignPlanRequest) GetClearOverrides() bool {
+	if x != nil {
+		return x.ClearOverrides
+	}
+	return false
+}
+
+type AssignPlanResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Plan          string                 `protobuf:"bytes,2,opt,name=plan,proto3" json:"plan,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AssignPlanResponse) Reset() {
+	*x = AssignPlanResponse{}
+	mi := &file_api_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AssignPlanResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AssignPlanResponse) ProtoMessage() {}
+
+func (x *AssignPlanResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AssignPlanResponse.ProtoReflect.Descriptor instead.
+func (*AssignPlanResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *AssignPlanResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *AssignPlanResponse) GetPlan() string {
+	if x != nil {
+		return x.Plan
+	}
+	return ""
+}
+
+// An organization (team): limits shared by its members and enforced together
+// with each member's own; -1 = unlimited. Window caps of 0 are not enforced.
+type Org struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Name            string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Rps             int32                  `protobuf:"varint,2,opt,name=rps,proto3" json:"rps,omitempty"`
+	MaxTokens       int64                  `protobuf:"varint,3,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	TokensPerMinute int64                  `protobuf:"varint,4,opt,name=tokens_per_minute,json=tokensPerMinute,proto3" json:"tokens_per_minute,omitempty"`
+	TokensPerHour   int64                  `protobuf:"varint,5,opt,name=tokens_per_hour,json=tokensPerHour,proto3" json:"tokens_per_hour,omitempty"`
+	TokensPerDay    int64                  `protobuf:"varint,6,opt,name=tokens_per_day,json=tokensPerDay,proto3" json:"tokens_per_day,omitempty"`
+	TokensPerMonth  int64                  `protobuf:"varint,7,opt,name=tokens_per_month,json=tokensPerMonth,proto3" json:"tokens_per_month,omitempty"`
+	RequestsPerDay  int64                  `protobuf:"varint,8,opt,name=requests_per_day,json=requestsPerDay,proto3" json:"requests_per_day,omitempty"`
+	QuotaPeriod     string                 `protobuf:"bytes,9,opt,name=quota_period,json=quotaPeriod,proto3" json:"quota_period,omitempty"` // "none" (default), "daily", "weekly" or "monthly"
+	BillingDay      int32                  `protobuf:"varint,10,opt,name=billing_day,json=billingDay,proto3" json:"billing_day,omitempty"`  // anchor of quota_period; 0 = default
+	// Responses only
+	Members        []string               `protobuf:"bytes,11,rep,name=members,proto3" json:"members,omitempty"`
+	Quota          *QuotaStatus           `protobuf:"bytes,12,opt,name=quota,proto3" json:"quota,omitempty"`                                                                                                               // the org's quota in the current period
+	ReservedTokens int64                  `protobuf:"varint,13,opt,name=reserved_tokens,json=reservedTokens,proto3" json:"reserved_tokens,omitempty"`                                                                      // quota set aside for members' requests in flight
+	WindowUsage    map[string]int64       `protobuf:"bytes,14,rep,name=window_usage,json=windowUsage,proto3" json:"window_usage,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`     // window name → consumed within the window
+	UsageByModel   map[string]*ModelUsage `protobuf:"bytes,15,rep,name=usage_by_model,json=usageByModel,proto3" json:"usage_by_model,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // members' usage while they belonged to it
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Org) Reset() {
+	*x = Org{}
+	mi := &file_api_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Org) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Org) ProtoMessage() {}
+
+func (x *Org) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Org.ProtoReflect.Descriptor instead.
+func (*Org) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Org) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Org) GetRps() int32 {
+	if x != nil {
+		return x.Rps
+	}
+	return 0
+}
+
+func (x *Org) GetMaxTokens() int64 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *Org) GetTokensPerMinute() int64 {
+	if x != nil {
+		return x.TokensPerMinute
+	}
+	return 0
+}
+
+func (x *Org) GetTokensPerHour() int64 {
+	if x != nil {
+		return x.TokensPerHour
+	}
+	return 0
+}
+
+func (x *Org) GetTokensPerDay() int64 {
+	if x != nil {
+		return x.TokensPerDay
+	}
+	return 0
+}
+
+func (x *Org) GetTokensPerMonth() int64 {
+	if x != nil {
+		return x.TokensPerMonth
+	}
+	return 0
+}
+
+func (x *Org) GetRequestsPerDay() int64 {
+	if x != nil {
+		return x.RequestsPerDay
+	}
+	return 0
+}
+
+func (x *Org) GetQuotaPeriod() string {
+	if x != nil {
+		return x.QuotaPeriod
+	}
+	return ""
+}
+
+func (x *Org) GetBillingDay() int32 {
+	if x != nil {
+		return x.BillingDay
+	}
+	return 0
+}
+
+func (x *Org) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *Org) GetQuota() *QuotaStatus {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+func (x *Org) GetReservedTokens() int64 {
+	if x != nil {
+		return x.ReservedTokens
+	}
+	return 0
+}
+
+func (x *Org) GetWindowUsage() map[string]int64 {
+	if x != nil {
+		return x.WindowUsage
+	}
+	return nil
+}
+
+func (x *Org) GetUsageByModel() map[string]*ModelUsage {
+	if x != nil {
+		return x.UsageByModel
+	}
+	return nil
+}
+
+// GET /admin/orgs; POST /admin/orgs takes an Org and returns it
+type OrgsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Orgs          []*Org                 `protobuf:"bytes,1,rep,name=orgs,proto3" json:"orgs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrgsResponse) Reset() {
+	*x = OrgsResponse{}
+	mi := &file_api_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrgsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrgsResponse) ProtoMessage() {}
+
+func (x *OrgsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrgsResponse.ProtoReflect.Descriptor instead.
+func (*OrgsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *OrgsResponse) GetOrgs() []*Org {
+	if x != nil {
+		return x.Orgs
+	}
+	return nil
+}
+
+// POST /admin/org moves a user into an organization
+type SetUserOrgRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Org           string                 `protobuf:"bytes,2,opt,name=org,proto3" json:"org,omitempty"` // empty = leave their organization
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetUserOrgRequest) Reset() {
+	*x = SetUserOrgRequest{}
+	mi := &file_api_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetUserOrgRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetUserOrgRequest) ProtoMessage() {}
+
+func (x *SetUserOrgRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetUserOrgRequest.ProtoReflect.Descriptor instead.
+func (*SetUserOrgRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SetUserOrgRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetUserOrgRequest) GetOrg() string {
+	if x != nil {
+		return x.Org
+	}
+	return ""
+}
+
+type SetUserOrgResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Org           string                 `protobuf:"bytes,2,opt,name=org,proto3" json:"org,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetUserOrgResponse) Reset() {
+	*x = SetUserOrgResponse{}
+	mi := &file_api_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetUserOrgResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetUserOrgResponse) ProtoMessage() {}
+
+func (x *SetUserOrgResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetUserOrgResponse.ProtoReflect.Descriptor instead.
+func (*SetUserOrgResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SetUserOrgResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetUserOrgResponse) GetOrg() string {
+	if x != nil {
+		return x.Org
+	}
+	return ""
+}
+
+// POST /admin/priority sets the upstream queue lane for a user's requests
+type SetPriorityRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Priority      string                 `protobuf:"bytes,2,opt,name=priority,proto3" json:"priority,omitempty"` // "interactive" (default) or "batch"; empty = default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPriorityRequest) Reset() {
+	*x = SetPriorityRequest{}
+	mi := &file_api_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPriorityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPriorityRequest) ProtoMessage() {}
+
+func (x *SetPriorityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPriorityRequest.ProtoReflect.Descriptor instead.
+func (*SetPriorityRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SetPriorityRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetPriorityRequest) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+type SetPriorityResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Priority      string                 `protobuf:"bytes,2,opt,name=priority,proto3" json:"priority,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPriorityResponse) Reset() {
+	*x = SetPriorityResponse{}
+	mi := &file_api_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPriorityResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPriorityResponse) ProtoMessage() {}
+
+func (x *SetPriorityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPriorityResponse.ProtoReflect.Descriptor instead.
+func (*SetPriorityResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SetPriorityResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetPriorityResponse) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+type SetAllowedModelsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Models        []string               `protobuf:"bytes,2,rep,name=models,proto3" json:"models,omitempty"` // empty = all models allowed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetAllowedModelsRequest) Reset() {
+	*x = SetAllowedModelsRequest{}
+	mi := &file_api_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetAllowedModelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAllowedModelsRequest) ProtoMessage() {}
+
+func (x *SetAllowedModelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAllowedModelsRequest.ProtoReflect.Descriptor instead.
+func (*SetAllowedModelsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SetAllowedModelsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetAllowedModelsRequest) GetModels() []string {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+type SetAllowedModelsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Models        []string               `protobuf:"bytes,2,rep,name=models,proto3" json:"models,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetAllowedModelsResponse) Reset() {
+	*x = SetAllowedModelsResponse{}
+	mi := &file_api_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetAllowedModelsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAllowedModelsResponse) ProtoMessage() {}
+
+func (x *SetAllowedModelsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAllowedModelsResponse.ProtoReflect.Descriptor instead.
+func (*SetAllowedModelsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *SetAllowedModelsResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetAllowedModelsResponse) GetModels() []string {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+// Limits scoped to one model, applied on top of the user-wide ones; 0 or -1 = none
+type ModelLimitInfo struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	MaxTokens       int64                  `protobuf:"varint,1,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	MaxTokensPerReq int64                  `protobuf:"varint,2,opt,name=max_tokens_per_req,json=maxTokensPerReq,proto3" json:"max_tokens_per_req,omitempty"`
+	UsedTokens      int64                  `protobuf:"varint,3,opt,name=used_tokens,json=usedTokens,proto3" json:"used_tokens,omitempty"` // tokens used on this model since its limits were set
+	Rps             float64                `protobuf:"fixed64,4,opt,name=rps,proto3" json:"rps,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ModelLimitInfo) Reset() {
+	*x = ModelLimitInfo{}
+	mi := &file_api_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModelLimitInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModelLimitInfo) ProtoMessage() {}
+
+func (x *ModelLimitInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModelLimitInfo.ProtoReflect.Descriptor instead.
+func (*ModelLimitInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ModelLimitInfo) GetMaxTokens() int64 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *ModelLimitInfo) GetMaxTokensPerReq() int64 {
+	if x != nil {
+		return x.MaxTokensPerReq
+	}
+	return 0
+}
+
+func (x *ModelLimitInfo) GetUsedTokens() int64 {
+	if x != nil {
+		return x.UsedTokens
+	}
+	return 0
+}
+
+func (x *ModelLimitInfo) GetRps() float64 {
+	if x != nil {
+		return x.Rps
+	}
+	return 0
+}
+
+// Represents the LimitInfo struct returned by the limiter
+type LimitInfo struct {
+	state                 protoimpl.MessageState     `protogen:"open.v1"`
+	MaxTokens             int64                      `protobuf:"varint,1,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`                       // Go json mapping: "MaxTokens"
+	MaxTokensPerReq       int64                      `protobuf:"varint,2,opt,name=max_tokens_per_req,json=maxTokensPerReq,proto3" json:"max_tokens_per_req,omitempty"` // Go json mapping: "MaxTokensPerReq"
+	UsedTokens            int64                      `protobuf:"varint,3,opt,name=used_tokens,json=usedTokens,proto3" json:"used_tokens,omitempty"`                    // Go json mapping: "UsedTokens"
+	Rps                   float64                    `protobuf:"fixed64,4,opt,name=rps,proto3" json:"rps,omitempty"`                                                   // Go json mapping: "RPS"
+	AllowedModels         []string                   `protobuf:"bytes,5,rep,name=allowed_models,json=allowedModels,proto3" json:"allowed_models,omitempty"`            // Go json mapping: "AllowedModels"; empty = all
+	TokensPerMinute       int64                      `protobuf:"varint,6,opt,name=tokens_per_minute,json=tokensPerMinute,proto3" json:"tokens_per_minute,omitempty"`   // sliding-window caps; -1 = unlimited
+	TokensPerHour         int64                      `protobuf:"varint,7,opt,name=tokens_per_hour,json=tokensPerHour,proto3" json:"tokens_per_hour,omitempty"`
+	TokensPerDay          int64                      `protobuf:"varint,8,opt,name=tokens_per_day,json=tokensPerDay,proto3" json:"tokens_per_day,omitempty"`
+	TokensPerMonth        int64                      `protobuf:"varint,9,opt,name=tokens_per_month,json=tokensPerMonth,proto3" json:"tokens_per_month,omitempty"`
+	RequestsPerDay        int64                      `protobuf:"varint,10,opt,name=requests_per_day,json=requestsPerDay,proto3" json:"requests_per_day,omitempty"`
+	WindowUsage           map[string]int64           `protobuf:"bytes,11,rep,name=window_usage,json=windowUsage,proto3" json:"window_usage,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // window name → consumed within the window
+	MaxConcurrentRequests int64                      `protobuf:"varint,12,opt,name=max_concurrent_requests,json=maxConcurrentRequests,proto3" json:"max_concurrent_requests,omitempty"`                                           // -1 = unlimited
+	InFlight              int64                      `protobuf:"varint,13,opt,name=in_flight,json=inFlight,proto3" json:"in_flight,omitempty"`                                                                                    // requests currently in flight
+	Models                map[string]*ModelLimitInfo `protobuf:"bytes,14,rep,name=models,proto3" json:"models,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`                               // per-model overrides, keyed by model
+	ReservedTokens        int64                      `protobuf:"varint,15,opt,name=reserved_tokens,json=reservedTokens,proto3" json:"reserved_tokens,omitempty"`                                                                  // quota set aside for requests in flight
+	Plan                  string                     `protobuf:"bytes,16,opt,name=plan,proto3" json:"plan,omitempty"`                                                                                                             // plan the limits derive from
+	Suspension            *SuspensionInfo            `protobuf:"bytes,17,opt,name=suspension,proto3" json:"suspension,omitempty"`                                                                                                 // unset = not suspended
+	Quota                 *QuotaStatus               `protobuf:"bytes,18,opt,name=quota,proto3" json:"quota,omitempty"`                                                                                                           // current quota period
+	Priority              string                     `protobuf:"bytes,19,opt,name=priority,proto3" json:"priority,omitempty"`                                                                                                     // upstream queue lane; empty = interactive
+	Org                   string                     `protobuf:"bytes,20,opt,name=org,proto3" json:"org,omitempty"`                                                                                                               // organization; empty = none
+	StreamTokensPerSecond int64                      `protobuf:"varint,21,opt,name=stream_tokens_per_second,json=streamTokensPerSecond,proto3" json:"stream_tokens_per_second,omitempty"`                                         // pace of streamed completions; -1 = unpaced
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *LimitInfo) Reset() {
+	*x = LimitInfo{}
+	mi := &file_api_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LimitInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LimitInfo) ProtoMessage() {}
+
+func (x *LimitInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LimitInfo.ProtoReflect.Descriptor instead.
+func (*LimitInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *LimitInfo) GetMaxTokens() int64 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetMaxTokensPerReq() int64 {
+	if x != nil {
+		return x.MaxTokensPerReq
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetUsedTokens() int64 {
+	if x != nil {
+		return x.UsedTokens
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetRps() float64 {
+	if x != nil {
+		return x.Rps
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetAllowedModels() []string {
+	if x != nil {
+		return x.AllowedModels
+	}
+	return nil
+}
+
+func (x *LimitInfo) GetTokensPerMinute() int64 {
+	if x != nil {
+		return x.TokensPerMinute
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetTokensPerHour() int64 {
+	if x != nil {
+		return x.TokensPerHour
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetTokensPerDay() int64 {
+	if x != nil {
+		return x.TokensPerDay
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetTokensPerMonth() int64 {
+	if x != nil {
+		return x.TokensPerMonth
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetRequestsPerDay() int64 {
+	if x != nil {
+		return x.RequestsPerDay
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetWindowUsage() map[string]int64 {
+	if x != nil {
+		return x.WindowUsage
+	}
+	return nil
+}
+
+func (x *LimitInfo) GetMaxConcurrentRequests() int64 {
+	if x != nil {
+		return x.MaxConcurrentRequests
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetInFlight() int64 {
+	if x != nil {
+		return x.InFlight
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetModels() map[string]*ModelLimitInfo {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+func (x *LimitInfo) GetReservedTokens() int64 {
+	if x != nil {
+		return x.ReservedTokens
+	}
+	return 0
+}
+
+func (x *LimitInfo) GetPlan() string {
+	if x != nil {
+		return x.Plan
+	}
+	return ""
+}
+
+func (x *LimitInfo) GetSuspension() *SuspensionInfo {
+	if x != nil {
+		return x.Suspension
+	}
+	return nil
+}
+
+func (x *LimitInfo) GetQuota() *QuotaStatus {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+func (x *LimitInfo) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *LimitInfo) GetOrg() string {
+	if x != nil {
+		return x.Org
+	}
+	return ""
+}
+
+func (x *LimitInfo) GetStreamTokensPerSecond() int64 {
+	if x != nil {
+		return x.StreamTokensPerSecond
+	}
+	return 0
+}
+
+// The token quota in the current period; times are RFC 3339
+type QuotaStatus struct {
+	state                   protoimpl.MessageState `protogen:"open.v1"`
+	Period                  string                 `protobuf:"bytes,1,opt,name=period,proto3" json:"period,omitempty"`                              // "none", "daily", "weekly" or "monthly"
+	BillingDay              int32                  `protobuf:"varint,2,opt,name=billing_day,json=billingDay,proto3" json:"billing_day,omitempty"`   // anchor of the period; 0 = default
+	PeriodStart             string                 `protobuf:"bytes,3,opt,name=period_start,json=periodStart,proto3" json:"period_start,omitempty"` // empty when the quota never resets
+	PeriodEnd               string                 `protobuf:"bytes,4,opt,name=period_end,json=periodEnd,proto3" json:"period_end,omitempty"`
+	MaxTokens               int64                  `protobuf:"varint,5,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`                                               // -1 = unlimited
+	UsedTokens              int64                  `protobuf:"varint,6,opt,name=used_tokens,json=usedTokens,proto3" json:"used_tokens,omitempty"`                                            // consumed in the current period
+	RemainingTokens         int64                  `protobuf:"varint,7,opt,name=remaining_tokens,json=remainingTokens,proto3" json:"remaining_tokens,omitempty"`                             // -1 = unlimited
+	MaxComputeSeconds       int64                  `protobuf:"varint,8,opt,name=max_compute_seconds,json=maxComputeSeconds,proto3" json:"max_compute_seconds,omitempty"`                     // upstream seconds per period; -1 = unlimited
+	UsedComputeSeconds      float64                `protobuf:"fixed64,9,opt,name=used_compute_seconds,json=usedComputeSeconds,proto3" json:"used_compute_seconds,omitempty"`                 // upstream time consumed in the current period
+	RemainingComputeSeconds float64                `protobuf:"fixed64,10,opt,name=remaining_compute_seconds,json=remainingComputeSeconds,proto3" json:"remaining_compute_seconds,omitempty"` // -1 = unlimited
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *QuotaStatus) Reset() {
+	*x = QuotaStatus{}
+	mi := &file_api_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaStatus) ProtoMessage() {}
+
+func (x *QuotaStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaStatus.ProtoReflect.Descriptor instead.
+func (*QuotaStatus) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *QuotaStatus) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *QuotaStatus) GetBillingDay() int32 {
+	if x != nil {
+		return x.BillingDay
+	}
+	return 0
+}
+
+func (x *QuotaStatus) GetPeriodStart() string {
+	if x != nil {
+		return x.PeriodStart
+	}
+	return ""
+}
+
+func (x *QuotaStatus) GetPeriodEnd() string {
+	if x != nil {
+		return x.PeriodEnd
+	}
+	return ""
+}
+
+func (x *QuotaStatus) GetMaxTokens() int64 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
 }
 
-// Deprecated: Use SetLimitsResponse.ProtoReflect.Descriptor instead.
-func (*SetLimitsResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{3}
+func (x *QuotaStatus) GetUsedTokens() int64 {
+	if x != nil {
+		return x.UsedTokens
+	}
+	return 0
 }
 
-func (x *SetLimitsResponse) GetUserId() string {
+func (x *QuotaStatus) GetRemainingTokens() int64 {
 	if x != nil {
-		return x.UserId
+		return x.RemainingTokens
 	}
-	return ""
+	return 0
 }
 
-func (x *SetLimitsResponse) GetRps() int32 {
+func (x *QuotaStatus) GetMaxComputeSeconds() int64 {
 	if x != nil {
-		return x.Rps
+		return x.MaxComputeSeconds
 	}
 	return 0
 }
 
-func (x *SetLimitsResponse) GetMaxTokens() int64 {
+func (x *QuotaStatus) GetUsedComputeSeconds() float64 {
 	if x != nil {
-		return x.MaxTokens
+		return x.UsedComputeSeconds
 	}
 	return 0
 }
 
-func (x *SetLimitsResponse) GetMaxTokensPerRequest() int64 {
+func (x *QuotaStatus) GetRemainingComputeSeconds() float64 {
 	if x != nil {
-		return x.MaxTokensPerRequest
+		return x.RemainingComputeSeconds
 	}
 	return 0
 }
 
-type SuspendUserRequest struct {
+// GET /admin/limits returns a map of UserID -> LimitInfo
+type AllLimitsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Limits        map[string]*LimitInfo  `protobuf:"bytes,1,rep,name=limits,proto3" json:"limits,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SuspendUserRequest) Reset() {
-	*x = SuspendUserRequest{}
-	mi := &file_api_proto_msgTypes[4]
+func (x *AllLimitsResponse) Reset() {
+	*x = AllLimitsResponse{}
+	mi := &file_api_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SuspendUserRequest) String() string {
+func (x *AllLimitsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SuspendUserRequest) ProtoMessage() {}
+func (*AllLimitsResponse) ProtoMessage() {}
 
-func (x *SuspendUserRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[4]
+func (x *AllLimitsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -301,41 +2184,54 @@ func (x *SuspendUserRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SuspendUserRequest.ProtoReflect.Descriptor instead.
-func (*SuspendUserRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use AllLimitsResponse.ProtoReflect.Descriptor instead.
+func (*AllLimitsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{26}
 }
 
-func (x *SuspendUserRequest) GetUserId() string {
+func (x *AllLimitsResponse) GetLimits() map[string]*LimitInfo {
 	if x != nil {
-		return x.UserId
+		return x.Limits
 	}
-	return ""
-}
-
-type SuspendUserResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	return nil
 }
 
-func (x *SuspendUserResponse) Reset() {
-	*x = SuspendUserResponse{}
-	mi := &file_api_proto_msgTypes[5]
+// Health and load of one Ollama backend
+type UpstreamStatus struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Url      string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Healthy  bool                   `protobuf:"varint,2,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	InFlight int64                  `protobuf:"varint,3,opt,name=in_flight,json=inFlight,proto3" json:"in_flight,omitempty"`
+	Routes   []string               `protobuf:"bytes,4,rep,name=routes,proto3" json:"routes,omitempty"`      // model patterns routed here; empty = all models
+	Attempts int64                  `protobuf:"varint,5,opt,name=attempts,proto3" json:"attempts,omitempty"` // proxy attempts sent here, including retries
+	Failures int64                  `protobuf:"varint,6,opt,name=failures,proto3" json:"failures,omitempty"` // attempts that failed before reaching the client
+	// Admission queue (queue.max_concurrent_per_upstream in config.json)
+	MaxConcurrent  int64                       `protobuf:"varint,7,opt,name=max_concurrent,json=maxConcurrent,proto3" json:"max_concurrent,omitempty"`                                      // concurrency budget; 0 = unlimited
+	Queued         int64                       `protobuf:"varint,8,opt,name=queued,proto3" json:"queued,omitempty"`                                                                         // requests waiting for a slot now
+	QueueAdmitted  int64                       `protobuf:"varint,9,opt,name=queue_admitted,json=queueAdmitted,proto3" json:"queue_admitted,omitempty"`                                      // requests given a slot so far
+	AvgQueueWaitMs float64                     `protobuf:"fixed64,10,opt,name=avg_queue_wait_ms,json=avgQueueWaitMs,proto3" json:"avg_queue_wait_ms,omitempty"`                             // mean time admitted requests spent queued
+	MaxQueueWaitMs float64                     `protobuf:"fixed64,11,opt,name=max_queue_wait_ms,json=maxQueueWaitMs,proto3" json:"max_queue_wait_ms,omitempty"`                             // longest time an admitted request spent queued
+	Lanes          map[string]*QueueLaneStatus `protobuf:"bytes,12,rep,name=lanes,proto3" json:"lanes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // the same per priority: "interactive", "batch"
+	Promoted       int64                       `protobuf:"varint,13,opt,name=promoted,proto3" json:"promoted,omitempty"`                                                                    // batch requests served early after waiting queue.promote_batch_after
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *UpstreamStatus) Reset() {
+	*x = UpstreamStatus{}
+	mi := &file_api_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SuspendUserResponse) String() string {
+func (x *UpstreamStatus) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SuspendUserResponse) ProtoMessage() {}
+func (*UpstreamStatus) ProtoMessage() {}
 
-func (x *SuspendUserResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[5]
+func (x *UpstreamStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -346,51 +2242,128 @@ func (x *SuspendUserResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SuspendUserResponse.ProtoReflect.Descriptor instead.
-func (*SuspendUserResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use UpstreamStatus.ProtoReflect.Descriptor instead.
+func (*UpstreamStatus) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{27}
 }
 
-func (x *SuspendUserResponse) GetUserId() string {
+func (x *UpstreamStatus) GetUrl() string {
 	if x != nil {
-		return x.UserId
+		return x.Url
 	}
 	return ""
 }
 
-func (x *SuspendUserResponse) GetStatus() string {
+func (x *UpstreamStatus) GetHealthy() bool {
 	if x != nil {
-		return x.Status
+		return x.Healthy
 	}
-	return ""
+	return false
 }
 
-// Represents the LimitInfo struct returned by the limiter
-type LimitInfo struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	MaxTokens       int64                  `protobuf:"varint,1,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`                       // Go json mapping: "MaxTokens"
-	MaxTokensPerReq int64                  `protobuf:"varint,2,opt,name=max_tokens_per_req,json=maxTokensPerReq,proto3" json:"max_tokens_per_req,omitempty"` // Go json mapping: "MaxTokensPerReq"
-	UsedTokens      int64                  `protobuf:"varint,3,opt,name=used_tokens,json=usedTokens,proto3" json:"used_tokens,omitempty"`                    // Go json mapping: "UsedTokens"
-	Rps             float64                `protobuf:"fixed64,4,opt,name=rps,proto3" json:"rps,omitempty"`                                                   // Go json mapping: "RPS"
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+func (x *UpstreamStatus) GetInFlight() int64 {
+	if x != nil {
+		return x.InFlight
+	}
+	return 0
 }
 
-func (x *LimitInfo) Reset() {
-	*x = LimitInfo{}
-	mi := &file_api_proto_msgTypes[6]
+func (x *UpstreamStatus) GetRoutes() []string {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+func (x *UpstreamStatus) GetAttempts() int64 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *UpstreamStatus) GetFailures() int64 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
+}
+
+func (x *UpstreamStatus) GetMaxConcurrent() int64 {
+	if x != nil {
+		return x.MaxConcurrent
+	}
+	return 0
+}
+
+func (x *UpstreamStatus) GetQueued() int64 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
+func (x *UpstreamStatus) GetQueueAdmitted() int64 {
+	if x != nil {
+		return x.QueueAdmitted
+	}
+	return 0
+}
+
+func (x *UpstreamStatus) GetAvgQueueWaitMs() float64 {
+	if x != nil {
+		return x.AvgQueueWaitMs
+	}
+	return 0
+}
+
+func (x *UpstreamStatus) GetMaxQueueWaitMs() float64 {
+	if x != nil {
+		return x.MaxQueueWaitMs
+	}
+	return 0
+}
+
+func (x *UpstreamStatus) GetLanes() map[string]*QueueLaneStatus {
+	if x != nil {
+		return x.Lanes
+	}
+	return nil
+}
+
+func (x *UpstreamStatus) GetPromoted() int64 {
+	if x != nil {
+		return x.Promoted
+	}
+	return 0
+}
+
+// Admission queue state of one priority lane on one backend
+type QueueLaneStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Queued        int64                  `protobuf:"varint,1,opt,name=queued,proto3" json:"queued,omitempty"`
+	Admitted      int64                  `protobuf:"varint,2,opt,name=admitted,proto3" json:"admitted,omitempty"`
+	AvgWaitMs     float64                `protobuf:"fixed64,3,opt,name=avg_wait_ms,json=avgWaitMs,proto3" json:"avg_wait_ms,omitempty"`
+	MaxWaitMs     float64                `protobuf:"fixed64,4,opt,name=max_wait_ms,json=maxWaitMs,proto3" json:"max_wait_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueueLaneStatus) Reset() {
+	*x = QueueLaneStatus{}
+	mi := &file_api_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LimitInfo) String() string {
+func (x *QueueLaneStatus) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LimitInfo) ProtoMessage() {}
+func (*QueueLaneStatus) ProtoMessage() {}
 
-func (x *LimitInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[6]
+func (x *QueueLaneStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -401,62 +2374,62 @@ func (x *LimitInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LimitInfo.ProtoReflect.Descriptor instead.
-func (*LimitInfo) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use QueueLaneStatus.ProtoReflect.Descriptor instead.
+func (*QueueLaneStatus) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{28}
 }
 
-func (x *LimitInfo) GetMaxTokens() int64 {
+func (x *QueueLaneStatus) GetQueued() int64 {
 	if x != nil {
-		return x.MaxTokens
+		return x.Queued
 	}
 	return 0
 }
 
-func (x *LimitInfo) GetMaxTokensPerReq() int64 {
+func (x *QueueLaneStatus) GetAdmitted() int64 {
 	if x != nil {
-		return x.MaxTokensPerReq
+		return x.Admitted
 	}
 	return 0
 }
 
-func (x *LimitInfo) GetUsedTokens() int64 {
+func (x *QueueLaneStatus) GetAvgWaitMs() float64 {
 	if x != nil {
-		return x.UsedTokens
+		return x.AvgWaitMs
 	}
 	return 0
 }
 
-func (x *LimitInfo) GetRps() float64 {
+func (x *QueueLaneStatus) GetMaxWaitMs() float64 {
 	if x != nil {
-		return x.Rps
+		return x.MaxWaitMs
 	}
 	return 0
 }
 
-// GET /admin/limits returns a map of UserID -> LimitInfo
-type AllLimitsResponse struct {
+// GET /admin/upstreams returns every backend in the pool
+type UpstreamsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Limits        map[string]*LimitInfo  `protobuf:"bytes,1,rep,name=limits,proto3" json:"limits,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Upstreams     []*UpstreamStatus      `protobuf:"bytes,1,rep,name=upstreams,proto3" json:"upstreams,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AllLimitsResponse) Reset() {
-	*x = AllLimitsResponse{}
-	mi := &file_api_proto_msgTypes[7]
+func (x *UpstreamsResponse) Reset() {
+	*x = UpstreamsResponse{}
+	mi := &file_api_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AllLimitsResponse) String() string {
+func (x *UpstreamsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AllLimitsResponse) ProtoMessage() {}
+func (*UpstreamsResponse) ProtoMessage() {}
 
-func (x *AllLimitsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[7]
+func (x *UpstreamsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -467,30 +2440,37 @@ func (x *AllLimitsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AllLimitsResponse.ProtoReflect.Descriptor instead.
-func (*AllLimitsResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use UpstreamsResponse.ProtoReflect.Descriptor instead.
+func (*UpstreamsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{29}
 }
 
-func (x *AllLimitsResponse) GetLimits() map[string]*LimitInfo {
+func (x *UpstreamsResponse) GetUpstreams() []*UpstreamStatus {
 	if x != nil {
-		return x.Limits
+		return x.Upstreams
 	}
 	return nil
 }
 
 // Represents the ModelUsage struct
 type ModelUsage struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	PromptTokens     int32                  `protobuf:"varint,1,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
-	CompletionTokens int32                  `protobuf:"varint,2,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	PromptTokens      int32                  `protobuf:"varint,1,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens  int32                  `protobuf:"varint,2,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	EmbeddingTokens   int32                  `protobuf:"varint,3,opt,name=embedding_tokens,json=embeddingTokens,proto3" json:"embedding_tokens,omitempty"`
+	AbortedRequests   int32                  `protobuf:"varint,4,opt,name=aborted_requests,json=abortedRequests,proto3" json:"aborted_requests,omitempty"`         // requests cut off before completion (e.g. client disconnect)
+	AbortedTokens     int32                  `protobuf:"varint,5,opt,name=aborted_tokens,json=abortedTokens,proto3" json:"aborted_tokens,omitempty"`               // estimated tokens billed for aborted requests
+	EstimatedRequests int32                  `protobuf:"varint,6,opt,name=estimated_requests,json=estimatedRequests,proto3" json:"estimated_requests,omitempty"`   // requests billed from a proxy-side estimate (no upstream usage)
+	EstimatedTokens   int32                  `protobuf:"varint,7,opt,name=estimated_tokens,json=estimatedTokens,proto3" json:"estimated_tokens,omitempty"`         // tokens billed from estimates, including aborted requests
+	ComputeSeconds    float64                `protobuf:"fixed64,8,opt,name=compute_seconds,json=computeSeconds,proto3" json:"compute_seconds,omitempty"`           // upstream time: Ollama's total_duration, else wall clock
+	WallClockRequests int32                  `protobuf:"varint,9,opt,name=wall_clock_requests,json=wallClockRequests,proto3" json:"wall_clock_requests,omitempty"` // requests whose compute time is the proxy's wall-clock measure
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *ModelUsage) Reset() {
 	*x = ModelUsage{}
-	mi := &file_api_proto_msgTypes[8]
+	mi := &file_api_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -502,7 +2482,7 @@ func (x *ModelUsage) String() string {
 func (*ModelUsage) ProtoMessage() {}
 
 func (x *ModelUsage) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[8]
+	mi := &file_api_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -515,7 +2495,7 @@ func (x *ModelUsage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ModelUsage.ProtoReflect.Descriptor instead.
 func (*ModelUsage) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{8}
+	return file_api_proto_rawDescGZIP(), []int{30}
 }
 
 func (x *ModelUsage) GetPromptTokens() int32 {
@@ -532,17 +2512,67 @@ func (x *ModelUsage) GetCompletionTokens() int32 {
 	return 0
 }
 
+func (x *ModelUsage) GetEmbeddingTokens() int32 {
+	if x != nil {
+		return x.EmbeddingTokens
+	}
+	return 0
+}
+
+func (x *ModelUsage) GetAbortedRequests() int32 {
+	if x != nil {
+		return x.AbortedRequests
+	}
+	return 0
+}
+
+func (x *ModelUsage) GetAbortedTokens() int32 {
+	if x != nil {
+		return x.AbortedTokens
+	}
+	return 0
+}
+
+func (x *ModelUsage) GetEstimatedRequests() int32 {
+	if x != nil {
+		return x.EstimatedRequests
+	}
+	return 0
+}
+
+func (x *ModelUsage) GetEstimatedTokens() int32 {
+	if x != nil {
+		return x.EstimatedTokens
+	}
+	return 0
+}
+
+func (x *ModelUsage) GetComputeSeconds() float64 {
+	if x != nil {
+		return x.ComputeSeconds
+	}
+	return 0
+}
+
+func (x *ModelUsage) GetWallClockRequests() int32 {
+	if x != nil {
+		return x.WallClockRequests
+	}
+	return 0
+}
+
 // GET /v1/usage returns a map of ModelName -> ModelUsage
 type UsageResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UsageByModel  map[string]*ModelUsage `protobuf:"bytes,1,rep,name=usage_by_model,json=usageByModel,proto3" json:"usage_by_model,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Quota         *QuotaStatus           `protobuf:"bytes,2,opt,name=quota,proto3" json:"quota,omitempty"` // GET /v1/usage only
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UsageResponse) Reset() {
 	*x = UsageResponse{}
-	mi := &file_api_proto_msgTypes[9]
+	mi := &file_api_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -554,7 +2584,7 @@ func (x *UsageResponse) String() string {
 func (*UsageResponse) ProtoMessage() {}
 
 func (x *UsageResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[9]
+	mi := &file_api_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -567,7 +2597,7 @@ func (x *UsageResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UsageResponse.ProtoReflect.Descriptor instead.
 func (*UsageResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{9}
+	return file_api_proto_rawDescGZIP(), []int{31}
 }
 
 func (x *UsageResponse) GetUsageByModel() map[string]*ModelUsage {
@@ -577,17 +2607,25 @@ func (x *UsageResponse) GetUsageByModel() map[string]*ModelUsage {
 	return nil
 }
 
-// GET /admin/usage returns a map of UserID -> (ModelName -> ModelUsage)
+func (x *UsageResponse) GetQuota() *QuotaStatus {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+// GET /admin/usage returns a map of UserID -> (ModelName -> ModelUsage), and the same per organization
 type AllUsageResponse struct {
 	state         protoimpl.MessageState    `protogen:"open.v1"`
 	UsageByUser   map[string]*UsageResponse `protobuf:"bytes,1,rep,name=usage_by_user,json=usageByUser,proto3" json:"usage_by_user,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	UsageByOrg    map[string]*UsageResponse `protobuf:"bytes,2,rep,name=usage_by_org,json=usageByOrg,proto3" json:"usage_by_org,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // each organization's members, while they belonged to it
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *AllUsageResponse) Reset() {
 	*x = AllUsageResponse{}
-	mi := &file_api_proto_msgTypes[10]
+	mi := &file_api_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -599,7 +2637,7 @@ func (x *AllUsageResponse) String() string {
 func (*AllUsageResponse) ProtoMessage() {}
 
 func (x *AllUsageResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[10]
+	mi := &file_api_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -612,7 +2650,7 @@ func (x *AllUsageResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AllUsageResponse.ProtoReflect.Descriptor instead.
 func (*AllUsageResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{10}
+	return file_api_proto_rawDescGZIP(), []int{32}
 }
 
 func (x *AllUsageResponse) GetUsageByUser() map[string]*UsageResponse {
@@ -622,6 +2660,13 @@ func (x *AllUsageResponse) GetUsageByUser() map[string]*UsageResponse {
 	return nil
 }
 
+func (x *AllUsageResponse) GetUsageByOrg() map[string]*UsageResponse {
+	if x != nil {
+		return x.UsageByOrg
+	}
+	return nil
+}
+
 type ChatMessage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Role          string                 `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
@@ -632,7 +2677,7 @@ type ChatMessage struct {
 
 func (x *ChatMessage) Reset() {
 	*x = ChatMessage{}
-	mi := &file_api_proto_msgTypes[11]
+	mi := &file_api_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -644,7 +2689,7 @@ func (x *ChatMessage) String() string {
 func (*ChatMessage) ProtoMessage() {}
 
 func (x *ChatMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[11]
+	mi := &file_api_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -657,7 +2702,7 @@ func (x *ChatMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ChatMessage.ProtoReflect.Descriptor instead.
 func (*ChatMessage) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{11}
+	return file_api_proto_rawDescGZIP(), []int{33}
 }
 
 func (x *ChatMessage) GetRole() string {
@@ -686,7 +2731,7 @@ type ChatCompletionRequest struct {
 
 func (x *ChatCompletionRequest) Reset() {
 	*x = ChatCompletionRequest{}
-	mi := &file_api_proto_msgTypes[12]
+	mi := &file_api_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -698,7 +2743,7 @@ func (x *ChatCompletionRequest) String() string {
 func (*ChatCompletionRequest) ProtoMessage() {}
 
 func (x *ChatCompletionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[12]
+	mi := &file_api_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -711,7 +2756,7 @@ func (x *ChatCompletionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ChatCompletionRequest.ProtoReflect.Descriptor instead.
 func (*ChatCompletionRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{12}
+	return file_api_proto_rawDescGZIP(), []int{34}
 }
 
 func (x *ChatCompletionRequest) GetModel() string {
@@ -753,49 +2798,284 @@ const file_api_proto_rawDesc = "" +
 	"\rLoginResponse\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x17\n" +
 	"\aapi_key\x18\x02 \x01(\tR\x06apiKey\x12\x19\n" +
-	"\bis_admin\x18\x03 \x01(\bR\aisAdmin\"\x91\x01\n" +
+	"\bis_admin\x18\x03 \x01(\bR\aisAdmin\"\xf1\x03\n" +
 	"\x10SetLimitsRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x10\n" +
 	"\x03rps\x18\x02 \x01(\x05R\x03rps\x12\x1d\n" +
 	"\n" +
 	"max_tokens\x18\x03 \x01(\x03R\tmaxTokens\x123\n" +
-	"\x16max_tokens_per_request\x18\x04 \x01(\x03R\x13maxTokensPerRequest\"\x92\x01\n" +
+	"\x16max_tokens_per_request\x18\x04 \x01(\x03R\x13maxTokensPerRequest\x12*\n" +
+	"\x11tokens_per_minute\x18\x05 \x01(\x03R\x0ftokensPerMinute\x12&\n" +
+	"\x0ftokens_per_hour\x18\x06 \x01(\x03R\rtokensPerHour\x12$\n" +
+	"\x0etokens_per_day\x18\a \x01(\x03R\ftokensPerDay\x12(\n" +
+	"\x10tokens_per_month\x18\b \x01(\x03R\x0etokensPerMonth\x12(\n" +
+	"\x10requests_per_day\x18\t \x01(\x03R\x0erequestsPerDay\x126\n" +
+	"\x17max_concurrent_requests\x18\n" +
+	" \x01(\x03R\x15maxConcurrentRequests\x12\x14\n" +
+	"\x05model\x18\v \x01(\tR\x05model\x12!\n" +
+	"\fquota_period\x18\f \x01(\tR\vquotaPeriod\x12\x1f\n" +
+	"\vbilling_day\x18\r \x01(\x05R\n" +
+	"billingDay\"\xf2\x03\n" +
 	"\x11SetLimitsResponse\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x10\n" +
 	"\x03rps\x18\x02 \x01(\x05R\x03rps\x12\x1d\n" +
 	"\n" +
 	"max_tokens\x18\x03 \x01(\x03R\tmaxTokens\x123\n" +
-	"\x16max_tokens_per_request\x18\x04 \x01(\x03R\x13maxTokensPerRequest\"-\n" +
+	"\x16max_tokens_per_request\x18\x04 \x01(\x03R\x13maxTokensPerRequest\x12*\n" +
+	"\x11tokens_per_minute\x18\x05 \x01(\x03R\x0ftokensPerMinute\x12&\n" +
+	"\x0ftokens_per_hour\x18\x06 \x01(\x03R\rtokensPerHour\x12$\n" +
+	"\x0etokens_per_day\x18\a \x01(\x03R\ftokensPerDay\x12(\n" +
+	"\x10tokens_per_month\x18\b \x01(\x03R\x0etokensPerMonth\x12(\n" +
+	"\x10requests_per_day\x18\t \x01(\x03R\x0erequestsPerDay\x126\n" +
+	"\x17max_concurrent_requests\x18\n" +
+	" \x01(\x03R\x15maxConcurrentRequests\x12\x14\n" +
+	"\x05model\x18\v \x01(\tR\x05model\x12!\n" +
+	"\fquota_period\x18\f \x01(\tR\vquotaPeriod\x12\x1f\n" +
+	"\vbilling_day\x18\r \x01(\x05R\n" +
+	"billingDay\"\xb8\a\n" +
+	"\x12PatchLimitsRequest\x12\x15\n" +
+	"\x03rps\x18\x01 \x01(\x05H\x00R\x03rps\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"max_tokens\x18\x02 \x01(\x03H\x01R\tmaxTokens\x88\x01\x01\x128\n" +
+	"\x16max_tokens_per_request\x18\x03 \x01(\x03H\x02R\x13maxTokensPerRequest\x88\x01\x01\x12;\n" +
+	"\x17max_concurrent_requests\x18\x04 \x01(\x03H\x03R\x15maxConcurrentRequests\x88\x01\x01\x12/\n" +
+	"\x11tokens_per_minute\x18\x05 \x01(\x03H\x04R\x0ftokensPerMinute\x88\x01\x01\x12+\n" +
+	"\x0ftokens_per_hour\x18\x06 \x01(\x03H\x05R\rtokensPerHour\x88\x01\x01\x12)\n" +
+	"\x0etokens_per_day\x18\a \x01(\x03H\x06R\ftokensPerDay\x88\x01\x01\x12-\n" +
+	"\x10tokens_per_month\x18\b \x01(\x03H\aR\x0etokensPerMonth\x88\x01\x01\x12-\n" +
+	"\x10requests_per_day\x18\t \x01(\x03H\bR\x0erequestsPerDay\x88\x01\x01\x12&\n" +
+	"\fquota_period\x18\n" +
+	" \x01(\tH\tR\vquotaPeriod\x88\x01\x01\x12$\n" +
+	"\vbilling_day\x18\v \x01(\x05H\n" +
+	"R\n" +
+	"billingDay\x88\x01\x01\x12\x1c\n" +
+	"\tunlimited\x18\f \x03(\tR\tunlimited\x12\x1f\n" +
+	"\vreset_usage\x18\r \x01(\bR\n" +
+	"resetUsage\x12<\n" +
+	"\x18stream_tokens_per_second\x18\x0e \x01(\x03H\vR\x15streamTokensPerSecond\x88\x01\x01\x123\n" +
+	"\x13max_compute_seconds\x18\x0f \x01(\x03H\fR\x11maxComputeSeconds\x88\x01\x01B\x06\n" +
+	"\x04_rpsB\r\n" +
+	"\v_max_tokensB\x19\n" +
+	"\x17_max_tokens_per_requestB\x1a\n" +
+	"\x18_max_concurrent_requestsB\x14\n" +
+	"\x12_tokens_per_minuteB\x12\n" +
+	"\x10_tokens_per_hourB\x11\n" +
+	"\x0f_tokens_per_dayB\x13\n" +
+	"\x11_tokens_per_monthB\x13\n" +
+	"\x11_requests_per_dayB\x0f\n" +
+	"\r_quota_periodB\x0e\n" +
+	"\f_billing_dayB\x1b\n" +
+	"\x19_stream_tokens_per_secondB\x16\n" +
+	"\x14_max_compute_seconds\"a\n" +
 	"\x12SuspendUserRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\"F\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x1a\n" +
+	"\bduration\x18\x03 \x01(\tR\bduration\"\x84\x01\n" +
+	"\x0eSuspensionInfo\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\x12!\n" +
+	"\fsuspended_by\x18\x02 \x01(\tR\vsuspendedBy\x12!\n" +
+	"\fsuspended_at\x18\x03 \x01(\tR\vsuspendedAt\x12\x14\n" +
+	"\x05until\x18\x04 \x01(\tR\x05until\"\x80\x01\n" +
 	"\x13SuspendUserResponse\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
-	"\x06status\x18\x02 \x01(\tR\x06status\"\x8a\x01\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x128\n" +
+	"\n" +
+	"suspension\x18\x03 \x01(\v2\x18.proxy.v1.SuspensionInfoR\n" +
+	"suspension\"/\n" +
+	"\x14UnsuspendUserRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"H\n" +
+	"\x15UnsuspendUserResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\"\xc0\x04\n" +
+	"\x04Plan\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03rps\x18\x02 \x01(\x05R\x03rps\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x03 \x01(\x03R\tmaxTokens\x123\n" +
+	"\x16max_tokens_per_request\x18\x04 \x01(\x03R\x13maxTokensPerRequest\x126\n" +
+	"\x17max_concurrent_requests\x18\x05 \x01(\x03R\x15maxConcurrentRequests\x12*\n" +
+	"\x11tokens_per_minute\x18\x06 \x01(\x03R\x0ftokensPerMinute\x12&\n" +
+	"\x0ftokens_per_hour\x18\a \x01(\x03R\rtokensPerHour\x12$\n" +
+	"\x0etokens_per_day\x18\b \x01(\x03R\ftokensPerDay\x12(\n" +
+	"\x10tokens_per_month\x18\t \x01(\x03R\x0etokensPerMonth\x12(\n" +
+	"\x10requests_per_day\x18\n" +
+	" \x01(\x03R\x0erequestsPerDay\x12\x14\n" +
+	"\x05users\x18\v \x01(\x05R\x05users\x12!\n" +
+	"\fquota_period\x18\f \x01(\tR\vquotaPeriod\x12\x16\n" +
+	"\x06weight\x18\r \x01(\x05R\x06weight\x127\n" +
+	"\x18stream_tokens_per_second\x18\x0e \x01(\x03R\x15streamTokensPerSecond\x12.\n" +
+	"\x13max_compute_seconds\x18\x0f \x01(\x03R\x11maxComputeSeconds\"Z\n" +
+	"\x0fSetPlanResponse\x12\"\n" +
+	"\x04plan\x18\x01 \x01(\v2\x0e.proxy.v1.PlanR\x04plan\x12#\n" +
+	"\rusers_updated\x18\x02 \x01(\x05R\fusersUpdated\"X\n" +
+	"\rPlansResponse\x12$\n" +
+	"\x05plans\x18\x01 \x03(\v2\x0e.proxy.v1.PlanR\x05plans\x12!\n" +
+	"\fdefault_plan\x18\x02 \x01(\tR\vdefaultPlan\"i\n" +
+	"\x11AssignPlanRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04plan\x18\x02 \x01(\tR\x04plan\x12'\n" +
+	"\x0fclear_overrides\x18\x03 \x01(\bR\x0eclearOverrides\"A\n" +
+	"\x12AssignPlanResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04plan\x18\x02 \x01(\tR\x04plan\"\xed\x05\n" +
+	"\x03Org\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03rps\x18\x02 \x01(\x05R\x03rps\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x03 \x01(\x03R\tmaxTokens\x12*\n" +
+	"\x11tokens_per_minute\x18\x04 \x01(\x03R\x0ftokensPerMinute\x12&\n" +
+	"\x0ftokens_per_hour\x18\x05 \x01(\x03R\rtokensPerHour\x12$\n" +
+	"\x0etokens_per_day\x18\x06 \x01(\x03R\ftokensPerDay\x12(\n" +
+	"\x10tokens_per_month\x18\a \x01(\x03R\x0etokensPerMonth\x12(\n" +
+	"\x10requests_per_day\x18\b \x01(\x03R\x0erequestsPerDay\x12!\n" +
+	"\fquota_period\x18\t \x01(\tR\vquotaPeriod\x12\x1f\n" +
+	"\vbilling_day\x18\n" +
+	" \x01(\x05R\n" +
+	"billingDay\x12\x18\n" +
+	"\amembers\x18\v \x03(\tR\amembers\x12+\n" +
+	"\x05quota\x18\f \x01(\v2\x15.proxy.v1.QuotaStatusR\x05quota\x12'\n" +
+	"\x0freserved_tokens\x18\r \x01(\x03R\x0ereservedTokens\x12A\n" +
+	"\fwindow_usage\x18\x0e \x03(\v2\x1e.proxy.v1.Org.WindowUsageEntryR\vwindowUsage\x12E\n" +
+	"\x0eusage_by_model\x18\x0f \x03(\v2\x1f.proxy.v1.Org.UsageByModelEntryR\fusageByModel\x1a>\n" +
+	"\x10WindowUsageEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aU\n" +
+	"\x11UsageByModelEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12*\n" +
+	"\x05value\x18\x02 \x01(\v2\x14.proxy.v1.ModelUsageR\x05value:\x028\x01\"1\n" +
+	"\fOrgsResponse\x12!\n" +
+	"\x04orgs\x18\x01 \x03(\v2\r.proxy.v1.OrgR\x04orgs\">\n" +
+	"\x11SetUserOrgRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x10\n" +
+	"\x03org\x18\x02 \x01(\tR\x03org\"?\n" +
+	"\x12SetUserOrgResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x10\n" +
+	"\x03org\x18\x02 \x01(\tR\x03org\"I\n" +
+	"\x12SetPriorityRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1a\n" +
+	"\bpriority\x18\x02 \x01(\tR\bpriority\"J\n" +
+	"\x13SetPriorityResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1a\n" +
+	"\bpriority\x18\x02 \x01(\tR\bpriority\"J\n" +
+	"\x17SetAllowedModelsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06models\x18\x02 \x03(\tR\x06models\"K\n" +
+	"\x18SetAllowedModelsResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06models\x18\x02 \x03(\tR\x06models\"\x8f\x01\n" +
+	"\x0eModelLimitInfo\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x01 \x01(\x03R\tmaxTokens\x12+\n" +
+	"\x12max_tokens_per_req\x18\x02 \x01(\x03R\x0fmaxTokensPerReq\x12\x1f\n" +
+	"\vused_tokens\x18\x03 \x01(\x03R\n" +
+	"usedTokens\x12\x10\n" +
+	"\x03rps\x18\x04 \x01(\x01R\x03rps\"\xf6\a\n" +
 	"\tLimitInfo\x12\x1d\n" +
 	"\n" +
 	"max_tokens\x18\x01 \x01(\x03R\tmaxTokens\x12+\n" +
 	"\x12max_tokens_per_req\x18\x02 \x01(\x03R\x0fmaxTokensPerReq\x12\x1f\n" +
 	"\vused_tokens\x18\x03 \x01(\x03R\n" +
 	"usedTokens\x12\x10\n" +
-	"\x03rps\x18\x04 \x01(\x01R\x03rps\"\xa4\x01\n" +
+	"\x03rps\x18\x04 \x01(\x01R\x03rps\x12%\n" +
+	"\x0eallowed_models\x18\x05 \x03(\tR\rallowedModels\x12*\n" +
+	"\x11tokens_per_minute\x18\x06 \x01(\x03R\x0ftokensPerMinute\x12&\n" +
+	"\x0ftokens_per_hour\x18\a \x01(\x03R\rtokensPerHour\x12$\n" +
+	"\x0etokens_per_day\x18\b \x01(\x03R\ftokensPerDay\x12(\n" +
+	"\x10tokens_per_month\x18\t \x01(\x03R\x0etokensPerMonth\x12(\n" +
+	"\x10requests_per_day\x18\n" +
+	" \x01(\x03R\x0erequestsPerDay\x12G\n" +
+	"\fwindow_usage\x18\v \x03(\v2$.proxy.v1.LimitInfo.WindowUsageEntryR\vwindowUsage\x126\n" +
+	"\x17max_concurrent_requests\x18\f \x01(\x03R\x15maxConcurrentRequests\x12\x1b\n" +
+	"\tin_flight\x18\r \x01(\x03R\binFlight\x127\n" +
+	"\x06models\x18\x0e \x03(\v2\x1f.proxy.v1.LimitInfo.ModelsEntryR\x06models\x12'\n" +
+	"\x0freserved_tokens\x18\x0f \x01(\x03R\x0ereservedTokens\x12\x12\n" +
+	"\x04plan\x18\x10 \x01(\tR\x04plan\x128\n" +
+	"\n" +
+	"suspension\x18\x11 \x01(\v2\x18.proxy.v1.SuspensionInfoR\n" +
+	"suspension\x12+\n" +
+	"\x05quota\x18\x12 \x01(\v2\x15.proxy.v1.QuotaStatusR\x05quota\x12\x1a\n" +
+	"\bpriority\x18\x13 \x01(\tR\bpriority\x12\x10\n" +
+	"\x03org\x18\x14 \x01(\tR\x03org\x127\n" +
+	"\x18stream_tokens_per_second\x18\x15 \x01(\x03R\x15streamTokensPerSecond\x1a>\n" +
+	"\x10WindowUsageEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aS\n" +
+	"\vModelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12.\n" +
+	"\x05value\x18\x02 \x01(\v2\x18.proxy.v1.ModelLimitInfoR\x05value:\x028\x01\"\x91\x03\n" +
+	"\vQuotaStatus\x12\x16\n" +
+	"\x06period\x18\x01 \x01(\tR\x06period\x12\x1f\n" +
+	"\vbilling_day\x18\x02 \x01(\x05R\n" +
+	"billingDay\x12!\n" +
+	"\fperiod_start\x18\x03 \x01(\tR\vperiodStart\x12\x1d\n" +
+	"\n" +
+	"period_end\x18\x04 \x01(\tR\tperiodEnd\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x05 \x01(\x03R\tmaxTokens\x12\x1f\n" +
+	"\vused_tokens\x18\x06 \x01(\x03R\n" +
+	"usedTokens\x12)\n" +
+	"\x10remaining_tokens\x18\a \x01(\x03R\x0fremainingTokens\x12.\n" +
+	"\x13max_compute_seconds\x18\b \x01(\x03R\x11maxComputeSeconds\x120\n" +
+	"\x14used_compute_seconds\x18\t \x01(\x01R\x12usedComputeSeconds\x12:\n" +
+	"\x19remaining_compute_seconds\x18\n" +
+	" \x01(\x01R\x17remainingComputeSeconds\"\xa4\x01\n" +
 	"\x11AllLimitsResponse\x12?\n" +
 	"\x06limits\x18\x01 \x03(\v2'.proxy.v1.AllLimitsResponse.LimitsEntryR\x06limits\x1aN\n" +
 	"\vLimitsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12)\n" +
-	"\x05value\x18\x02 \x01(\v2\x13.proxy.v1.LimitInfoR\x05value:\x028\x01\"^\n" +
+	"\x05value\x18\x02 \x01(\v2\x13.proxy.v1.LimitInfoR\x05value:\x028\x01\"\x91\x04\n" +
+	"\x0eUpstreamStatus\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x18\n" +
+	"\ahealthy\x18\x02 \x01(\bR\ahealthy\x12\x1b\n" +
+	"\tin_flight\x18\x03 \x01(\x03R\binFlight\x12\x16\n" +
+	"\x06routes\x18\x04 \x03(\tR\x06routes\x12\x1a\n" +
+	"\battempts\x18\x05 \x01(\x03R\battempts\x12\x1a\n" +
+	"\bfailures\x18\x06 \x01(\x03R\bfailures\x12%\n" +
+	"\x0emax_concurrent\x18\a \x01(\x03R\rmaxConcurrent\x12\x16\n" +
+	"\x06queued\x18\b \x01(\x03R\x06queued\x12%\n" +
+	"\x0equeue_admitted\x18\t \x01(\x03R\rqueueAdmitted\x12)\n" +
+	"\x11avg_queue_wait_ms\x18\n" +
+	" \x01(\x01R\x0eavgQueueWaitMs\x12)\n" +
+	"\x11max_queue_wait_ms\x18\v \x01(\x01R\x0emaxQueueWaitMs\x129\n" +
+	"\x05lanes\x18\f \x03(\v2#.proxy.v1.UpstreamStatus.LanesEntryR\x05lanes\x12\x1a\n" +
+	"\bpromoted\x18\r \x01(\x03R\bpromoted\x1aS\n" +
+	"\n" +
+	"LanesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12/\n" +
+	"\x05value\x18\x02 \x01(\v2\x19.proxy.v1.QueueLaneStatusR\x05value:\x028\x01\"\x85\x01\n" +
+	"\x0fQueueLaneStatus\x12\x16\n" +
+	"\x06queued\x18\x01 \x01(\x03R\x06queued\x12\x1a\n" +
+	"\badmitted\x18\x02 \x01(\x03R\badmitted\x12\x1e\n" +
+	"\vavg_wait_ms\x18\x03 \x01(\x01R\tavgWaitMs\x12\x1e\n" +
+	"\vmax_wait_ms\x18\x04 \x01(\x01R\tmaxWaitMs\"K\n" +
+	"\x11UpstreamsResponse\x126\n" +
+	"\tupstreams\x18\x01 \x03(\v2\x18.proxy.v1.UpstreamStatusR\tupstreams\"\x8e\x03\n" +
 	"\n" +
 	"ModelUsage\x12#\n" +
 	"\rprompt_tokens\x18\x01 \x01(\x05R\fpromptTokens\x12+\n" +
-	"\x11completion_tokens\x18\x02 \x01(\x05R\x10completionTokens\"\xb7\x01\n" +
+	"\x11completion_tokens\x18\x02 \x01(\x05R\x10completionTokens\x12)\n" +
+	"\x10embedding_tokens\x18\x03 \x01(\x05R\x0fembeddingTokens\x12)\n" +
+	"\x10aborted_requests\x18\x04 \x01(\x05R\x0fabortedRequests\x12%\n" +
+	"\x0eaborted_tokens\x18\x05 \x01(\x05R\rabortedTokens\x12-\n" +
+	"\x12estimated_requests\x18\x06 \x01(\x05R\x11estimatedRequests\x12)\n" +
+	"\x10estimated_tokens\x18\a \x01(\x05R\x0festimatedTokens\x12'\n" +
+	"\x0fcompute_seconds\x18\b \x01(\x01R\x0ecomputeSeconds\x12.\n" +
+	"\x13wall_clock_requests\x18\t \x01(\x05R\x11wallClockRequests\"\xe4\x01\n" +
 	"\rUsageResponse\x12O\n" +
-	"\x0eusage_by_model\x18\x01 \x03(\v2).proxy.v1.UsageResponse.UsageByModelEntryR\fusageByModel\x1aU\n" +
+	"\x0eusage_by_model\x18\x01 \x03(\v2).proxy.v1.UsageResponse.UsageByModelEntryR\fusageByModel\x12+\n" +
+	"\x05quota\x18\x02 \x01(\v2\x15.proxy.v1.QuotaStatusR\x05quota\x1aU\n" +
 	"\x11UsageByModelEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12*\n" +
-	"\x05value\x18\x02 \x01(\v2\x14.proxy.v1.ModelUsageR\x05value:\x028\x01\"\xbc\x01\n" +
+	"\x05value\x18\x02 \x01(\v2\x14.proxy.v1.ModelUsageR\x05value:\x028\x01\"\xe2\x02\n" +
 	"\x10AllUsageResponse\x12O\n" +
-	"\rusage_by_user\x18\x01 \x03(\v2+.proxy.v1.AllUsageResponse.UsageByUserEntryR\vusageByUser\x1aW\n" +
+	"\rusage_by_user\x18\x01 \x03(\v2+.proxy.v1.AllUsageResponse.UsageByUserEntryR\vusageByUser\x12L\n" +
+	"\fusage_by_org\x18\x02 \x03(\v2*.proxy.v1.AllUsageResponse.UsageByOrgEntryR\n" +
+	"usageByOrg\x1aW\n" +
 	"\x10UsageByUserEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12-\n" +
+	"\x05value\x18\x02 \x01(\v2\x17.proxy.v1.UsageResponseR\x05value:\x028\x01\x1aV\n" +
+	"\x0fUsageByOrgEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12-\n" +
 	"\x05value\x18\x02 \x01(\v2\x17.proxy.v1.UsageResponseR\x05value:\x028\x01\";\n" +
 	"\vChatMessage\x12\x12\n" +
 	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
@@ -819,38 +3099,85 @@ func file_api_proto_rawDescGZIP() []byte {
 	return file_api_proto_rawDescData
 }
 
-var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 44)
 var file_api_proto_goTypes = []any{
-	(*LoginRequest)(nil),          // 0: proxy.v1.LoginRequest
-	(*LoginResponse)(nil),         // 1: proxy.v1.LoginResponse
-	(*SetLimitsRequest)(nil),      // 2: proxy.v1.SetLimitsRequest
-	(*SetLimitsResponse)(nil),     // 3: proxy.v1.SetLimitsResponse
-	(*SuspendUserRequest)(nil),    // 4: proxy.v1.SuspendUserRequest
-	(*SuspendUserResponse)(nil),   // 5: proxy.v1.SuspendUserResponse
-	(*LimitInfo)(nil),             // 6: proxy.v1.LimitInfo
-	(*AllLimitsResponse)(nil),     // 7: proxy.v1.AllLimitsResponse
-	(*ModelUsage)(nil),            // 8: proxy.v1.ModelUsage
-	(*UsageResponse)(nil),         // 9: proxy.v1.UsageResponse
-	(*AllUsageResponse)(nil),      // 10: proxy.v1.AllUsageResponse
-	(*ChatMessage)(nil),           // 11: proxy.v1.ChatMessage
-	(*ChatCompletionRequest)(nil), // 12: proxy.v1.ChatCompletionRequest
-	nil,                           // 13: proxy.v1.AllLimitsResponse.LimitsEntry
-	nil,                           // 14: proxy.v1.UsageResponse.UsageByModelEntry
-	nil,                           // 15: proxy.v1.AllUsageResponse.UsageByUserEntry
+	(*LoginRequest)(nil),             // 0: proxy.v1.LoginRequest
+	(*LoginResponse)(nil),            // 1: proxy.v1.LoginResponse
+	(*SetLimitsRequest)(nil),         // 2: proxy.v1.SetLimitsRequest
+	(*SetLimitsResponse)(nil),        // 3: proxy.v1.SetLimitsResponse
+	(*PatchLimitsRequest)(nil),       // 4: proxy.v1.PatchLimitsRequest
+	(*SuspendUserRequest)(nil),       // 5: proxy.v1.SuspendUserRequest
+	(*SuspensionInfo)(nil),           // 6: proxy.v1.SuspensionInfo
+	(*SuspendUserResponse)(nil),      // 7: proxy.v1.SuspendUserResponse
+	(*UnsuspendUserRequest)(nil),     // 8: proxy.v1.UnsuspendUserRequest
+	(*UnsuspendUserResponse)(nil),    // 9: proxy.v1.UnsuspendUserResponse
+	(*Plan)(nil),                     // 10: proxy.v1.Plan
+	(*SetPlanResponse)(nil),          // 11: proxy.v1.SetPlanResponse
+	(*PlansResponse)(nil),            // 12: proxy.v1.PlansResponse
+	(*AssignPlanRequest)(nil),        // 13: proxy.v1.AssignPlanRequest
+	(*AssignPlanResponse)(nil),       // 14: proxy.v1.AssignPlanResponse
+	(*Org)(nil),                      // 15: proxy.v1.Org
+	(*OrgsResponse)(nil),             // 16: proxy.v1.OrgsResponse
+	(*SetUserOrgRequest)(nil),        // 17: proxy.v1.SetUserOrgRequest
+	(*SetUserOrgResponse)(nil),       // 18: proxy.v1.SetUserOrgResponse
+	(*SetPriorityRequest)(nil),       // 19: proxy.v1.SetPriorityRequest
+	(*SetPriorityResponse)(nil),      // 20: proxy.v1.SetPriorityResponse
+	(*SetAllowedModelsRequest)(nil),  // 21: proxy.v1.SetAllowedModelsRequest
+	(*SetAllowedModelsResponse)(nil), // 22: proxy.v1.SetAllowedModelsResponse
+	(*ModelLimitInfo)(nil),           // 23: proxy.v1.ModelLimitInfo
+	(*LimitInfo)(nil),                // 24: proxy.v1.LimitInfo
+	(*QuotaStatus)(nil),              // 25: proxy.v1.QuotaStatus
+	(*AllLimitsResponse)(nil),        // 26: proxy.v1.AllLimitsResponse
+	(*UpstreamStatus)(nil),           // 27: proxy.v1.UpstreamStatus
+	(*QueueLaneStatus)(nil),          // 28: proxy.v1.QueueLaneStatus
+	(*UpstreamsResponse)(nil),        // 29: proxy.v1.UpstreamsResponse
+	(*ModelUsage)(nil),               // 30: proxy.v1.ModelUsage
+	(*UsageResponse)(nil),            // 31: proxy.v1.UsageResponse
+	(*AllUsageResponse)(nil),         // 32: proxy.v1.AllUsageResponse
+	(*ChatMessage)(nil),              // 33: proxy.v1.ChatMessage
+	(*ChatCompletionRequest)(nil),    // 34: proxy.v1.ChatCompletionRequest
+	nil,                              // 35: proxy.v1.Org.WindowUsageEntry
+	nil,                              // 36: proxy.v1.Org.UsageByModelEntry
+	nil,                              // 37: proxy.v1.LimitInfo.WindowUsageEntry
+	nil,                              // 38: proxy.v1.LimitInfo.ModelsEntry
+	nil,                              // 39: proxy.v1.AllLimitsResponse.LimitsEntry
+	nil,                              // 40: proxy.v1.UpstreamStatus.LanesEntry
+	nil,                              // 41: proxy.v1.UsageResponse.UsageByModelEntry
+	nil,                              // 42: proxy.v1.AllUsageResponse.UsageByUserEntry
+	nil,                              // 43: proxy.v1.AllUsageResponse.UsageByOrgEntry
 }
 var file_api_proto_depIdxs = []int32{
-	13, // 0: proxy.v1.AllLimitsResponse.limits:type_name -> proxy.v1.AllLimitsResponse.LimitsEntry
-	14, // 1: proxy.v1.UsageResponse.usage_by_model:type_name -> proxy.v1.UsageResponse.UsageByModelEntry
-	15, // 2: proxy.v1.AllUsageResponse.usage_by_user:type_name -> proxy.v1.AllUsageResponse.UsageByUserEntry
-	11, // 3: proxy.v1.ChatCompletionRequest.messages:type_name -> proxy.v1.ChatMessage
-	6,  // 4: proxy.v1.AllLimitsResponse.LimitsEntry.value:type_name -> proxy.v1.LimitInfo
-	8,  // 5: proxy.v1.UsageResponse.UsageByModelEntry.value:type_name -> proxy.v1.ModelUsage
-	9,  // 6: proxy.v1.AllUsageResponse.UsageByUserEntry.value:type_name -> proxy.v1.UsageResponse
-	7,  // [7:7] is the sub-list for method output_type
-	7,  // [7:7] is the sub-list for method input_type
-	7,  // [7:7] is the sub-list for extension type_name
-	7,  // [7:7] is the sub-list for extension extendee
-	0,  // [0:7] is the sub-list for field type_name
+	6,  // 0: proxy.v1.SuspendUserResponse.suspension:type_name -> proxy.v1.SuspensionInfo
+	10, // 1: proxy.v1.SetPlanResponse.plan:type_name -> proxy.v1.Plan
+	10, // 2: proxy.v1.PlansResponse.plans:type_name -> proxy.v1.Plan
+	25, // 3: proxy.v1.Org.quota:type_name -> proxy.v1.QuotaStatus
+	35, // 4: proxy.v1.Org.window_usage:type_name -> proxy.v1.Org.WindowUsageEntry
+	36, // 5: proxy.v1.Org.usage_by_model:type_name -> proxy.v1.Org.UsageByModelEntry
+	15, // 6: proxy.v1.OrgsResponse.orgs:type_name -> proxy.v1.Org
+	37, // 7: proxy.v1.LimitInfo.window_usage:type_name -> proxy.v1.LimitInfo.WindowUsageEntry
+	38, // 8: proxy.v1.LimitInfo.models:type_name -> proxy.v1.LimitInfo.ModelsEntry
+	6,  // 9: proxy.v1.LimitInfo.suspension:type_name -> proxy.v1.SuspensionInfo
+	25, // 10: proxy.v1.LimitInfo.quota:type_name -> proxy.v1.QuotaStatus
+	39, // 11: proxy.v1.AllLimitsResponse.limits:type_name -> proxy.v1.AllLimitsResponse.LimitsEntry
+	40, // 12: proxy.v1.UpstreamStatus.lanes:type_name -> proxy.v1.UpstreamStatus.LanesEntry
+	27, // 13: proxy.v1.UpstreamsResponse.upstreams:type_name -> proxy.v1.UpstreamStatus
+	41, // 14: proxy.v1.UsageResponse.usage_by_model:type_name -> proxy.v1.UsageResponse.UsageByModelEntry
+	25, // 15: proxy.v1.UsageResponse.quota:type_name -> proxy.v1.QuotaStatus
+	42, // 16: proxy.v1.AllUsageResponse.usage_by_user:type_name -> proxy.v1.AllUsageResponse.UsageByUserEntry
+	43, // 17: proxy.v1.AllUsageResponse.usage_by_org:type_name -> proxy.v1.AllUsageResponse.UsageByOrgEntry
+	33, // 18: proxy.v1.ChatCompletionRequest.messages:type_name -> proxy.v1.ChatMessage
+	30, // 19: proxy.v1.Org.UsageByModelEntry.value:type_name -> proxy.v1.ModelUsage
+	23, // 20: proxy.v1.LimitInfo.ModelsEntry.value:type_name -> proxy.v1.ModelLimitInfo
+	24, // 21: proxy.v1.AllLimitsResponse.LimitsEntry.value:type_name -> proxy.v1.LimitInfo
+	28, // 22: proxy.v1.UpstreamStatus.LanesEntry.value:type_name -> proxy.v1.QueueLaneStatus
+	30, // 23: proxy.v1.UsageResponse.UsageByModelEntry.value:type_name -> proxy.v1.ModelUsage
+	31, // 24: proxy.v1.AllUsageResponse.UsageByUserEntry.value:type_name -> proxy.v1.UsageResponse
+	31, // 25: proxy.v1.AllUsageResponse.UsageByOrgEntry.value:type_name -> proxy.v1.UsageResponse
+	26, // [26:26] is the sub-list for method output_type
+	26, // [26:26] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_init() }
@@ -858,13 +3185,14 @@ func file_api_proto_init() {
 	if File_api_proto != nil {
 		return
 	}
+	file_api_proto_msgTypes[4].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_rawDesc), len(file_api_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   16,
+			NumMessages:   44,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
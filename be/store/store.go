@@ -3,38 +3,133 @@ package store
 import (
 	"lb/users"
 	"sync"
+	"time"
 )
 
 // ModelUsage tracks token usage for one model.
+// Aborted and estimated requests are included in the prompt/completion totals
+// (they are billed) and additionally counted in the Aborted*/Estimated* fields.
 type ModelUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
+	PromptTokens      int `json:"prompt_tokens"`
+	CompletionTokens  int `json:"completion_tokens"`
+	EmbeddingTokens   int `json:"embedding_tokens"`
+	AbortedRequests   int `json:"aborted_requests"`
+	AbortedTokens     int `json:"aborted_tokens"`
+	EstimatedRequests int `json:"estimated_requests"`
+	EstimatedTokens   int `json:"estimated_tokens"`
+
+	// Compute is the upstream time spent on the requests: what Ollama reports
+	// when it does, else the wall-clock time the proxy measured. Requests
+	// measured by wall clock are also counted in WallClockRequests.
+	Compute           time.Duration `json:"compute_ns"`
+	WallClockRequests int           `json:"wall_clock_requests"`
+}
+
+// Record is the accounted usage of one request.
+type Record struct {
+	PromptTokens     int
+	CompletionTokens int
+	// Aborted marks a response that was cut off before completion (e.g. the
+	// client disconnected mid-stream). Its tokens are a best-effort estimate of
+	// what was generated up to that point.
+	Aborted bool
+	// Estimated marks counts approximated by the proxy because the upstream
+	// reported no usage, as opposed to exact upstream counts.
+	Estimated bool
 }
 
 // Store is a thread-safe in-memory usage store.
 type Store struct {
-	mu   sync.Mutex
-	data map[string]map[string]*ModelUsage // user -> model -> usage
+	mu    sync.Mutex
+	data  map[string]map[string]*ModelUsage // user -> model -> usage
+	orgs  map[string]map[string]*ModelUsage // org -> model -> usage
+	orgOf func(user string) string          // see AttributeOrgs; nil = no orgs
 }
 
 func New() *Store {
-	return &Store{data: make(map[string]map[string]*ModelUsage)}
+	return &Store{
+		data: make(map[string]map[string]*ModelUsage),
+		orgs: make(map[string]map[string]*ModelUsage),
+	}
 }
 
-// Add increments token counts for the given user + model.
-func (s *Store) Add(user, model string, prompt, completion int) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if s.data[user] == nil {
-		s.data[user] = make(map[string]*ModelUsage)
+// AttributeOrgs makes every write also count towards the organization orgOf
+// names for the user at the time of the write ("" = none), so an
+// organization keeps the usage of members that later leave it. Call it before
+// recording any usage.
+func (s *Store) AttributeOrgs(orgOf func(user string) string) {
+	s.orgOf = orgOf
+}
+
+// entry returns the usage record for key + model in m. Caller must hold s.mu.
+func entry(m map[string]map[string]*ModelUsage, key, model string) *ModelUsage {
+	if m[key] == nil {
+		m[key] = make(map[string]*ModelUsage)
 	}
-	u := s.data[user][model]
+	u := m[key][model]
 	if u == nil {
 		u = &ModelUsage{}
-		s.data[user][model] = u
+		m[key][model] = u
 	}
-	u.PromptTokens += prompt
-	u.CompletionTokens += completion
+	return u
+}
+
+// update applies f to the usage record for user + model and, if the user
+// belongs to an organization, to the organization's record for model.
+func (s *Store) update(user, model string, f func(u *ModelUsage)) {
+	var org string
+	if s.orgOf != nil {
+		org = s.orgOf(user)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(entry(s.data, user, model))
+	if org != "" {
+		f(entry(s.orgs, org, model))
+	}
+}
+
+// Add increments token counts for the given user + model.
+func (s *Store) Add(user, model string, prompt, completion int) {
+	s.update(user, model, func(u *ModelUsage) {
+		u.PromptTokens += prompt
+		u.CompletionTokens += completion
+	})
+}
+
+// Record adds one request's usage to the ledger for the given user + model.
+func (s *Store) Record(user, model string, r Record) {
+	s.update(user, model, func(u *ModelUsage) {
+		u.PromptTokens += r.PromptTokens
+		u.CompletionTokens += r.CompletionTokens
+		if r.Aborted {
+			u.AbortedRequests++
+			u.AbortedTokens += r.PromptTokens + r.CompletionTokens
+		}
+		if r.Estimated {
+			u.EstimatedRequests++
+			u.EstimatedTokens += r.PromptTokens + r.CompletionTokens
+		}
+	})
+}
+
+// AddEmbedding increments embedding token counts for the given user + model.
+// Embedding responses only report prompt tokens, so they are tracked separately
+// from chat prompt/completion tokens.
+func (s *Store) AddEmbedding(user, model string, tokens int) {
+	s.update(user, model, func(u *ModelUsage) { u.EmbeddingTokens += tokens })
+}
+
+// AddCompute adds the upstream time one request took for the given user +
+// model. wallClock marks time measured by the proxy rather than reported by
+// the upstream.
+func (s *Store) AddCompute(user, model string, d time.Duration, wallClock bool) {
+	s.update(user, model, func(u *ModelUsage) {
+		u.Compute += d
+		if wallClock {
+			u.WallClockRequests++
+		}
+	})
 }
 
 // Get returns a copy of usage for the given user, keyed by model.
@@ -64,3 +159,15 @@ func (s *Store) GetAll() map[string]map[string]ModelUsage {
 	}
 	return out
 }
+
+// Org returns a copy of the usage recorded for the given organization,
+// keyed by model: what its members used while they belonged to it.
+func (s *Store) Org(org string) map[string]ModelUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]ModelUsage)
+	for model, u := range s.orgs[org] {
+		out[model] = *u
+	}
+	return out
+}
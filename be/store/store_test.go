@@ -3,6 +3,7 @@ package store_test
 import (
 	"lb/store"
 	"testing"
+	"time"
 )
 
 func TestAdd_And_Get(t *testing.T) {
@@ -45,6 +46,52 @@ func TestAdd_MultipleModels(t *testing.T) {
 	}
 }
 
+func TestAddEmbedding(t *testing.T) {
+	s := store.New()
+	s.Add("user-e", "nomic-embed-text", 4, 0)
+	s.AddEmbedding("user-e", "nomic-embed-text", 12)
+	s.AddEmbedding("user-e", "nomic-embed-text", 8)
+
+	u := s.Get("user-e")["nomic-embed-text"]
+	if u.EmbeddingTokens != 20 {
+		t.Errorf("embedding tokens: got %d, want 20", u.EmbeddingTokens)
+	}
+	if u.PromptTokens != 4 || u.CompletionTokens != 0 {
+		t.Errorf("chat tokens should be untouched, got prompt=%d completion=%d", u.PromptTokens, u.CompletionTokens)
+	}
+}
+
+func TestRecord_Aborted(t *testing.T) {
+	s := store.New()
+	s.Record("user-f", "llama3.2:1b", store.Record{PromptTokens: 10, CompletionTokens: 20})
+	s.Record("user-f", "llama3.2:1b", store.Record{PromptTokens: 5, CompletionTokens: 3, Aborted: true})
+
+	u := s.Get("user-f")["llama3.2:1b"]
+	if u.PromptTokens != 15 || u.CompletionTokens != 23 {
+		t.Errorf("aborted usage should still be billed: got prompt=%d completion=%d", u.PromptTokens, u.CompletionTokens)
+	}
+	if u.AbortedRequests != 1 {
+		t.Errorf("aborted requests: got %d, want 1", u.AbortedRequests)
+	}
+	if u.AbortedTokens != 8 {
+		t.Errorf("aborted tokens: got %d, want 8", u.AbortedTokens)
+	}
+}
+
+func TestRecord_Estimated(t *testing.T) {
+	s := store.New()
+	s.Record("user-g", "llama3.2:1b", store.Record{PromptTokens: 4, CompletionTokens: 6, Estimated: true})
+	s.Record("user-g", "llama3.2:1b", store.Record{PromptTokens: 1, CompletionTokens: 1, Estimated: true, Aborted: true})
+
+	u := s.Get("user-g")["llama3.2:1b"]
+	if u.EstimatedRequests != 2 || u.EstimatedTokens != 12 {
+		t.Errorf("estimated: got %d requests / %d tokens, want 2 / 12", u.EstimatedRequests, u.EstimatedTokens)
+	}
+	if u.AbortedRequests != 1 {
+		t.Errorf("aborted requests: got %d, want 1", u.AbortedRequests)
+	}
+}
+
 func TestGetAll(t *testing.T) {
 	s := store.New()
 	s.Add("user-c", "llama3.2:1b", 1, 1)
@@ -63,6 +110,45 @@ func TestGetAll(t *testing.T) {
 	}
 }
 
+func TestOrg(t *testing.T) {
+	s := store.New()
+	orgs := map[string]string{"user-e": "acme", "user-f": "acme"}
+	s.AttributeOrgs(func(user string) string { return orgs[user] })
+	s.Add("user-e", "llama3.2:1b", 10, 5)
+	s.Record("user-f", "llama3.2:1b", store.Record{PromptTokens: 3, CompletionTokens: 2, Aborted: true})
+	s.AddEmbedding("user-f", "nomic-embed-text", 7)
+	s.Add("user-g", "llama3.2:1b", 100, 100) // in no organization
+
+	got := s.Org("acme")
+	if u := got["llama3.2:1b"]; u.PromptTokens != 13 || u.CompletionTokens != 7 || u.AbortedRequests != 1 {
+		t.Errorf("llama3.2:1b org usage = %+v", u)
+	}
+	if u := got["nomic-embed-text"]; u.EmbeddingTokens != 7 {
+		t.Errorf("nomic-embed-text org usage = %+v", u)
+	}
+}
+
+func TestOrg_MemberMoves(t *testing.T) {
+	s := store.New()
+	orgs := map[string]string{"user-h": "acme"}
+	s.AttributeOrgs(func(user string) string { return orgs[user] })
+	s.Add("user-h", "llama3.2:1b", 10, 5)
+
+	// Usage stays with the organization it was recorded under.
+	orgs["user-h"] = "globex"
+	s.Add("user-h", "llama3.2:1b", 1, 1)
+
+	if u := s.Org("acme")["llama3.2:1b"]; u.PromptTokens != 10 || u.CompletionTokens != 5 {
+		t.Errorf("acme = %+v, want 10 and 5 from before the move", u)
+	}
+	if u := s.Org("globex")["llama3.2:1b"]; u.PromptTokens != 1 || u.CompletionTokens != 1 {
+		t.Errorf("globex = %+v, want only the 1 and 1 since the move", u)
+	}
+	if u := s.Get("user-h")["llama3.2:1b"]; u.PromptTokens != 11 {
+		t.Errorf("user-h prompt tokens = %d, want all 11", u.PromptTokens)
+	}
+}
+
 func TestAdd_ThreadSafe(t *testing.T) {
 	s := store.New()
 	done := make(chan struct{})
@@ -81,3 +167,20 @@ func TestAdd_ThreadSafe(t *testing.T) {
 		t.Errorf("expected 100 prompt tokens, got %d", usage["llama3.2:1b"].PromptTokens)
 	}
 }
+
+func TestAddCompute(t *testing.T) {
+	s := store.New()
+	s.AddCompute("user-h", "moondream", 1500*time.Millisecond, false)
+	s.AddCompute("user-h", "moondream", 250*time.Millisecond, true)
+
+	u := s.Get("user-h")["moondream"]
+	if u.Compute != 1750*time.Millisecond {
+		t.Errorf("compute: got %s, want 1.75s", u.Compute)
+	}
+	if u.WallClockRequests != 1 {
+		t.Errorf("wall-clock requests: got %d, want 1", u.WallClockRequests)
+	}
+	if u.PromptTokens != 0 {
+		t.Errorf("tokens should be untouched, got prompt=%d", u.PromptTokens)
+	}
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"fmt"
 	"html/template"
 	"lb/auth"
 	"lb/limiter"
@@ -57,17 +58,25 @@ const rawDashboardTemplate = `<!DOCTYPE html>
 <body>
 <div id="toast" class="toast"></div>
 <script>
-function suspend(userId) {
+function adminPost(path, body, okMsg) {
   const key = document.cookie.split('; ').find(r=>r.startsWith('admin_key='))?.split('=')[1]
     || prompt('Admin API key:');
   if (!key) return;
-  fetch('/admin/suspend', {
+  fetch(path, {
     method: 'POST',
     headers: {'Content-Type':'application/json','Authorization':'Bearer '+key},
-    body: JSON.stringify({user_id: userId})
-  }).then(r => showToast(r.ok ? 'User suspended: '+userId : 'Error '+r.status, r.ok))
+    body: JSON.stringify(body)
+  }).then(r => showToast(r.ok ? okMsg : 'Error '+r.status, r.ok))
     .catch(e => showToast('Request failed: '+e, false));
 }
+function suspend(userId) {
+  const reason = prompt('Reason for suspending '+userId+':');
+  if (reason === null) return;
+  adminPost('/admin/suspend', {user_id: userId, reason: reason}, 'User suspended: '+userId);
+}
+function unsuspend(userId) {
+  adminPost('/admin/unsuspend', {user_id: userId}, 'User unsuspended: '+userId);
+}
 function showToast(msg, ok) {
   const t = document.getElementById('toast');
   t.className = 'toast ' + (ok ? 'ok' : 'err');
@@ -82,7 +91,7 @@ function showToast(msg, ok) {
 <div class="card">
   <h2>Usage by User &amp; Model</h2>
   <table>
-    <thead><tr><th>User</th><th>Model</th><th>Prompt Tokens</th><th>Completion Tokens</th><th>Total</th></tr></thead>
+    <thead><tr><th>User</th><th>Model</th><th>Prompt Tokens</th><th>Completion Tokens</th><th>Embedding Tokens</th><th>Total</th><th>Compute</th></tr></thead>
     <tbody>
     {{- range $user, $models := .Usage}}
       {{- range $model, $u := $models}}
@@ -91,11 +100,13 @@ function showToast(msg, ok) {
         <td>{{$model}}</td>
         <td>{{$u.PromptTokens}}</td>
         <td>{{$u.CompletionTokens}}</td>
-        <td>{{add $u.PromptTokens $u.CompletionTokens}}</td>
+        <td>{{$u.EmbeddingTokens}}</td>
+        <td>{{add (add $u.PromptTokens $u.CompletionTokens) $u.EmbeddingTokens}}</td>
+        <td>{{printf "%.1fs" $u.Compute.Seconds}}{{with $u.WallClockRequests}} <span class="inf">{{.}} by wall clock</span>{{end}}</td>
       </tr>
       {{- end}}
     {{- else}}
-      <tr><td colspan="5" style="color:#64748b;text-align:center;padding:1.5rem">No usage recorded yet.</td></tr>
+      <tr><td colspan="7" style="color:#64748b;text-align:center;padding:1.5rem">No usage recorded yet.</td></tr>
     {{- end}}
     </tbody>
   </table>
@@ -104,25 +115,107 @@ function showToast(msg, ok) {
 <div class="card">
   <h2>Rate &amp; Quota Limits</h2>
   <table>
-    <thead><tr><th>User</th><th>RPS Limit</th><th>Token Quota</th><th>Tokens Used</th><th>Remaining</th><th>Actions</th></tr></thead>
+    <thead><tr><th>User</th><th>Plan</th><th>RPS Limit</th><th>Token Quota</th><th>Tokens Used</th><th>Remaining</th><th>In Flight</th><th>Windows</th><th>Actions</th></tr></thead>
     <tbody>
     {{- range $user, $info := .Limits}}
       <tr>
         <td><span class="tag tag-purple">{{$user}}</span></td>
-        <td>{{if eq $info.RPS 0.0}}<span class="inf">∞</span>{{else}}{{printf "%.0f" $info.RPS}}/s{{end}}</td>
-        <td>{{if eq $info.MaxTokens 0}}<span class="inf">∞</span>{{else}}{{$info.MaxTokens}}{{end}}</td>
-        <td>{{$info.UsedTokens}}</td>
+        <td>{{$info.Plan}}{{if eq $info.Priority "batch"}} <span class="inf">batch</span>{{end}}{{with $info.Org}}<div class="inf">org: {{.}}</div>{{end}}{{if gt $info.StreamPace 0}}<div class="inf">paced: {{$info.StreamPace}} tok/s</div>{{end}}</td>
+        <td>{{if eq $info.RPS -1.0}}<span class="inf">∞</span>{{else}}{{printf "%.0f" $info.RPS}}/s{{end}}</td>
+        <td>{{if eq $info.MaxTokens -1}}<span class="inf">∞</span>{{else}}{{$info.MaxTokens}}{{end}}
+          {{- if not $info.Quota.PeriodEnd.IsZero}}<div class="inf">{{$info.Quota.Period}}, resets {{$info.Quota.PeriodEnd.Format "2006-01-02"}}</div>{{end}}</td>
+        <td>{{$info.UsedTokens}}{{if gt $info.ReservedTokens 0}} <span class="inf">+{{$info.ReservedTokens}} reserved</span>{{end}}</td>
         <td>
-          {{- if eq $info.MaxTokens 0}}<span class="inf">∞</span>
+          {{- if eq $info.MaxTokens -1}}<span class="inf">∞</span>
           {{- else}}
             {{remaining $info.MaxTokens $info.UsedTokens}}
             <span class="quota-bar-wrap"><div class="quota-bar" style="width:{{pct $info.UsedTokens $info.MaxTokens}}%"></div></span>
           {{- end}}
         </td>
-        <td><button class="btn-suspend" onclick="suspend('{{$user}}')">Suspend</button></td>
+        <td>{{$info.InFlight}} / {{if eq $info.MaxConcurrent -1}}<span class="inf">∞</span>{{else}}{{$info.MaxConcurrent}}{{end}}</td>
+        <td>
+          {{- range windows $info}}<div>{{.}}</div>{{else}}<span class="inf">∞</span>{{end -}}
+        </td>
+        <td>
+          {{- with $info.Suspension}}
+          <div class="inf" title="by {{.By}} at {{.At.UTC.Format "2006-01-02 15:04"}}">suspended{{if .Reason}}: {{.Reason}}{{end}}{{if not .Until.IsZero}} until {{.Until.UTC.Format "2006-01-02 15:04"}}{{end}}</div>
+          <button class="btn-suspend" onclick="unsuspend('{{$user}}')">Unsuspend</button>
+          {{- else}}
+          <button class="btn-suspend" onclick="suspend('{{$user}}')">Suspend</button>
+          {{- end}}
+        </td>
       </tr>
     {{- else}}
-      <tr><td colspan="5" style="color:#64748b;text-align:center;padding:1.5rem">No limits configured.</td></tr>
+      <tr><td colspan="9" style="color:#64748b;text-align:center;padding:1.5rem">No limits configured.</td></tr>
+    {{- end}}
+    </tbody>
+  </table>
+</div>
+<div class="card">
+  <h2>Plans</h2>
+  <table>
+    <thead><tr><th>Plan</th><th>RPS Limit</th><th>Token Quota</th><th>Per-Request Cap</th><th>Concurrent</th><th>Users</th></tr></thead>
+    <tbody>
+    {{- range .Plans}}
+      <tr>
+        <td><span class="tag tag-purple">{{.Name}}</span>{{if eq .Name $.DefaultPlan}} <span class="inf">default</span>{{end}}</td>
+        <td>{{if eq .Plan.RPS -1}}<span class="inf">∞</span>{{else}}{{.Plan.RPS}}/s{{end}}</td>
+        <td>{{if eq .Plan.MaxTokens -1}}<span class="inf">∞</span>{{else}}{{.Plan.MaxTokens}}{{end}}</td>
+        <td>{{if eq .Plan.MaxTokensPerReq -1}}<span class="inf">∞</span>{{else}}{{.Plan.MaxTokensPerReq}}{{end}}</td>
+        <td>{{if eq .Plan.MaxConcurrent -1}}<span class="inf">∞</span>{{else}}{{.Plan.MaxConcurrent}}{{end}}</td>
+        <td>{{.Users}}</td>
+      </tr>
+    {{- end}}
+    </tbody>
+  </table>
+</div>
+<div class="card">
+  <h2>Organizations</h2>
+  <table>
+    <thead><tr><th>Org</th><th>Members</th><th>RPS Limit</th><th>Token Quota</th><th>Tokens Used</th><th>Remaining</th><th>Usage by Model</th></tr></thead>
+    <tbody>
+    {{- range .Orgs}}
+      <tr>
+        <td><span class="tag tag-purple">{{.Info.Name}}</span></td>
+        <td>{{range $i, $m := .Info.Members}}{{if $i}}, {{end}}{{$m}}{{else}}<span class="inf">none</span>{{end}}</td>
+        <td>{{if eq .Info.Limits.RPS -1}}<span class="inf">∞</span>{{else}}{{.Info.Limits.RPS}}/s{{end}}</td>
+        <td>{{if eq .Info.Quota.MaxTokens -1}}<span class="inf">∞</span>{{else}}{{.Info.Quota.MaxTokens}}{{end}}
+          {{- if not .Info.Quota.PeriodEnd.IsZero}}<div class="inf">{{.Info.Quota.Period}}, resets {{.Info.Quota.PeriodEnd.Format "2006-01-02"}}</div>{{end}}</td>
+        <td>{{.Info.Quota.UsedTokens}}{{if gt .Info.ReservedTokens 0}} <span class="inf">+{{.Info.ReservedTokens}} reserved</span>{{end}}</td>
+        <td>
+          {{- if eq .Info.Quota.MaxTokens -1}}<span class="inf">∞</span>
+          {{- else}}
+            {{.Info.Quota.Remaining}}
+            <span class="quota-bar-wrap"><div class="quota-bar" style="width:{{pct .Info.Quota.UsedTokens .Info.Quota.MaxTokens}}%"></div></span>
+          {{- end}}
+        </td>
+        <td>
+          {{- range $model, $u := .Usage}}<div>{{$model}}: {{add (add $u.PromptTokens $u.CompletionTokens) $u.EmbeddingTokens}}</div>{{else}}<span class="inf">none</span>{{end -}}
+        </td>
+      </tr>
+    {{- else}}
+      <tr><td colspan="7" style="color:#64748b;text-align:center;padding:1.5rem">No organizations configured.</td></tr>
+    {{- end}}
+    </tbody>
+  </table>
+</div>
+<div class="card">
+  <h2>Per-Model Limits</h2>
+  <table>
+    <thead><tr><th>User</th><th>Model</th><th>RPS Limit</th><th>Token Quota</th><th>Tokens Used</th><th>Per-Request Cap</th></tr></thead>
+    <tbody>
+    {{- range $user, $info := .Limits}}
+      {{- range $model, $m := $info.Models}}
+      <tr>
+        <td><span class="tag tag-purple">{{$user}}</span></td>
+        <td>{{$model}}</td>
+        <td>{{if eq $m.RPS 0.0}}<span class="inf">user-wide</span>{{else if eq $m.RPS -1.0}}<span class="inf">∞</span>{{else}}{{printf "%.0f" $m.RPS}}/s{{end}}</td>
+        <td>{{if eq $m.MaxTokens 0}}<span class="inf">user-wide</span>{{else if eq $m.MaxTokens -1}}<span class="inf">∞</span>{{else}}{{$m.MaxTokens}}
+          <span class="quota-bar-wrap"><div class="quota-bar" style="width:{{pct $m.UsedTokens $m.MaxTokens}}%"></div></span>{{end}}</td>
+        <td>{{$m.UsedTokens}}</td>
+        <td>{{if eq $m.MaxTokensPerReq 0}}<span class="inf">user-wide</span>{{else if eq $m.MaxTokensPerReq -1}}<span class="inf">∞</span>{{else}}{{$m.MaxTokensPerReq}}{{end}}</td>
+      </tr>
+      {{- end}}
     {{- end}}
     </tbody>
   </table>
@@ -132,8 +225,17 @@ function showToast(msg, ok) {
 </html>`
 
 type dashboardData struct {
-	Usage  map[string]map[string]store.ModelUsage
-	Limits map[string]limiter.LimitInfo
+	Usage       map[string]map[string]store.ModelUsage
+	Limits      map[string]limiter.LimitInfo
+	Plans       []limiter.PlanInfo
+	DefaultPlan string
+	Orgs        []orgRow
+}
+
+// orgRow is an organization with the usage recorded for it.
+type orgRow struct {
+	Info  limiter.OrgInfo
+	Usage map[string]store.ModelUsage
 }
 
 // Dashboard handles GET /admin/ui — renders a live usage + limits overview.
@@ -146,14 +248,40 @@ func Dashboard(s *store.Store, lim *limiter.Limiter) echo.HandlerFunc {
 			}
 			return 0
 		},
+		// pct is used as a share of max, clamped to 0-100; an unlimited or
+		// zero max shows an empty bar.
 		"pct": func(used, max int64) int64 {
-			if max == 0 {
+			if max <= 0 {
 				return 0
 			}
-			if p := used * 100 / max; p <= 100 {
+			switch p := used * 100 / max; {
+			case p < 0:
+				return 0
+			case p > 100:
+				return 100
+			default:
 				return p
 			}
-			return 100
+		},
+		// windows lists the user's capped sliding windows as "name used/limit".
+		"windows": func(info limiter.LimitInfo) []string {
+			caps := []struct {
+				name  string
+				limit int64
+			}{
+				{limiter.WindowTokensPerMinute, info.Windows.TokensPerMinute},
+				{limiter.WindowTokensPerHour, info.Windows.TokensPerHour},
+				{limiter.WindowTokensPerDay, info.Windows.TokensPerDay},
+				{limiter.WindowTokensPerMonth, info.Windows.TokensPerMonth},
+				{limiter.WindowRequestsPerDay, info.Windows.RequestsPerDay},
+			}
+			var out []string
+			for _, w := range caps {
+				if w.limit > 0 {
+					out = append(out, fmt.Sprintf("%s %d/%d", w.name, info.WindowUsage[w.name], w.limit))
+				}
+			}
+			return out
 		},
 	}
 	tmpl := template.Must(template.New("dashboard").Funcs(funcs).Parse(rawDashboardTemplate))
@@ -164,8 +292,13 @@ func Dashboard(s *store.Store, lim *limiter.Limiter) echo.HandlerFunc {
 			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "admin access required"})
 		}
 		data := dashboardData{
-			Usage:  s.GetAll(),
-			Limits: lim.GetAllLimits(),
+			Usage:       s.GetAll(),
+			Limits:      lim.GetAllLimits(),
+			Plans:       lim.Plans(),
+			DefaultPlan: lim.DefaultPlan(),
+		}
+		for _, o := range lim.Orgs() {
+			data.Orgs = append(data.Orgs, orgRow{Info: o, Usage: s.Org(o.Name)})
 		}
 		c.Response().Header().Set("Content-Type", "text/html; charset=utf-8")
 		return tmpl.Execute(c.Response().Writer, data)
@@ -0,0 +1,234 @@
+// Package upstream manages the pool of Ollama backends the proxy forwards to.
+// Each backend is probed periodically; unhealthy backends are taken out of
+// rotation and requests are balanced across the healthy ones by least
+// in-flight requests. An optional routing table restricts each model to the
+// backends that actually host it.
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// healthPath is probed on every backend. Ollama answers it cheaply without
+// touching any model.
+const healthPath = "/api/version"
+
+// probeTimeout bounds a single health probe so one hung backend can't stall
+// the check loop.
+const probeTimeout = 2 * time.Second
+
+// ErrNoHealthyUpstream is returned by Pick when every candidate backend is unhealthy.
+var ErrNoHealthyUpstream = fmt.Errorf("no healthy upstream available")
+
+// ErrUnknownModel is returned by PickFor when no route matches the model.
+var ErrUnknownModel = fmt.Errorf("model is not served by any upstream")
+
+// Backend is one Ollama instance.
+type Backend struct {
+	URL      *url.URL
+	Routes   []string // model patterns routed to this backend (informational)
+	healthy  atomic.Bool
+	inFlight atomic.Int64
+	attempts atomic.Int64 // proxy attempts sent here, including retries
+	failures atomic.Int64 // attempts that failed before reaching the client
+	queue    fairQueue    // admission queue for Pool.Queue's concurrency budget
+}
+
+// Acquire marks a request as in flight on this backend.
+func (b *Backend) Acquire() { b.inFlight.Add(1) }
+
+// Release marks an in-flight request as finished.
+func (b *Backend) Release() { b.inFlight.Add(-1) }
+
+// Healthy reports whether the last health probe succeeded.
+func (b *Backend) Healthy() bool { return b.healthy.Load() }
+
+// InFlight returns the number of requests currently proxied to this backend.
+func (b *Backend) InFlight() int64 { return b.inFlight.Load() }
+
+// RecordAttempt counts one proxy attempt against this backend.
+func (b *Backend) RecordAttempt() { b.attempts.Add(1) }
+
+// RecordFailure counts one attempt that failed before reaching the client.
+func (b *Backend) RecordFailure() { b.failures.Add(1) }
+
+// Attempts returns the number of proxy attempts sent to this backend.
+func (b *Backend) Attempts() int64 { return b.attempts.Load() }
+
+// Failures returns the number of attempts that failed before reaching the client.
+func (b *Backend) Failures() int64 { return b.failures.Load() }
+
+// Queue returns the state of this backend's admission queue.
+func (b *Backend) Queue() QueueStats { return b.queue.stats() }
+
+// load ranks backends for picking: requests in flight plus requests queued.
+func (b *Backend) load() int64 { return b.InFlight() + int64(b.queue.depth()) }
+
+// Pool is a set of backends with active health checking.
+type Pool struct {
+	Retry    RetryPolicy   // applied by the proxy to every request; zero = no retries
+	Timeouts TimeoutPolicy // per-request deadlines; zero = none
+	Queue    QueuePolicy   // per-backend concurrency budget; zero = unlimited
+
+	backends []*Backend
+	routes   *routeTable // nil = every backend serves every model
+	client   *http.Client
+	next     atomic.Uint64 // rotates the tie-break start index
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewPool parses the backend base URLs and optional model routes. Backends
+// start healthy so traffic flows before the first probe completes.
+// With no routes every backend serves every model.
+func NewPool(urls []string, routes []Route) (*Pool, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("at least one upstream URL is required")
+	}
+	p := &Pool{
+		client: &http.Client{Timeout: probeTimeout},
+		stop:   make(chan struct{}),
+	}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid upstream URL %q", raw)
+		}
+		b := &Backend{URL: u}
+		b.healthy.Store(true)
+		p.backends = append(p.backends, b)
+	}
+	if len(routes) > 0 {
+		rt, err := newRouteTable(routes, p.backends)
+		if err != nil {
+			return nil, err
+		}
+		p.routes = rt
+	}
+	return p, nil
+}
+
+// Backends returns every backend in the pool, healthy or not.
+func (p *Pool) Backends() []*Backend {
+	return p.backends
+}
+
+// Pick returns the healthy backend with the fewest in-flight requests,
+// ignoring model routes.
+func (p *Pool) Pick() (*Backend, error) {
+	return p.pickFrom(p.backends, nil)
+}
+
+// PickFor returns the least-loaded healthy backend that serves model.
+// Backends in exclude (e.g. ones a retry already failed on) are avoided unless
+// no other healthy candidate is left, in which case they are reused.
+// It returns ErrUnknownModel if the routing table has no match.
+func (p *Pool) PickFor(model string, exclude ...*Backend) (*Backend, error) {
+	candidates := p.backends
+	if p.routes != nil {
+		if candidates = p.routes.match(model); candidates == nil {
+			return nil, ErrUnknownModel
+		}
+	}
+	if len(exclude) > 0 {
+		if b, err := p.pickFrom(candidates, exclude); err == nil {
+			return b, nil
+		}
+	}
+	return p.pickFrom(candidates, nil)
+}
+
+// pickFrom returns the candidate with the fewest in-flight and queued requests.
+// Ties are broken round-robin so idle backends share load evenly.
+func (p *Pool) pickFrom(candidates, exclude []*Backend) (*Backend, error) {
+	n := len(candidates)
+	start := int(p.next.Add(1) % uint64(n))
+	var best *Backend
+	for i := 0; i < n; i++ {
+		b := candidates[(start+i)%n]
+		if !b.Healthy() || slices.Contains(exclude, b) {
+			continue
+		}
+		if best == nil || b.load() < best.load() {
+			best = b
+		}
+	}
+	if best == nil {
+		return nil, ErrNoHealthyUpstream
+	}
+	return best, nil
+}
+
+// Admit waits for one of b's slots under the pool's QueuePolicy, sharing the
+// backend fairly between users by weight (a user with weight 2 is served twice
+// as often as one with weight 1 while requests queue) and serving interactive
+// requests before batch ones. It returns ErrQueueTimeout once
+// QueuePolicy.MaxWait has passed, or the context's cause if it ends first.
+// Every successful Admit must be paired with Done.
+func (p *Pool) Admit(ctx context.Context, b *Backend, user string, weight float64, prio Priority) error {
+	return b.queue.acquire(ctx, p.Queue, user, weight, prio)
+}
+
+// Done frees the slot taken by Admit, handing it to the next queued request.
+func (p *Pool) Done(b *Backend) {
+	b.queue.release(p.Queue)
+}
+
+// CheckNow probes every backend once, concurrently, and updates its health.
+func (p *Pool) CheckNow(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, b := range p.backends {
+		wg.Add(1)
+		go func(b *Backend) {
+			defer wg.Done()
+			ok := p.probe(ctx, b)
+			if was := b.healthy.Swap(ok); was != ok {
+				log.Printf("upstream %s healthy=%t", b.URL, ok)
+			}
+		}(b)
+	}
+	wg.Wait()
+}
+
+func (p *Pool) probe(ctx context.Context, b *Backend) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.URL.JoinPath(healthPath).String(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// Start probes all backends immediately and then every interval until Stop is called.
+func (p *Pool) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			p.CheckNow(context.Background())
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background health checks.
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
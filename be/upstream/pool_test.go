@@ -0,0 +1,186 @@
+package upstream_test
+
+import (
+	"context"
+	"lb/upstream"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeOllama starts a local upstream whose health endpoint can be toggled.
+func fakeOllama(t *testing.T) (*httptest.Server, *atomic.Bool) {
+	t.Helper()
+	var up atomic.Bool
+	up.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"version":"0.0.0"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &up
+}
+
+func TestNewPool_RejectsInvalid(t *testing.T) {
+	if _, err := upstream.NewPool(nil, nil); err == nil {
+		t.Fatal("expected error for empty upstream list")
+	}
+	if _, err := upstream.NewPool([]string{"localhost:11434"}, nil); err == nil {
+		t.Fatal("expected error for URL without scheme")
+	}
+}
+
+func TestPick_LeastInFlight(t *testing.T) {
+	a, _ := fakeOllama(t)
+	b, _ := fakeOllama(t)
+	pool, err := upstream.NewPool([]string{a.URL, b.URL}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := pool.Pick()
+	first.Acquire()
+	first.Acquire()
+
+	// The other backend is idle, so it must be picked every time.
+	for i := 0; i < 10; i++ {
+		got, err := pool.Pick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == first {
+			t.Fatalf("pick %d: chose busy backend %s", i, got.URL)
+		}
+	}
+
+	first.Release()
+	first.Release()
+	if first.InFlight() != 0 {
+		t.Fatalf("in-flight after release: got %d, want 0", first.InFlight())
+	}
+}
+
+func TestPick_SpreadsIdleLoad(t *testing.T) {
+	a, _ := fakeOllama(t)
+	b, _ := fakeOllama(t)
+	c, _ := fakeOllama(t)
+	pool, _ := upstream.NewPool([]string{a.URL, b.URL, c.URL}, nil)
+
+	seen := make(map[*upstream.Backend]int)
+	for i := 0; i < 30; i++ {
+		got, _ := pool.Pick()
+		seen[got]++
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected idle picks spread over 3 backends, got %d", len(seen))
+	}
+}
+
+func TestCheckNow_RemovesAndRestoresUnhealthy(t *testing.T) {
+	a, aUp := fakeOllama(t)
+	b, _ := fakeOllama(t)
+	pool, _ := upstream.NewPool([]string{a.URL, b.URL}, nil)
+
+	aUp.Store(false)
+	pool.CheckNow(context.Background())
+
+	for i := 0; i < 10; i++ {
+		got, err := pool.Pick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.URL.String() == a.URL {
+			t.Fatal("unhealthy backend was picked")
+		}
+	}
+
+	aUp.Store(true)
+	pool.CheckNow(context.Background())
+	if !pool.Backends()[0].Healthy() {
+		t.Fatal("backend should be healthy again after recovery")
+	}
+}
+
+func TestPick_NoneHealthy(t *testing.T) {
+	a, aUp := fakeOllama(t)
+	pool, _ := upstream.NewPool([]string{a.URL, "http://127.0.0.1:1"}, nil)
+
+	aUp.Store(false)
+	pool.CheckNow(context.Background())
+
+	if _, err := pool.Pick(); err != upstream.ErrNoHealthyUpstream {
+		t.Fatalf("expected ErrNoHealthyUpstream, got %v", err)
+	}
+}
+
+func TestPickFor_Routes(t *testing.T) {
+	llama, _ := fakeOllama(t)
+	vision, _ := fakeOllama(t)
+	general, _ := fakeOllama(t)
+	pool, err := upstream.NewPool(
+		[]string{llama.URL, vision.URL, general.URL},
+		[]upstream.Route{
+			{Model: "llama*", Upstreams: []string{llama.URL}},
+			{Model: "llama3.2-vision*", Upstreams: []string{vision.URL}},
+			{Model: "moondream", Upstreams: []string{vision.URL}},
+			{Model: "*", Upstreams: []string{general.URL}},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"llama3.2:1b":            llama.URL,
+		"llama3.2-vision:latest": vision.URL, // longest prefix wins
+		"moondream":              vision.URL,
+		"moondream:latest":       vision.URL, // untagged route matches any tag
+		"mistral":                general.URL,
+	}
+	for model, want := range cases {
+		got, err := pool.PickFor(model)
+		if err != nil {
+			t.Fatalf("PickFor(%q): %v", model, err)
+		}
+		if got.URL.String() != want {
+			t.Errorf("PickFor(%q) = %s, want %s", model, got.URL, want)
+		}
+	}
+}
+
+func TestPickFor_UntaggedModelIsLatest(t *testing.T) {
+	a, _ := fakeOllama(t)
+	pool, _ := upstream.NewPool([]string{a.URL}, []upstream.Route{
+		{Model: "moondream:latest", Upstreams: []string{a.URL}},
+	})
+	if _, err := pool.PickFor("moondream"); err != nil {
+		t.Errorf("PickFor(%q): %v, want the moondream:latest route", "moondream", err)
+	}
+	if _, err := pool.PickFor("moondream:1.8b"); err != upstream.ErrUnknownModel {
+		t.Errorf("PickFor(%q): got %v, want ErrUnknownModel", "moondream:1.8b", err)
+	}
+}
+
+func TestPickFor_UnknownModelWithoutDefault(t *testing.T) {
+	a, _ := fakeOllama(t)
+	pool, _ := upstream.NewPool([]string{a.URL}, []upstream.Route{
+		{Model: "llama*", Upstreams: []string{a.URL}},
+	})
+	if _, err := pool.PickFor("moondream"); err != upstream.ErrUnknownModel {
+		t.Fatalf("expected ErrUnknownModel, got %v", err)
+	}
+}
+
+func TestNewPool_RouteToUnknownUpstream(t *testing.T) {
+	a, _ := fakeOllama(t)
+	_, err := upstream.NewPool([]string{a.URL}, []upstream.Route{
+		{Model: "*", Upstreams: []string{"http://elsewhere:11434"}},
+	})
+	if err == nil {
+		t.Fatal("expected error for route to upstream outside the pool")
+	}
+}
@@ -0,0 +1,277 @@
+package upstream
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueTimeout is returned by Pool.Admit when no slot on the backend frees
+// up within QueuePolicy.MaxWait.
+var ErrQueueTimeout = errors.New("timed out waiting for upstream capacity")
+
+// QueuePolicy bounds how many requests each backend serves at once. Requests
+// over the budget wait in a per-backend queue that shares the backend fairly
+// between users in proportion to their weights, interactive requests first.
+type QueuePolicy struct {
+	MaxConcurrent int           // per backend; 0 = unlimited, nothing queues
+	MaxWait       time.Duration // longest a request may queue; 0 = as long as its context allows
+	PromoteAfter  time.Duration // batch requests queued this long go ahead of interactive ones; 0 = never
+}
+
+// Priority is the lane a request queues in.
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota // served first
+	PriorityBatch                       // served when no interactive request waits, or once promoted
+	numPriorities
+)
+
+var priorityNames = [numPriorities]string{"interactive", "batch"}
+
+func (p Priority) String() string { return priorityNames[p] }
+
+// ParsePriority parses a priority name. ok is false for unknown names.
+func ParsePriority(s string) (p Priority, ok bool) {
+	for i, name := range priorityNames {
+		if s == name {
+			return Priority(i), true
+		}
+	}
+	return 0, false
+}
+
+// fairQueue is a start-time fair queue (SFQ) over one backend's slots. Each
+// request gets a virtual start tag: the later of the queue's virtual time and
+// the finish tag of the user's previous request, which is start + 1/weight.
+// Slots go to the smallest start tag, so a user with twice the weight is
+// served twice as often under contention and a heavy user cannot starve the
+// others, while an idle user's next request is not penalised for past usage.
+//
+// Each priority has its own lane, and a free slot goes to the interactive
+// lane before the batch lane. To keep batch traffic moving, a batch request
+// that has waited QueuePolicy.PromoteAfter is served ahead of both lanes.
+type fairQueue struct {
+	mu      sync.Mutex
+	running int
+	vtime   float64            // start tag of the request dispatched last
+	finish  map[string]float64 // user → finish tag of their latest request
+	waiting [numPriorities]waiters
+	seq     uint64 // tie-break: FIFO among equal tags
+
+	// Wait statistics per lane, over every admitted request.
+	admitted  [numPriorities]int64
+	totalWait [numPriorities]time.Duration
+	maxWait   [numPriorities]time.Duration
+	promoted  int64 // batch requests served through starvation protection
+}
+
+// waiter is one queued request.
+type waiter struct {
+	start    float64
+	seq      uint64
+	lane     Priority
+	enqueued time.Time
+	ready    chan struct{} // closed when the request is handed a slot
+	index    int           // position in the heap; -1 once dispatched
+}
+
+// before reports whether w is served before o within a lane.
+func (w *waiter) before(o *waiter) bool {
+	if w.start != o.start {
+		return w.start < o.start
+	}
+	return w.seq < o.seq
+}
+
+// waiters is a min-heap of queued requests by start tag.
+type waiters []*waiter
+
+func (w waiters) Len() int           { return len(w) }
+func (w waiters) Less(i, j int) bool { return w[i].before(w[j]) }
+func (w waiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index, w[j].index = i, j
+}
+func (w *waiters) Push(x any) {
+	wt := x.(*waiter)
+	wt.index = len(*w)
+	*w = append(*w, wt)
+}
+func (w *waiters) Pop() any {
+	old := *w
+	wt := old[len(old)-1]
+	*w, wt.index = old[:len(old)-1], -1
+	return wt
+}
+
+// tag assigns the next start tag for user and advances their finish tag.
+// Caller must hold q.mu.
+func (q *fairQueue) tag(user string, weight float64) float64 {
+	if q.finish == nil {
+		q.finish = make(map[string]float64)
+	}
+	if weight <= 0 {
+		weight = 1
+	}
+	start := max(q.vtime, q.finish[user])
+	q.finish[user] = start + 1/weight
+	return start
+}
+
+// dispatched records that a request with the given start tag took a slot in
+// lane after waiting d. Caller must hold q.mu.
+func (q *fairQueue) dispatched(start float64, lane Priority, d time.Duration) {
+	q.vtime = max(q.vtime, start)
+	q.admitted[lane]++
+	q.totalWait[lane] += d
+	q.maxWait[lane] = max(q.maxWait[lane], d)
+	// Finish tags at or behind the virtual time carry no history any more.
+	for user, f := range q.finish {
+		if f <= q.vtime {
+			delete(q.finish, user)
+		}
+	}
+}
+
+// queued returns the number of requests waiting in every lane. Caller must hold q.mu.
+func (q *fairQueue) queued() int {
+	n := 0
+	for _, lane := range q.waiting {
+		n += len(lane)
+	}
+	return n
+}
+
+// depth returns the number of requests waiting in every lane.
+func (q *fairQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.queued()
+}
+
+// acquire takes a slot, queueing in lane prio while pol's budget is used up.
+// It fails with ErrQueueTimeout after pol.MaxWait, or with the context's cause.
+func (q *fairQueue) acquire(ctx context.Context, pol QueuePolicy, user string, weight float64, prio Priority) error {
+	q.mu.Lock()
+	start := q.tag(user, weight)
+	if pol.MaxConcurrent <= 0 || (q.running < pol.MaxConcurrent && q.queued() == 0) {
+		q.running++
+		q.dispatched(start, prio, 0)
+		q.mu.Unlock()
+		return nil
+	}
+	q.seq++
+	w := &waiter{start: start, seq: q.seq, lane: prio, enqueued: time.Now(), ready: make(chan struct{})}
+	heap.Push(&q.waiting[prio], w)
+	q.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if pol.MaxWait > 0 {
+		t := time.NewTimer(pol.MaxWait)
+		defer t.Stop()
+		timeout = t.C
+	}
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = context.Cause(ctx)
+	}
+
+	q.mu.Lock()
+	if w.index >= 0 {
+		heap.Remove(&q.waiting[prio], w.index)
+		q.mu.Unlock()
+		return err
+	}
+	q.mu.Unlock()
+	// Handed a slot just as we gave up: pass it on.
+	q.release(pol)
+	return err
+}
+
+// next removes the request to serve next: a batch request overdue for
+// promotion, else the head of the highest-priority non-empty lane.
+// Caller must hold q.mu.
+func (q *fairQueue) next(pol QueuePolicy) *waiter {
+	if batch := &q.waiting[PriorityBatch]; pol.PromoteAfter > 0 && len(*batch) > 0 {
+		cutoff := time.Now().Add(-pol.PromoteAfter)
+		var overdue *waiter
+		for _, w := range *batch {
+			if !w.enqueued.After(cutoff) && (overdue == nil || w.before(overdue)) {
+				overdue = w
+			}
+		}
+		if overdue != nil {
+			q.promoted++
+			return heap.Remove(batch, overdue.index).(*waiter)
+		}
+	}
+	for lane := range q.waiting {
+		if len(q.waiting[lane]) > 0 {
+			return heap.Pop(&q.waiting[lane]).(*waiter)
+		}
+	}
+	return nil
+}
+
+// release frees a slot, handing it straight to the next queued request if
+// the budget allows.
+func (q *fairQueue) release(pol QueuePolicy) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if pol.MaxConcurrent <= 0 || q.running <= pol.MaxConcurrent {
+		if w := q.next(pol); w != nil {
+			q.dispatched(w.start, w.lane, time.Since(w.enqueued))
+			close(w.ready)
+			return
+		}
+	}
+	q.running--
+}
+
+// QueueStats describes a backend's admission queue.
+type QueueStats struct {
+	QueueLaneStats                           // every lane together
+	Promoted       int64                     // batch requests served ahead of interactive ones after waiting PromoteAfter
+	Lanes          map[string]QueueLaneStats // keyed by priority name
+}
+
+// QueueLaneStats describes the requests queued at one priority.
+type QueueLaneStats struct {
+	Queued   int           // requests waiting for a slot now
+	Admitted int64         // requests given a slot so far
+	AvgWait  time.Duration // mean time admitted requests spent queued
+	MaxWait  time.Duration // longest time an admitted request spent queued
+}
+
+func laneStats(queued int, admitted int64, total, longest time.Duration) QueueLaneStats {
+	st := QueueLaneStats{Queued: queued, Admitted: admitted, MaxWait: longest}
+	if admitted > 0 {
+		st.AvgWait = total / time.Duration(admitted)
+	}
+	return st
+}
+
+func (q *fairQueue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st := QueueStats{Promoted: q.promoted, Lanes: make(map[string]QueueLaneStats, numPriorities)}
+	var admitted int64
+	var total, longest time.Duration
+	for lane := range numPriorities {
+		st.Lanes[lane.String()] = laneStats(len(q.waiting[lane]), q.admitted[lane], q.totalWait[lane], q.maxWait[lane])
+		admitted += q.admitted[lane]
+		total += q.totalWait[lane]
+		longest = max(longest, q.maxWait[lane])
+	}
+	st.QueueLaneStats = laneStats(q.queued(), admitted, total, longest)
+	return st
+}
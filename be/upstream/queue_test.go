@@ -0,0 +1,170 @@
+package upstream_test
+
+import (
+	"context"
+	"errors"
+	"lb/upstream"
+	"sync"
+	"testing"
+	"time"
+)
+
+// enqueue starts an interactive Admit for user in the background and waits until it is
+// queued, so tests control arrival order. The user is sent on served once
+// admitted; the slot is kept until Done.
+func enqueue(t *testing.T, p *upstream.Pool, b *upstream.Backend, user string, weight float64, served chan<- string) {
+	t.Helper()
+	enqueueAt(t, p, b, user, weight, upstream.PriorityInteractive, served)
+}
+
+// enqueueAt is enqueue at the given priority.
+func enqueueAt(t *testing.T, p *upstream.Pool, b *upstream.Backend, user string, weight float64, prio upstream.Priority, served chan<- string) {
+	t.Helper()
+	before := b.Queue().Queued
+	go func() {
+		if err := p.Admit(context.Background(), b, user, weight, prio); err != nil {
+			t.Errorf("%s: %v", user, err)
+			return
+		}
+		served <- user
+	}()
+	for b.Queue().Queued == before {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// drain frees one slot at a time and returns the order queued users got it.
+func drain(p *upstream.Pool, b *upstream.Backend, served <-chan string, n int) []string {
+	var order []string
+	for range n {
+		p.Done(b)
+		order = append(order, <-served)
+	}
+	p.Done(b)
+	return order
+}
+
+func TestAdmit_FairShareAcrossUsers(t *testing.T) {
+	p, _ := upstream.NewPool([]string{"http://a:1"}, nil)
+	p.Queue = upstream.QueuePolicy{MaxConcurrent: 1}
+	b := p.Backends()[0]
+	if err := p.Admit(context.Background(), b, "heavy", 1, upstream.PriorityInteractive); err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan string, 8)
+	for range 3 {
+		enqueue(t, p, b, "heavy", 1, served)
+	}
+	enqueue(t, p, b, "light", 1, served)
+
+	// The light user arrived last but, with heavy already holding the slot,
+	// is served next rather than behind heavy's whole backlog.
+	got := drain(p, b, served, 4)
+	want := []string{"light", "heavy", "heavy", "heavy"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("service order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestAdmit_Weights(t *testing.T) {
+	p, _ := upstream.NewPool([]string{"http://a:1"}, nil)
+	p.Queue = upstream.QueuePolicy{MaxConcurrent: 1}
+	b := p.Backends()[0]
+	if err := p.Admit(context.Background(), b, "x", 1, upstream.PriorityInteractive); err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan string, 8)
+	for range 3 {
+		enqueue(t, p, b, "one", 1, served)
+		enqueue(t, p, b, "two", 2, served)
+	}
+	counts := map[string]int{}
+	for _, u := range drain(p, b, served, 6)[:3] {
+		counts[u]++
+	}
+	if counts["two"] != 2 {
+		t.Errorf("weight 2 should get 2 of the first 3 slots, got %v", counts)
+	}
+}
+
+func TestAdmit_MaxWait(t *testing.T) {
+	p, _ := upstream.NewPool([]string{"http://a:1"}, nil)
+	p.Queue = upstream.QueuePolicy{MaxConcurrent: 1, MaxWait: 20 * time.Millisecond}
+	b := p.Backends()[0]
+	if err := p.Admit(context.Background(), b, "a", 1, upstream.PriorityInteractive); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := p.Admit(context.Background(), b, "b", 1, upstream.PriorityInteractive); !errors.Is(err, upstream.ErrQueueTimeout) {
+			t.Errorf("expected ErrQueueTimeout, got %v", err)
+		}
+	}()
+	wg.Wait()
+	if q := b.Queue().Queued; q != 0 {
+		t.Errorf("timed-out request should leave the queue, depth = %d", q)
+	}
+	p.Done(b)
+	// The slot is free again: no one is left to hand it to.
+	if err := p.Admit(context.Background(), b, "c", 1, upstream.PriorityInteractive); err != nil {
+		t.Fatalf("slot should be free: %v", err)
+	}
+}
+
+func TestAdmit_InteractiveBeforeBatch(t *testing.T) {
+	p, _ := upstream.NewPool([]string{"http://a:1"}, nil)
+	p.Queue = upstream.QueuePolicy{MaxConcurrent: 1}
+	b := p.Backends()[0]
+	if err := p.Admit(context.Background(), b, "x", 1, upstream.PriorityInteractive); err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan string, 8)
+	enqueueAt(t, p, b, "batch", 1, upstream.PriorityBatch, served)
+	enqueueAt(t, p, b, "batch", 1, upstream.PriorityBatch, served)
+	enqueue(t, p, b, "chat", 1, served)
+
+	got := drain(p, b, served, 3)
+	want := []string{"chat", "batch", "batch"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("service order = %v, want %v", got, want)
+		}
+	}
+	lanes := b.Queue().Lanes
+	if lanes["interactive"].Admitted != 2 || lanes["batch"].Admitted != 2 {
+		t.Errorf("per-lane admitted = %+v", lanes)
+	}
+	if lanes["batch"].MaxWait < lanes["interactive"].MaxWait {
+		t.Errorf("batch should have waited longest: %+v", lanes)
+	}
+}
+
+func TestAdmit_PromotesStarvedBatch(t *testing.T) {
+	p, _ := upstream.NewPool([]string{"http://a:1"}, nil)
+	p.Queue = upstream.QueuePolicy{MaxConcurrent: 1, PromoteAfter: 20 * time.Millisecond}
+	b := p.Backends()[0]
+	if err := p.Admit(context.Background(), b, "x", 1, upstream.PriorityInteractive); err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan string, 8)
+	enqueueAt(t, p, b, "batch", 1, upstream.PriorityBatch, served)
+	time.Sleep(30 * time.Millisecond)
+	enqueue(t, p, b, "chat", 1, served)
+
+	// The batch request has waited past PromoteAfter, so it no longer yields.
+	got := drain(p, b, served, 2)
+	if got[0] != "batch" {
+		t.Fatalf("service order = %v, want the starved batch request first", got)
+	}
+	if n := b.Queue().Promoted; n != 1 {
+		t.Errorf("promoted = %d, want 1", n)
+	}
+}
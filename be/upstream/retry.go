@@ -0,0 +1,34 @@
+package upstream
+
+import "time"
+
+// RetryPolicy controls how a request is retried when an upstream refuses the
+// connection or answers with a retryable status before any bytes reach the client.
+type RetryPolicy struct {
+	MaxAttempts   int           // total attempts including the first; <= 1 disables retries
+	Backoff       time.Duration // delay before the second attempt; doubles per attempt
+	MaxBackoff    time.Duration // upper bound on the delay; 0 = unbounded
+	RetryStatuses []int         // upstream statuses that trigger a retry, e.g. 502, 503, 504
+}
+
+// Retryable reports whether an upstream response status should be retried.
+func (p RetryPolicy) Retryable(status int) bool {
+	for _, s := range p.RetryStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay returns the backoff to wait after the given (1-based) failed attempt.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,44 @@
+package upstream_test
+
+import (
+	"lb/upstream"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := upstream.RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 350 * time.Millisecond}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 350 * time.Millisecond, 350 * time.Millisecond}
+	for i, w := range want {
+		if got := p.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	p := upstream.RetryPolicy{RetryStatuses: []int{502, 503}}
+	if !p.Retryable(503) {
+		t.Error("503 should be retryable")
+	}
+	if p.Retryable(500) || p.Retryable(200) {
+		t.Error("only configured statuses should be retryable")
+	}
+}
+
+func TestPickFor_ExcludeFallsBackWhenExhausted(t *testing.T) {
+	a, _ := fakeOllama(t)
+	b, _ := fakeOllama(t)
+	pool, _ := upstream.NewPool([]string{a.URL, b.URL}, nil)
+	first, second := pool.Backends()[0], pool.Backends()[1]
+
+	for i := 0; i < 5; i++ {
+		if got, _ := pool.PickFor("m", first); got != second {
+			t.Fatal("excluded backend was picked while another was available")
+		}
+	}
+	// With every backend excluded, the pool reuses one instead of failing.
+	if _, err := pool.PickFor("m", first, second); err != nil {
+		t.Fatalf("expected fallback to an excluded backend, got %v", err)
+	}
+}
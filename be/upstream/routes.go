@@ -0,0 +1,87 @@
+package upstream
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Route maps a model pattern to the upstreams that host it.
+//
+// Patterns are matched most-specific first:
+//   - exact name, e.g. "moondream:latest"; an untagged model is taken as its
+//     ":latest" tag, as Ollama does, so "moondream" matches it too
+//   - untagged name, e.g. "llama3.2" matches "llama3.2:1b"
+//   - prefix ending in "*", e.g. "llama*" (longest prefix wins)
+//   - "*", the default route
+type Route struct {
+	Model     string   `json:"model"`
+	Upstreams []string `json:"upstreams"`
+}
+
+type prefixRoute struct {
+	prefix   string
+	backends []*Backend
+}
+
+// routeTable resolves a model name to its candidate backends.
+type routeTable struct {
+	exact    map[string][]*Backend
+	prefixes []prefixRoute // sorted longest prefix first
+	fallback []*Backend    // nil = unknown models are rejected
+}
+
+func newRouteTable(routes []Route, backends []*Backend) (*routeTable, error) {
+	byURL := make(map[string]*Backend, len(backends))
+	for _, b := range backends {
+		byURL[strings.TrimRight(b.URL.String(), "/")] = b
+	}
+
+	rt := &routeTable{exact: make(map[string][]*Backend)}
+	for _, r := range routes {
+		if r.Model == "" || len(r.Upstreams) == 0 {
+			return nil, fmt.Errorf("route %q: model and upstreams are required", r.Model)
+		}
+		var targets []*Backend
+		for _, u := range r.Upstreams {
+			b, ok := byURL[strings.TrimRight(u, "/")]
+			if !ok {
+				return nil, fmt.Errorf("route %q: upstream %q is not in ollama_urls", r.Model, u)
+			}
+			targets = append(targets, b)
+			b.Routes = append(b.Routes, r.Model)
+		}
+		switch {
+		case r.Model == "*":
+			rt.fallback = targets
+		case strings.HasSuffix(r.Model, "*"):
+			rt.prefixes = append(rt.prefixes, prefixRoute{strings.TrimSuffix(r.Model, "*"), targets})
+		default:
+			rt.exact[r.Model] = targets
+		}
+	}
+	sort.SliceStable(rt.prefixes, func(i, j int) bool {
+		return len(rt.prefixes[i].prefix) > len(rt.prefixes[j].prefix)
+	})
+	return rt, nil
+}
+
+// match returns the backends serving model, or nil if no route matches.
+func (rt *routeTable) match(model string) []*Backend {
+	if bs, ok := rt.exact[model]; ok {
+		return bs
+	}
+	if name, _, found := strings.Cut(model, ":"); found {
+		if bs, ok := rt.exact[name]; ok {
+			return bs
+		}
+	} else if bs, ok := rt.exact[model+":latest"]; ok {
+		return bs
+	}
+	for _, pr := range rt.prefixes {
+		if strings.HasPrefix(model, pr.prefix) {
+			return pr.backends
+		}
+	}
+	return rt.fallback
+}
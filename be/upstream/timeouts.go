@@ -0,0 +1,79 @@
+package upstream
+
+import (
+	"strings"
+	"time"
+)
+
+// Timeouts bounds one proxied request. A zero field disables that deadline.
+type Timeouts struct {
+	FirstToken time.Duration // request start → first response body byte
+	Idle       time.Duration // longest gap between streamed chunks
+	Total      time.Duration // whole request, including retries
+}
+
+// TimeoutOverrides replaces some of the Timeouts of a broader level. A nil
+// field inherits that deadline; a zero one disables it.
+type TimeoutOverrides struct {
+	FirstToken *time.Duration
+	Idle       *time.Duration
+	Total      *time.Duration
+}
+
+// apply returns t with the fields set in o replaced.
+func (o TimeoutOverrides) apply(t Timeouts) Timeouts {
+	if o.FirstToken != nil {
+		t.FirstToken = *o.FirstToken
+	}
+	if o.Idle != nil {
+		t.Idle = *o.Idle
+	}
+	if o.Total != nil {
+		t.Total = *o.Total
+	}
+	return t
+}
+
+// TimeoutPolicy resolves the Timeouts for a user and model. Overrides apply
+// field by field: a per-user value wins over a per-model one, which wins over
+// Default.
+type TimeoutPolicy struct {
+	Default Timeouts
+	Models  map[string]TimeoutOverrides // keyed by model pattern, matched like Route.Model
+	Users   map[string]TimeoutOverrides // keyed by user ID
+}
+
+// For returns the effective timeouts for user calling model.
+func (p TimeoutPolicy) For(user, model string) Timeouts {
+	t := p.Default
+	if m, ok := p.matchModel(model); ok {
+		t = m.apply(t)
+	}
+	if u, ok := p.Users[user]; ok {
+		t = u.apply(t)
+	}
+	return t
+}
+
+// matchModel picks the most specific model pattern: exact name, then
+// untagged name, then the longest "prefix*", then "*".
+func (p TimeoutPolicy) matchModel(model string) (TimeoutOverrides, bool) {
+	if t, ok := p.Models[model]; ok {
+		return t, true
+	}
+	if name, _, found := strings.Cut(model, ":"); found {
+		if t, ok := p.Models[name]; ok {
+			return t, true
+		}
+	} else if t, ok := p.Models[model+":latest"]; ok {
+		return t, true
+	}
+	best, bestLen := TimeoutOverrides{}, -1
+	for pattern, t := range p.Models {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = t, len(prefix)
+		}
+	}
+	return best, bestLen >= 0
+}
@@ -0,0 +1,42 @@
+package upstream_test
+
+import (
+	"lb/upstream"
+	"testing"
+	"time"
+)
+
+func TestTimeoutPolicy_For(t *testing.T) {
+	d := func(v time.Duration) *time.Duration { return &v }
+	p := upstream.TimeoutPolicy{
+		Default: upstream.Timeouts{FirstToken: time.Minute, Idle: 30 * time.Second, Total: 10 * time.Minute},
+		Models: map[string]upstream.TimeoutOverrides{
+			"llama*":          {FirstToken: d(2 * time.Minute)},
+			"llama3.2-vision": {FirstToken: d(5 * time.Minute), Idle: d(time.Minute)},
+			"moondream":       {Idle: d(0)}, // 0 disables, unlike an omitted field
+			"mistral:latest":  {Total: d(5 * time.Minute)},
+		},
+		Users: map[string]upstream.TimeoutOverrides{
+			"bob":   {Total: d(time.Minute)},
+			"carol": {Total: d(0)},
+		},
+	}
+
+	cases := []struct {
+		user, model string
+		want        upstream.Timeouts
+	}{
+		{"alice", "phi3", p.Default},
+		{"alice", "mistral", upstream.Timeouts{FirstToken: time.Minute, Idle: 30 * time.Second, Total: 5 * time.Minute}}, // untagged = :latest
+		{"alice", "llama3.2:1b", upstream.Timeouts{FirstToken: 2 * time.Minute, Idle: 30 * time.Second, Total: 10 * time.Minute}},
+		{"alice", "llama3.2-vision:latest", upstream.Timeouts{FirstToken: 5 * time.Minute, Idle: time.Minute, Total: 10 * time.Minute}},
+		{"bob", "llama3.2:1b", upstream.Timeouts{FirstToken: 2 * time.Minute, Idle: 30 * time.Second, Total: time.Minute}},
+		{"alice", "moondream:latest", upstream.Timeouts{FirstToken: time.Minute, Total: 10 * time.Minute}},
+		{"carol", "moondream", upstream.Timeouts{FirstToken: time.Minute}},
+	}
+	for _, c := range cases {
+		if got := p.For(c.user, c.model); got != c.want {
+			t.Errorf("For(%q, %q) = %+v, want %+v", c.user, c.model, got, c.want)
+		}
+	}
+}
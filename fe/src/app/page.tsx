@@ -18,6 +18,14 @@ interface Message {
   image?: string | null;
 }
 
+// errorMessage reads a proxy error body: either {"error": "..."} or the
+// OpenAI envelope {"error": {"message": "..."}}.
+function errorMessage(body: unknown): string {
+  const err = (body as { error?: string | { message?: string } }).error;
+  if (typeof err === "string") return err;
+  return err?.message ?? "Request failed";
+}
+
 export default function ChatPage() {
   const router = useRouter();
   const [messages, setMessages] = useState<Message[]>([]);
@@ -165,7 +173,7 @@ export default function ChatPage() {
           const copy = [...m];
           copy[copy.length - 1] = {
             role: "assistant",
-            content: `⚠️ ${(err as { error: string }).error}`,
+            content: `⚠️ ${errorMessage(err)}`,
           };
           return copy;
         });